@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/a2hop/line-counter/counter"
+)
+
+// baselineSchemaVersion is bumped whenever the saved ProjectStats layout
+// changes. Version 2 added the per-language maps and the generated, test
+// and line ending counts, and left generated files out of the totals.
+const baselineSchemaVersion = 2
+
+// oldestBaselineSchemaVersion is the oldest schema version still loaded.
+// Version 1 baselines are compared with a warning: their files are keyed
+// by extension only and their totals include generated files.
+const oldestBaselineSchemaVersion = 1
+
+// Baseline is a saved snapshot of ProjectStats used to track changes over time
+type Baseline struct {
+	SchemaVersion int
+	CreatedAt     time.Time
+	Stats         *counter.ProjectStats
+}
+
+// SaveBaseline writes stats to path as a JSON baseline
+func SaveBaseline(path string, stats *counter.ProjectStats) error {
+	data, err := encodeBaseline(stats)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// encodeBaseline returns stats as indented baseline JSON stamped with the
+// current schema version
+func encodeBaseline(stats *counter.ProjectStats) ([]byte, error) {
+	data, err := json.MarshalIndent(Baseline{
+		SchemaVersion: baselineSchemaVersion,
+		CreatedAt:     time.Now().UTC(),
+		Stats:         stats,
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// LoadBaseline reads a baseline written by SaveBaseline, rejecting files
+// saved with an unknown schema version. Older baselines are loaded as they
+// are, with a warning to logger, or to slog.Default() if it is nil, that
+// their counts are not measured the same way.
+func LoadBaseline(path string, logger *slog.Logger) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var baseline Baseline
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return nil, fmt.Errorf("could not parse baseline %s: %v", path, err)
+	}
+	if baseline.SchemaVersion < oldestBaselineSchemaVersion || baseline.SchemaVersion > baselineSchemaVersion {
+		return nil, fmt.Errorf("baseline %s has schema version %d, expected %d; save it again",
+			path, baseline.SchemaVersion, baselineSchemaVersion)
+	}
+	if baseline.Stats == nil {
+		return nil, fmt.Errorf("baseline %s contains no stats", path)
+	}
+	if baseline.SchemaVersion < baselineSchemaVersion {
+		if logger == nil {
+			logger = slog.Default()
+		}
+		logger.Warn("baseline was saved by an older version; generated files and languages told apart by content may show up as changes until it is saved again",
+			"path", path, "schema_version", baseline.SchemaVersion)
+	}
+	return &baseline, nil
+}
+
+// printDelta prints the per-language change from old to cur under title
+func printDelta(w io.Writer, title string, old, cur *counter.ProjectStats) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, title)
+	fmt.Fprintln(w, strings.Repeat("-", 78))
+	fmt.Fprintf(w, "%-16s %-8s %-10s %-10s %-12s %-10s\n", "Language", "Files", "Total", "Code", "Comments", "Blank")
+	fmt.Fprintln(w, strings.Repeat("-", 78))
+
+	oldLanguages, curLanguages := byLanguage(old), byLanguage(cur)
+	seen := make(map[string]bool)
+	var names []string
+	for _, stats := range []*counter.ProjectStats{oldLanguages, curLanguages} {
+		for name := range stats.FilesByExt {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		printDeltaRow(w, name, curLanguages.FilesByExt[name]-oldLanguages.FilesByExt[name],
+			oldLanguages.StatsByExt[name], curLanguages.StatsByExt[name])
+	}
+
+	fmt.Fprintln(w, strings.Repeat("-", 78))
+	printDeltaRow(w, "TOTAL", cur.TotalFiles-old.TotalFiles, old.TotalStats, cur.TotalStats)
+}
+
+func printDeltaRow(w io.Writer, label string, files int, old, cur counter.FileStats) {
+	fmt.Fprintf(w, "%-16s %-8s %-10s %-10s %-12s %-10s\n",
+		label, signed(files), signed(cur.TotalLines-old.TotalLines),
+		signed(cur.CodeLines-old.CodeLines), signed(cur.CommentLines-old.CommentLines),
+		signed(cur.BlankLines-old.BlankLines))
+}
+
+// checkGrowth fails when the code lines grew from old to cur by more than
+// limit. A negative limit disables the check.
+func checkGrowth(old, cur *counter.ProjectStats, limit int) error {
+	if limit < 0 {
+		return nil
+	}
+	if growth := cur.TotalStats.CodeLines - old.TotalStats.CodeLines; growth > limit {
+		return fmt.Errorf("code lines grew by %d since the baseline (limit %d)", growth, limit)
+	}
+	return nil
+}
+
+// signed formats n with an explicit sign, e.g. "+3" or "-1"
+func signed(n int) string {
+	return fmt.Sprintf("%+d", n)
+}
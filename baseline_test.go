@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/a2hop/line-counter/counter"
+)
+
+// baselineV1 is a baseline of one Go file as saved before schema version 2,
+// without the per-language maps
+const baselineV1 = `{
+  "SchemaVersion": 1,
+  "CreatedAt": "2024-01-01T00:00:00Z",
+  "Stats": {
+    "FilesByExt": {".go": 1},
+    "StatsByExt": {".go": {"TotalLines": 4, "CodeLines": 2, "BlankLines": 1, "CommentLines": 1}},
+    "TotalStats": {"TotalLines": 4, "CodeLines": 2, "BlankLines": 1, "CommentLines": 1},
+    "TotalFiles": 1
+  }
+}
+`
+
+// TestLoadBaselineOlderSchema checks that a version 1 baseline is loaded
+// with a warning and compared by extension
+func TestLoadBaselineOlderSchema(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "baseline.json")
+	if err := os.WriteFile(path, []byte(baselineV1), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var log bytes.Buffer
+	baseline, err := LoadBaseline(path, slog.New(slog.NewTextHandler(&log, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(log.String(), "older version") || !strings.Contains(log.String(), "schema_version=1") {
+		t.Errorf("missing warning about the schema version:\n%s", log.String())
+	}
+
+	src := filepath.Join(dir, "src")
+	writeTree(t, src, map[string]string{"main.go": "package main\n\n// main runs\nfunc main() {}\n"})
+	var out bytes.Buffer
+	printDelta(&out, "Change:", baseline.Stats, count(t, src, counter.Options{}))
+	for _, line := range strings.Split(out.String(), "\n") {
+		if strings.HasPrefix(line, "Go ") || strings.HasPrefix(line, "TOTAL ") {
+			if fields := strings.Fields(line); strings.Join(fields[1:], " ") != "+0 +0 +0 +0 +0" {
+				t.Errorf("unchanged file shows a change: %q", line)
+			}
+		}
+	}
+	if !strings.Contains(out.String(), "\nGo ") {
+		t.Errorf("no Go row in:\n%s", out.String())
+	}
+}
+
+// TestLoadBaselineUnknownSchema checks that baselines of schema versions
+// this version does not know are rejected
+func TestLoadBaselineUnknownSchema(t *testing.T) {
+	for _, version := range []string{"0", "3"} {
+		path := filepath.Join(t.TempDir(), "baseline.json")
+		data := strings.Replace(baselineV1, `"SchemaVersion": 1`, `"SchemaVersion": `+version, 1)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadBaseline(path, nil); err == nil || !strings.Contains(err.Error(), "schema version "+version) {
+			t.Errorf("version %s: err = %v, want a schema version error", version, err)
+		}
+	}
+}
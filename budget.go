@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/a2hop/line-counter/counter"
+)
+
+// runCheck implements "line-counter check [path]": it counts the path and
+// compares the code lines below each directory of the budget file with its
+// budget, failing if any is over
+func runCheck(ctx context.Context, w io.Writer, args []string, opts Options) error {
+	if len(args) > 1 {
+		return errors.New("check: expected at most one path")
+	}
+	root := "."
+	if len(args) == 1 {
+		root = args[0]
+	}
+	path := opts.Budgets
+	if path == "" {
+		path = filepath.Join(root, counter.BudgetsFile)
+	}
+	budgets, err := counter.LoadBudgets(path)
+	if err != nil {
+		return fmt.Errorf("check: %v", err)
+	}
+
+	opts.CollectFiles = true
+	stats, err := countStats(ctx, root, opts)
+	opts.progress.clear()
+	if err != nil {
+		return countError(err, opts)
+	}
+
+	results := counter.CheckBudgets(stats, root, budgets)
+	over := printBudgets(w, results, opts.colors)
+	if over > 0 {
+		return fmt.Errorf("%d budget(s) exceeded", over)
+	}
+	return nil
+}
+
+// printBudgets prints the code lines and budget of each directory,
+// marking those over budget in the error color, and returns how many are
+func printBudgets(w io.Writer, results []counter.BudgetResult, colors *palette) int {
+	over := 0
+	fmt.Fprintln(w, colors.paint("title", "Code line budgets:"))
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	fmt.Fprintf(w, "%-32s %-10s %-10s %-8s %s\n", "Directory", "Code", "Budget", "Used", "Status")
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	for _, r := range results {
+		status := "ok"
+		if r.Over() {
+			status = colors.paint("error", fmt.Sprintf("OVER by %d", r.CodeLines-r.MaxCodeLines))
+			over++
+		}
+		// A budget of 0 allows no code, of which no share can be used
+		used := "n/a"
+		if r.MaxCodeLines > 0 {
+			used = percent(r.CodeLines, r.MaxCodeLines)
+		}
+		fmt.Fprintf(w, "%-32s %-10d %-10d %-8s %s\n", r.Dir, r.CodeLines, r.MaxCodeLines, used, status)
+	}
+	return over
+}
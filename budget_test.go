@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/a2hop/line-counter/counter"
+)
+
+// TestPrintBudgets checks the share of each budget used and the status of
+// the directories over budget
+func TestPrintBudgets(t *testing.T) {
+	results := []counter.BudgetResult{
+		{Budget: counter.Budget{Dir: ".", MaxCodeLines: 200}, CodeLines: 50},
+		{Budget: counter.Budget{Dir: "internal/legacy", MaxCodeLines: 10}, CodeLines: 15},
+		{Budget: counter.Budget{Dir: "vendor", MaxCodeLines: 0}, CodeLines: 3},
+		{Budget: counter.Budget{Dir: "empty", MaxCodeLines: 0}, CodeLines: 0},
+	}
+	var out bytes.Buffer
+	if over := printBudgets(&out, results, nil); over != 2 {
+		t.Errorf("printBudgets() = %d over budget, want 2", over)
+	}
+
+	want := map[string][]string{
+		".":               {"50", "200", "25.0%", "ok"},
+		"internal/legacy": {"15", "10", "150.0%", "OVER", "by", "5"},
+		"vendor":          {"3", "0", "n/a", "OVER", "by", "3"},
+		"empty":           {"0", "0", "n/a", "ok"},
+	}
+	for _, line := range strings.Split(out.String(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || want[fields[0]] == nil {
+			continue
+		}
+		if got := strings.Join(fields[1:], " "); got != strings.Join(want[fields[0]], " ") {
+			t.Errorf("%s: got %q, want %q", fields[0], got, strings.Join(want[fields[0]], " "))
+		}
+		delete(want, fields[0])
+	}
+	for dir := range want {
+		t.Errorf("no row for %s in:\n%s", dir, out.String())
+	}
+}
@@ -0,0 +1,16 @@
+module github.com/a2hop/line-counter/cmd/golangci-lint-plugin
+
+go 1.22.0
+
+require (
+	github.com/a2hop/line-counter v0.0.0
+	github.com/golangci/plugin-module-register v0.1.1
+	golang.org/x/tools v0.30.0
+)
+
+require (
+	golang.org/x/mod v0.23.0 // indirect
+	golang.org/x/sync v0.11.0 // indirect
+)
+
+replace github.com/a2hop/line-counter => ../..
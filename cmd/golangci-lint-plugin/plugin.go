@@ -0,0 +1,93 @@
+// Package plugin is a golangci-lint module plugin reporting Go files with
+// more lines than a limit, counted as by line-counter --max-file-lines.
+//
+// It lives in a module of its own so that line-counter itself keeps to
+// the standard library. Build it into golangci-lint with a .custom-gcl.yml
+// giving the path of this directory, then enable it in .golangci.yml:
+//
+//	linters-settings:
+//	  custom:
+//	    linecounter:
+//	      type: module
+//	      settings:
+//	        max-file-lines: 500
+package plugin
+
+import (
+	"fmt"
+
+	"github.com/golangci/plugin-module-register/register"
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/a2hop/line-counter/counter"
+)
+
+func init() {
+	register.Plugin("linecounter", New)
+}
+
+// Settings are the settings of the linter in .golangci.yml
+type Settings struct {
+	// MaxFileLines is the most lines a file may have; 0 turns the check off
+	MaxFileLines int `json:"max-file-lines"`
+}
+
+// Plugin is the linecounter linter
+type Plugin struct {
+	settings Settings
+	counter  *counter.Counter
+}
+
+// New returns the linter configured with the settings of .golangci.yml
+func New(settings any) (register.LinterPlugin, error) {
+	s, err := register.DecodeSettings[Settings](settings)
+	if err != nil {
+		return nil, err
+	}
+	if s.MaxFileLines < 0 {
+		return nil, fmt.Errorf("linecounter: max-file-lines must not be negative")
+	}
+	c, err := counter.New(counter.Options{})
+	if err != nil {
+		return nil, err
+	}
+	return &Plugin{settings: s, counter: c}, nil
+}
+
+// BuildAnalyzers returns the analyzer of the linter
+func (p *Plugin) BuildAnalyzers() ([]*analysis.Analyzer, error) {
+	return []*analysis.Analyzer{p.Analyzer()}, nil
+}
+
+// GetLoadMode returns the load mode of the linter, which only needs the
+// names of the files of a package
+func (p *Plugin) GetLoadMode() string {
+	return register.LoadModeSyntax
+}
+
+// Analyzer returns the analyzer reporting the files of a package with
+// more than Settings.MaxFileLines lines
+func (p *Plugin) Analyzer() *analysis.Analyzer {
+	return &analysis.Analyzer{
+		Name: "linecounter",
+		Doc:  "reports files with more lines than max-file-lines",
+		Run:  p.run,
+	}
+}
+
+func (p *Plugin) run(pass *analysis.Pass) (any, error) {
+	if p.settings.MaxFileLines == 0 {
+		return nil, nil
+	}
+	for _, file := range pass.Files {
+		path := pass.Fset.Position(file.Package).Filename
+		result, err := p.counter.CountFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if result.Stats.TotalLines > p.settings.MaxFileLines {
+			pass.Reportf(file.Package, "%d lines exceed max-file-lines %d", result.Stats.TotalLines, p.settings.MaxFileLines)
+		}
+	}
+	return nil, nil
+}
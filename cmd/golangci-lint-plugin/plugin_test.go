@@ -0,0 +1,21 @@
+package plugin
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	linter, err := New(map[string]any{"max-file-lines": 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysistest.Run(t, analysistest.TestData(), linter.(*Plugin).Analyzer(), "a")
+}
+
+func TestNewRejectsUnknownSettings(t *testing.T) {
+	if _, err := New(map[string]any{"max-lines": 5}); err == nil {
+		t.Error("New accepted an unknown setting")
+	}
+}
@@ -0,0 +1,8 @@
+package a // want "8 lines exceed max-file-lines 5"
+
+var X1 = 1
+var X2 = 2
+var X3 = 3
+
+// Y is the last
+var Y = 4
@@ -0,0 +1,3 @@
+package a
+
+var Z = 1
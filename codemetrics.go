@@ -0,0 +1,172 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/a2hop/line-counter/counter"
+)
+
+// metricsRuleID identifies the per-file metrics in code scanning output
+const metricsRuleID = "line-counter/file-metrics"
+
+// fileMetrics is the per-file part of the code scanning formats
+type fileMetrics struct {
+	Language     string `json:"language"`
+	TotalLines   int    `json:"totalLines"`
+	CodeLines    int    `json:"codeLines"`
+	CommentLines int    `json:"commentLines"`
+	BlankLines   int    `json:"blankLines"`
+}
+
+// sortedFiles returns the files of stats ordered by path
+func sortedFiles(stats *counter.ProjectStats) []counter.FileResult {
+	files := append([]counter.FileResult(nil), stats.Files...)
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files
+}
+
+func metricsOf(file counter.FileResult) fileMetrics {
+	return fileMetrics{
+		Language:     counter.LanguageName(file.Language),
+		TotalLines:   file.Stats.TotalLines,
+		CodeLines:    file.Stats.CodeLines,
+		CommentLines: file.Stats.CommentLines,
+		BlankLines:   file.Stats.BlankLines,
+	}
+}
+
+func metricsMessage(file counter.FileResult) string {
+	return fmt.Sprintf("%d lines: %d code, %d comments, %d blank",
+		file.Stats.TotalLines, file.Stats.CodeLines, file.Stats.CommentLines, file.Stats.BlankLines)
+}
+
+// sarifText is a SARIF message or description
+type sarifText struct {
+	Text string `json:"text"`
+}
+
+type sarifRule struct {
+	ID               string    `json:"id"`
+	ShortDescription sarifText `json:"shortDescription"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+	} `json:"physicalLocation"`
+}
+
+type sarifResult struct {
+	RuleID     string          `json:"ruleId"`
+	Level      string          `json:"level"`
+	Message    sarifText       `json:"message"`
+	Locations  []sarifLocation `json:"locations"`
+	Properties fileMetrics     `json:"properties"`
+}
+
+type sarifRun struct {
+	Tool struct {
+		Driver struct {
+			Name           string      `json:"name"`
+			InformationURI string      `json:"informationUri"`
+			Rules          []sarifRule `json:"rules"`
+		} `json:"driver"`
+	} `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+// SARIFFormatter writes a SARIF 2.1.0 log with one note-level result per
+// file carrying its line counts, for code scanning platforms
+type SARIFFormatter struct{}
+
+func (SARIFFormatter) Format(w io.Writer, stats *counter.ProjectStats) error {
+	var run sarifRun
+	run.Tool.Driver.Name = "line-counter"
+	run.Tool.Driver.InformationURI = "https://github.com/a2hop/line-counter"
+	run.Tool.Driver.Rules = []sarifRule{{
+		ID:               metricsRuleID,
+		ShortDescription: sarifText{"Line, code, comment and blank line counts of a file"},
+	}}
+	run.Results = []sarifResult{}
+	for _, file := range sortedFiles(stats) {
+		var loc sarifLocation
+		loc.PhysicalLocation.ArtifactLocation.URI = filepath.ToSlash(file.Path)
+		run.Results = append(run.Results, sarifResult{
+			RuleID:     metricsRuleID,
+			Level:      "note",
+			Message:    sarifText{metricsMessage(file)},
+			Locations:  []sarifLocation{loc},
+			Properties: metricsOf(file),
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// CodeClimateIssue is one entry of a Code Climate report, the format GitLab
+// code quality reports use
+type CodeClimateIssue struct {
+	Type        string   `json:"type"`
+	CheckName   string   `json:"check_name"`
+	Description string   `json:"description"`
+	Categories  []string `json:"categories"`
+	Severity    string   `json:"severity"`
+	Fingerprint string   `json:"fingerprint"`
+	Location    struct {
+		Path  string `json:"path"`
+		Lines struct {
+			Begin int `json:"begin"`
+		} `json:"lines"`
+	} `json:"location"`
+	Metrics fileMetrics `json:"metrics"`
+}
+
+// CodeClimateFormatter writes a Code Climate JSON array with one info
+// issue per file carrying its line counts
+type CodeClimateFormatter struct{}
+
+func (CodeClimateFormatter) Format(w io.Writer, stats *counter.ProjectStats) error {
+	issues := []CodeClimateIssue{}
+	for _, file := range sortedFiles(stats) {
+		path := filepath.ToSlash(file.Path)
+		issue := CodeClimateIssue{
+			Type:        "issue",
+			CheckName:   metricsRuleID,
+			Description: metricsMessage(file),
+			Categories:  []string{"Complexity"},
+			Severity:    "info",
+			Metrics:     metricsOf(file),
+		}
+		// The fingerprint only depends on the file, so platforms track one
+		// entry per file across runs
+		sum := sha256.Sum256([]byte(metricsRuleID + ":" + path))
+		issue.Fingerprint = hex.EncodeToString(sum[:16])
+		issue.Location.Path = path
+		issue.Location.Lines.Begin = 1
+		issues = append(issues, issue)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(issues)
+}
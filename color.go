@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Values of --color
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// colorsEnv names the environment variable overriding the colors of
+// defaultTheme, e.g. "language=35:error=4;31"
+const colorsEnv = "LINE_COUNTER_COLORS"
+
+// defaultTheme maps the roles of text output to their ANSI SGR parameters
+var defaultTheme = map[string]string{
+	// title is the header of the report and the titles of its tables
+	"title": "1",
+	// language is the name of a language in a breakdown
+	"language": "36",
+	// total is the TOTAL row of a breakdown and the counts of the summary
+	"total": "1",
+	// error is an exceeded limit or budget
+	"error": "31",
+}
+
+// palette colors text output by role. A nil palette leaves text alone.
+type palette struct {
+	theme map[string]string
+}
+
+// newPalette returns the palette for output to w with --color mode, or nil
+// if the output is not to be colored: with never, and with auto unless w
+// is a terminal other than TERM=dumb and NO_COLOR is unset. The default
+// colors are overridden by $LINE_COUNTER_COLORS.
+func newPalette(mode string, w io.Writer) (*palette, error) {
+	switch mode {
+	case colorNever:
+		return nil, nil
+	case colorAuto:
+		if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" || !isTerminal(w) {
+			return nil, nil
+		}
+	case colorAlways:
+	default:
+		return nil, fmt.Errorf("invalid --color %q (expected auto, always or never)", mode)
+	}
+
+	theme, err := parseTheme(os.Getenv(colorsEnv))
+	if err != nil {
+		return nil, err
+	}
+	return &palette{theme: theme}, nil
+}
+
+// parseTheme returns defaultTheme with the role=parameters entries of
+// spec, separated by colons, applied. An empty parameter turns the color
+// of the role off.
+func parseTheme(spec string) (map[string]string, error) {
+	theme := make(map[string]string, len(defaultTheme))
+	for role, code := range defaultTheme {
+		theme[role] = code
+	}
+	for _, entry := range strings.Split(spec, ":") {
+		if entry == "" {
+			continue
+		}
+		role, code, ok := strings.Cut(entry, "=")
+		if _, known := defaultTheme[role]; !ok || !known {
+			return nil, fmt.Errorf("invalid %s entry %q (expected role=parameters, with a role of title, language, total or error)", colorsEnv, entry)
+		}
+		if strings.Trim(code, "0123456789;") != "" {
+			return nil, fmt.Errorf("invalid %s entry %q: parameters must be numbers separated by semicolons", colorsEnv, entry)
+		}
+		theme[role] = code
+	}
+	return theme, nil
+}
+
+// paint returns s in the color of role
+func (p *palette) paint(role, s string) string {
+	if p == nil || p.theme[role] == "" {
+		return s
+	}
+	return "\033[" + p.theme[role] + "m" + s + "\033[0m"
+}
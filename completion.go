@@ -0,0 +1,197 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/a2hop/line-counter/counter"
+)
+
+// subcommand is a command given as the first argument
+type subcommand struct {
+	name    string
+	summary string
+}
+
+// subcommands lists the commands of the CLI. Without one, the arguments are
+// the paths to count, as with count.
+var subcommands = []subcommand{
+	{"count", "count the paths and print the results (the default)"},
+	{"watch", "count the path and print updated results whenever code files change"},
+	{"diff", "print the per-language change between two baselines or directories"},
+	{"merge", "combine baselines saved with --save-baseline"},
+	{"record", "append the counts of the path to the history file"},
+	{"history", "print the totals of the history file over time"},
+	{"trend", "count the last commit of each interval of the git history and print the counts over time"},
+	{"check", "fail if the code lines below a directory exceed its budget in the budget file"},
+	{"serve", "count the path periodically and serve the counts as Prometheus metrics"},
+	{"completion", "print a bash, zsh or fish completion script"},
+}
+
+// flagValues lists the values completed for the flags that take one of a
+// fixed set
+var flagValues = map[string][]string{
+	"format":      {"text", "json", "csv", "markdown", "html", "sbom", "influxdb", "sarif", "codeclimate", "junit", "ndjson"},
+	"tests":       {counter.TestsInclude, counter.TestsSeparate, counter.TestsExclude},
+	"sort":        {"name", "files", "total", "code", "comments"},
+	"group-by":    {groupByLanguage, groupByExtension},
+	"mixed-as":    {"code", "comment", "mixed"},
+	"meta-as":     {counter.MetaAsSyntax, counter.MetaAsCode, counter.MetaAsComment, counter.MetaAsMeta},
+	"embedded-as": {counter.EmbeddedAsLanguage, counter.EmbeddedAsContainer},
+	"line-ending": {counter.EndingLF, counter.EndingCRLF, "any"},
+	"log-format":  {"text", "json"},
+	"color":       {colorAuto, colorAlways, colorNever},
+	"report":      {"effort", "generated"},
+	"pprof":       {"cpu", "mem"},
+	"columns":     tableColumnNames,
+	"step":        {stepDaily, stepWeekly, stepMonthly},
+}
+
+// usage prints the commands and flags of the CLI
+func usage() {
+	w := flag.CommandLine.Output()
+	fmt.Fprintln(w, "Usage: line-counter [command] [flags] [path ...]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range subcommands {
+		fmt.Fprintf(w, "  %-11s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Flags:")
+	flag.PrintDefaults()
+}
+
+// completionFlag is a flag as the completion scripts describe it
+type completionFlag struct {
+	name, usage string
+	// takesValue is false for boolean flags
+	takesValue bool
+	values     []string
+}
+
+// completionFlags returns the flags of fs in name order
+func completionFlags(fs *flag.FlagSet) []completionFlag {
+	var flags []completionFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		_, usage := flag.UnquoteUsage(f)
+		boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+		flags = append(flags, completionFlag{
+			name:       f.Name,
+			usage:      usage,
+			takesValue: !ok || !boolFlag.IsBoolFlag(),
+			values:     flagValues[f.Name],
+		})
+	})
+	sort.Slice(flags, func(i, j int) bool { return flags[i].name < flags[j].name })
+	return flags
+}
+
+// dashed returns the flag as it is usually spelled: -v for short names,
+// --format for the others
+func (f completionFlag) dashed() string {
+	if len(f.name) <= 2 {
+		return "-" + f.name
+	}
+	return "--" + f.name
+}
+
+// runCompletion implements "line-counter completion bash|zsh|fish"
+func runCompletion(w io.Writer, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("completion: expected one shell: bash, zsh or fish")
+	}
+	flags := completionFlags(flag.CommandLine)
+	switch args[0] {
+	case "bash":
+		writeBashCompletion(w, flags)
+	case "zsh":
+		writeZshCompletion(w, flags)
+	case "fish":
+		writeFishCompletion(w, flags)
+	default:
+		return fmt.Errorf("completion: unknown shell %q (expected bash, zsh or fish)", args[0])
+	}
+	return nil
+}
+
+func subcommandNames() string {
+	names := make([]string, len(subcommands))
+	for i, cmd := range subcommands {
+		names[i] = cmd.name
+	}
+	return strings.Join(names, " ")
+}
+
+func writeBashCompletion(w io.Writer, flags []completionFlag) {
+	var names []string
+	fmt.Fprintln(w, "# bash completion for line-counter")
+	fmt.Fprintln(w, "_line_counter() {")
+	fmt.Fprintln(w, `    local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"`)
+	fmt.Fprintln(w, `    case "$prev" in`)
+	for _, f := range flags {
+		names = append(names, f.dashed())
+		if f.values != nil {
+			fmt.Fprintf(w, "        -%s|--%s) COMPREPLY=($(compgen -W %q -- \"$cur\")); return ;;\n", f.name, f.name, strings.Join(f.values, " "))
+		}
+	}
+	fmt.Fprintln(w, "    esac")
+	fmt.Fprintln(w, `    if [[ "$cur" == -* ]]; then`)
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(names, " "))
+	fmt.Fprintln(w, `    elif [[ $COMP_CWORD -eq 1 ]]; then`)
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W %q -- \"$cur\") $(compgen -f -- \"$cur\"))\n", subcommandNames())
+	fmt.Fprintln(w, "    else")
+	fmt.Fprintln(w, `        COMPREPLY=($(compgen -f -- "$cur"))`)
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "complete -o filenames -F _line_counter line-counter")
+}
+
+// zshEscaper escapes flag descriptions for _arguments specs in single
+// quotes
+var zshEscaper = strings.NewReplacer(`'`, `'\''`, "[", `\[`, "]", `\]`, ":", `\:`)
+
+func writeZshCompletion(w io.Writer, flags []completionFlag) {
+	fmt.Fprintln(w, "#compdef line-counter")
+	fmt.Fprintln(w, "_arguments \\")
+	for _, f := range flags {
+		spec := f.dashed() + "[" + zshEscaper.Replace(f.usage) + "]"
+		switch {
+		case f.values != nil:
+			spec += ":" + f.name + ":(" + strings.Join(f.values, " ") + ")"
+		case f.takesValue:
+			spec += ":" + f.name + ":_files"
+		}
+		fmt.Fprintf(w, "  '%s' \\\n", spec)
+	}
+	fmt.Fprintf(w, "  '1:command or path:{_alternative \"commands:command:(%s)\" \"files:path:_files\"}' \\\n", subcommandNames())
+	fmt.Fprintln(w, "  '*:path:_files'")
+}
+
+// fishEscaper escapes strings in single quotes for fish
+var fishEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+func writeFishCompletion(w io.Writer, flags []completionFlag) {
+	fmt.Fprintln(w, "# fish completion for line-counter")
+	for _, cmd := range subcommands {
+		fmt.Fprintf(w, "complete -c line-counter -n __fish_use_subcommand -a %s -d '%s'\n", cmd.name, fishEscaper.Replace(cmd.summary))
+	}
+	for _, f := range flags {
+		option := "-l " + f.name
+		if len(f.name) == 1 {
+			option = "-s " + f.name
+		} else if len(f.name) == 2 {
+			option = "-o " + f.name
+		}
+		line := fmt.Sprintf("complete -c line-counter %s -d '%s'", option, fishEscaper.Replace(f.usage))
+		switch {
+		case f.values != nil:
+			line += fmt.Sprintf(" -xa '%s'", strings.Join(f.values, " "))
+		case f.takesValue:
+			line += " -r"
+		}
+		fmt.Fprintln(w, line)
+	}
+}
@@ -0,0 +1,255 @@
+package counter
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// archiveSuffixes are the name suffixes of the archives Count reads
+var archiveSuffixes = []string{".zip", ".tar", ".tar.gz", ".tgz"}
+
+// maxArchiveMember is the size above which archive members are skipped,
+// since they are read into memory
+const maxArchiveMember = 256 << 20
+
+// IsArchive reports whether path is a zip, tar or gzipped tar archive that
+// Count reads instead of walking
+func IsArchive(path string) bool {
+	if archiveSuffix(path) == "" {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// archiveSuffix returns the entry of archiveSuffixes that path ends in, or ""
+func archiveSuffix(path string) string {
+	lower := strings.ToLower(path)
+	for _, suffix := range archiveSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return suffix
+		}
+	}
+	return ""
+}
+
+// CountArchive counts the code files inside the zip, tar or gzipped tar
+// archive at archivePath without extracting it. Members are filtered like
+// the files of a walk, except for .gitignore files and --git-tracked, and
+// are reported as paths below archivePath, e.g. src.tar.gz/pkg/main.go.
+// --by-author, --hcl-detail, --random-sample and git ranges do not apply.
+// Like Count, it returns partial stats along with ctx.Err() if ctx is done
+// first.
+func (c *Counter) CountArchive(ctx context.Context, archivePath string) (*ProjectStats, error) {
+	opts := c.archiveOptions()
+	walk, err := archiveJobs(ctx, archivePath, opts)
+	if err != nil {
+		return nil, err
+	}
+	return countMembers(ctx, archivePath, walk, opts)
+}
+
+// countMembers counts the files of an archive or git revision emitted by
+// walk as files below root
+func countMembers(ctx context.Context, root string, walk func(emit func(string, func() fileAnalysis)) error, opts Options) (*ProjectStats, error) {
+	stats := NewProjectStats()
+	_, err := analyzeJobs(ctx, walk, opts, func(a fileAnalysis) {
+		stats.record(a, root, opts)
+	})
+	if err := ctx.Err(); err != nil {
+		stats.Partial = true
+		return stats, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// archiveOptions returns the options for reading an archive, without the
+// ones that need a checkout
+func (c *Counter) archiveOptions() Options {
+	opts := c.opts
+	opts.ByAuthor = false
+	opts.GitTracked = false
+	opts.DiffRange = ""
+	return opts
+}
+
+// archiveJobs returns the walk function for analyzeJobs that emits the
+// members of the archive at archivePath passing the filters of opts
+func archiveJobs(ctx context.Context, archivePath string, opts Options) (func(emit func(string, func() fileAnalysis)) error, error) {
+	return memberJobs(ctx, archivePath, func(fn memberFunc) error {
+		return readArchive(archivePath, fn)
+	}, opts)
+}
+
+// memberFunc is called with the slash-separated name of each file of an
+// archive or git tree, its size, the git object holding its content or ""
+// for archive members, and a function reading it
+type memberFunc func(name string, size int64, object string, read func() ([]byte, error)) error
+
+// memberJobs returns the walk function for analyzeJobs that emits the
+// files listed by members passing the filters of opts, as paths below root
+func memberJobs(ctx context.Context, root string, members func(memberFunc) error, opts Options) (func(emit func(string, func() fileAnalysis)) error, error) {
+	filter, err := newPathFilter(".", opts)
+	if err != nil {
+		return nil, err
+	}
+
+	skipped := make(map[string]bool)
+	return func(emit func(string, func() fileAnalysis)) error {
+		return members(func(name string, size int64, object string, read func() ([]byte, error)) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if inSkippedDir(filter, name, skipped) || !filter.matchPath(name) {
+				return nil
+			}
+			key, ok, script := typeByName(path.Base(name))
+			if !ok && !script {
+				if !opts.AllText {
+					return nil
+				}
+				key = KeyOther
+			}
+
+			memberPath := filepath.Join(root, filepath.FromSlash(name))
+			if size > maxArchiveMember {
+				opts.logger().Warn("skipping large archive member", "path", memberPath, "limit_mib", maxArchiveMember>>20)
+				return nil
+			}
+			// Objects are cached by name too, as it decides how they are
+			// counted; scripts are read for their shebang line first
+			cacheID := object + " " + name
+			if object != "" && opts.cache != nil && !script && filter.matchType(key) {
+				if a, ok := opts.cache.lookupObject(cacheID, memberPath); ok {
+					emit(memberPath, func() fileAnalysis { return a })
+					return nil
+				}
+			}
+			data, err := read()
+			if err != nil {
+				return fmt.Errorf("could not read %s: %v", memberPath, err)
+			}
+			if script {
+				line, _ := bufio.NewReader(bytes.NewReader(data)).ReadString('\n')
+				if key, ok = parseShebang(line); !ok {
+					if !opts.AllText {
+						return nil
+					}
+					key = KeyOther
+				}
+			}
+			if !filter.matchType(key) {
+				return nil
+			}
+
+			emit(memberPath, func() fileAnalysis {
+				a := analyze(memberPath, key, dataSource(data), opts)
+				if object != "" && opts.cache != nil {
+					opts.cache.storeObject(cacheID, a)
+				}
+				return a
+			})
+			return nil
+		})
+	}, nil
+}
+
+// readArchive calls fn for each regular file in the archive at archivePath
+// with its slash-separated name, its size and a function reading it. For
+// tar archives read must be called before fn returns.
+func readArchive(archivePath string, fn memberFunc) error {
+	if archiveSuffix(archivePath) == ".zip" {
+		return readZip(archivePath, fn)
+	}
+	return readTar(archivePath, fn)
+}
+
+func readZip(archivePath string, fn memberFunc) error {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		name, ok := memberName(f.Name)
+		if !ok || !f.Mode().IsRegular() {
+			continue
+		}
+		f := f
+		err := fn(name, int64(f.UncompressedSize64), "", func() ([]byte, error) {
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			return io.ReadAll(rc)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readTar(archivePath string, fn memberFunc) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if suffix := archiveSuffix(archivePath); suffix == ".tar.gz" || suffix == ".tgz" {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("could not read %s: %v", archivePath, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("could not read %s: %v", archivePath, err)
+		}
+		name, ok := memberName(header.Name)
+		if !ok || header.Typeflag != tar.TypeReg {
+			continue
+		}
+		err = fn(name, header.Size, "", func() ([]byte, error) {
+			return io.ReadAll(tr)
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// memberName cleans the name of an archive member into a relative slash
+// path, rejecting names that climb out of the archive
+func memberName(name string) (string, bool) {
+	name = path.Clean(strings.TrimLeft(name, "/"))
+	if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+		return "", false
+	}
+	return name, true
+}
@@ -0,0 +1,151 @@
+package counter
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// BlameEntry attributes a single line of a file to its author
+type BlameEntry struct {
+	Author     string
+	LineNumber int
+	Kind       LineKind
+}
+
+// RunGitBlame runs "git blame --porcelain" on path and returns one entry per
+// counted line, classified the way a count of the file classifies it: by
+// the language its content refines its extension to, after decoding, and
+// by region for files embedding other languages. The lines of notebooks
+// are their cell sources, as saved one per line by Jupyter.
+func RunGitBlame(path string) ([]BlameEntry, error) {
+	opts := Options{ByAuthor: true}
+	a := analyze(path, fileType(path, opts), fileSource(path), opts)
+	if a.skipped {
+		return nil, fmt.Errorf("could not read %s", path)
+	}
+	return blameFile(path, a)
+}
+
+// blameFile attributes the lines of the file at path, measured by a, to
+// the authors git blame finds for them
+func blameFile(path string, a fileAnalysis) ([]BlameEntry, error) {
+	authors, err := blameAuthors(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// lineCounts holds the number of counted lines on each line of the
+	// file, one unless it is a notebook
+	lineCounts := make([]int, len(authors))
+	for i := range lineCounts {
+		lineCounts[i] = 1
+	}
+	if a.ext == ".ipynb" {
+		err := fileSource(path).read(func(r io.Reader) error {
+			data, err := io.ReadAll(r)
+			lineCounts = notebookLineCounts(string(data))
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var entries []BlameEntry
+	kinds := a.lines
+	for i, n := range lineCounts {
+		if i >= len(authors) {
+			break
+		}
+		for ; n > 0 && len(kinds) > 0; n-- {
+			entries = append(entries, BlameEntry{Author: authors[i], LineNumber: i + 1, Kind: kinds[0]})
+			kinds = kinds[1:]
+		}
+	}
+	return entries, nil
+}
+
+// blameAuthors runs "git blame --porcelain" on path and returns the author
+// of each of its lines
+func blameAuthors(path string) ([]string, error) {
+	cmd := exec.Command("git", "-C", filepath.Dir(path), "blame", "--porcelain", "--", filepath.Base(path))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git blame: %s", msg)
+		}
+		return nil, err
+	}
+
+	var lines []string
+	authors := make(map[string]string)
+	var commit string
+	var lineNumber int
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		text := scanner.Text()
+		if strings.HasPrefix(text, "\t") {
+			for len(lines) < lineNumber {
+				lines = append(lines, "")
+			}
+			lines[lineNumber-1] = authors[commit]
+			continue
+		}
+		if author, ok := strings.CutPrefix(text, "author "); ok {
+			authors[commit] = author
+			continue
+		}
+
+		// A line header reads "<sha> <original line> <final line> [<group size>]",
+		// with a SHA-1 or, in SHA-256 repositories, a SHA-256 object name
+		fields := strings.Fields(text)
+		if len(fields) >= 3 && isObjectName(fields[0]) {
+			if n, err := strconv.Atoi(fields[2]); err == nil && n > 0 {
+				commit = fields[0]
+				lineNumber = n
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// isObjectName reports whether s is a full SHA-1 or SHA-256 git object
+// name in hex
+func isObjectName(s string) bool {
+	if len(s) != 40 && len(s) != 64 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !strings.ContainsRune("0123456789abcdef", rune(s[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// addBlame attributes each blamed line to its author in p.AuthorStats
+func (p *ProjectStats) addBlame(entries []BlameEntry, mixedAs string) {
+	for _, entry := range entries {
+		stats := p.AuthorStats[entry.Author]
+		stats.TotalLines++
+		if entry.Kind == BlankLine {
+			stats.BlankLines++
+		} else {
+			stats.addLine(entry.Kind, mixedAs)
+		}
+		p.AuthorStats[entry.Author] = stats
+	}
+}
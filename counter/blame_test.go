@@ -0,0 +1,91 @@
+package counter
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"unicode/utf16"
+)
+
+// TestBlameMatchesCount checks that the lines attributed to authors are
+// classified as the count classifies them, for files embedding other
+// languages, notebooks and UTF-16 files
+func TestBlameMatchesCount(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found in PATH")
+	}
+	for _, format := range []string{"sha1", "sha256"} {
+		t.Run(format, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := exec.Command("git", "init", "-q", "--object-format="+format, dir).Run(); err != nil {
+				t.Skipf("git cannot create %s repositories: %v", format, err)
+			}
+			writeTree(t, dir, map[string]string{
+				"app.vue": "<template>\n  <p>hi</p>\n</template>\n\n<script>\n// state\nexport default {}\n</script>\n",
+				"nb.ipynb": `{
+ "cells": [
+  {
+   "cell_type": "markdown",
+   "metadata": {},
+   "source": [
+    "# Title\n",
+    "\n",
+    "Prose."
+   ]
+  },
+  {
+   "cell_type": "code",
+   "metadata": {},
+   "outputs": [],
+   "source": ["# add\n", "x = 1\n"]
+  }
+ ],
+ "metadata": {},
+ "nbformat": 4,
+ "nbformat_minor": 5
+}
+`,
+			})
+			// UTF-16LE with a byte order mark
+			var utf16Data []byte
+			for _, u := range utf16.Encode([]rune("\ufeff# comment\nx = 1\n")) {
+				utf16Data = append(utf16Data, byte(u), byte(u>>8))
+			}
+			if err := os.WriteFile(filepath.Join(dir, "wide.py"), utf16Data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			git(t, dir, "add", ".")
+			git(t, dir, "commit", "-q", "-m", "init")
+
+			stats, err := Count(context.Background(), dir, Options{ByAuthor: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := stats.AuthorStats["test"]
+			want := stats.TotalStats
+			if got.TotalLines != want.TotalLines || got.CodeLines != want.CodeLines ||
+				got.CommentLines != want.CommentLines || got.BlankLines != want.BlankLines {
+				t.Errorf("author stats = %+v, want the counts of %+v", got, want)
+			}
+			if want.CommentLines != 5 {
+				t.Errorf("counted %d comment lines, want 5", want.CommentLines)
+			}
+		})
+	}
+}
+
+func TestIsObjectName(t *testing.T) {
+	for name, want := range map[string]bool{
+		"0123456789abcdef0123456789abcdef01234567":                         true,
+		"0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef": true,
+		"0123456789abcdef0123456789abcdef0123456":                          false,
+		"0123456789abcdef0123456789abcdef0123456g":                         false,
+		"author-mail <a@example.com>":                                      false,
+	} {
+		if got := isObjectName(name); got != want {
+			t.Errorf("isObjectName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package counter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// BudgetsFile is the name of the budget file read from the project root
+const BudgetsFile = "linecount-budgets.yaml"
+
+// Budget is the most code lines the files below Dir may hold
+type Budget struct {
+	// Dir is a slash-separated directory relative to the project root; "."
+	// is the whole project
+	Dir          string
+	MaxCodeLines int
+}
+
+// BudgetResult is a Budget along with the code lines counted below its Dir
+type BudgetResult struct {
+	Budget
+	CodeLines int
+}
+
+// Over reports whether the code lines exceed the budget
+func (r BudgetResult) Over() bool {
+	return r.CodeLines > r.MaxCodeLines
+}
+
+// LoadBudgets reads the budget file at path, a map of directories to the
+// most code lines each may hold, e.g. "internal/legacy: 20000". Files
+// ending in .json are decoded as JSON, anything else as YAML. The budgets
+// are returned by directory.
+func LoadBudgets(path string) ([]Budget, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &value)
+	} else {
+		value, err = parseYAML(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse budgets %s: %v", path, err)
+	}
+	entries, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("budgets %s: expected a map of directories to code lines", path)
+	}
+
+	budgets := make([]Budget, 0, len(entries))
+	for dir, limit := range entries {
+		max, err := budgetLimit(limit)
+		if err != nil {
+			return nil, fmt.Errorf("budgets %s: %s: %v", path, dir, err)
+		}
+		budgets = append(budgets, Budget{Dir: cleanBudgetDir(dir), MaxCodeLines: max})
+	}
+	sort.Slice(budgets, func(i, j int) bool { return budgets[i].Dir < budgets[j].Dir })
+	return budgets, nil
+}
+
+// budgetLimit decodes the code lines of a budget, a YAML string or a JSON
+// number
+func budgetLimit(value interface{}) (int, error) {
+	var max int
+	switch v := value.(type) {
+	case string:
+		n, err := strconv.Atoi(strings.ReplaceAll(v, "_", ""))
+		if err != nil {
+			return 0, fmt.Errorf("invalid code line budget %q", v)
+		}
+		max = n
+	case float64:
+		max = int(v)
+		if float64(max) != v {
+			return 0, fmt.Errorf("invalid code line budget %v", v)
+		}
+	default:
+		return 0, fmt.Errorf("expected a number of code lines")
+	}
+	if max < 0 {
+		return 0, fmt.Errorf("code line budget must not be negative")
+	}
+	return max, nil
+}
+
+func cleanBudgetDir(dir string) string {
+	dir = path.Clean(filepath.ToSlash(strings.TrimSpace(dir)))
+	return strings.TrimPrefix(dir, "./")
+}
+
+// CheckBudgets returns the code lines below the directory of each budget
+// among the files of stats, counted below root. stats must have been
+// counted with Options.CollectFiles.
+func CheckBudgets(stats *ProjectStats, root string, budgets []Budget) []BudgetResult {
+	results := make([]BudgetResult, len(budgets))
+	for i, budget := range budgets {
+		results[i].Budget = budget
+	}
+	for _, file := range stats.Files {
+		rel := relativePath(root, file.Path)
+		for i, budget := range budgets {
+			if budget.Dir == "." || strings.HasPrefix(rel, budget.Dir+"/") {
+				results[i].CodeLines += file.Stats.CodeLines
+			}
+		}
+	}
+	return results
+}
@@ -0,0 +1,81 @@
+package counter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadBudgets(t *testing.T) {
+	for _, tc := range []struct {
+		name, content string
+		want          []Budget
+		wantErr       string
+	}{
+		{"budgets.yaml", "internal/legacy: 20_000\n./cmd/: 500\n.: 0\n",
+			[]Budget{{".", 0}, {"cmd", 500}, {"internal/legacy", 20000}}, ""},
+		{"budgets.json", `{"pkg": 1200, "web/": 300}`,
+			[]Budget{{"pkg", 1200}, {"web", 300}}, ""},
+		{"budgets.yaml", "pkg: -1\n", nil, "must not be negative"},
+		{"budgets.yaml", "pkg: lots\n", nil, "invalid code line budget"},
+		{"budgets.json", `{"pkg": 1.5}`, nil, "invalid code line budget"},
+		{"budgets.json", `[1, 2]`, nil, "expected a map"},
+	} {
+		path := filepath.Join(t.TempDir(), tc.name)
+		if err := os.WriteFile(path, []byte(tc.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		budgets, err := LoadBudgets(path)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("%q: err = %v, want %q", tc.content, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tc.content, err)
+			continue
+		}
+		if !reflect.DeepEqual(budgets, tc.want) {
+			t.Errorf("%q: budgets = %v, want %v", tc.content, budgets, tc.want)
+		}
+	}
+}
+
+// TestCheckBudgets checks that the code lines of every file count towards
+// each budget whose directory holds it, and only those
+func TestCheckBudgets(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"main.go":                 "package main\n\nfunc main() {}\n",
+		"internal/legacy/old.go":  "package legacy\n\nvar A = 1\nvar B = 2\n",
+		"internal/legacy/x/y.go":  "package x\nvar C = 3\n",
+		"internal/legacyextra.go": "package internal\nvar D = 4\n",
+	})
+	c, err := New(Options{CollectFiles: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := c.Count(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := CheckBudgets(stats, dir, []Budget{{".", 10}, {"internal/legacy", 3}, {"web", 0}})
+	want := []BudgetResult{
+		{Budget{".", 10}, 9},
+		{Budget{"internal/legacy", 3}, 5},
+		{Budget{"web", 0}, 0},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("CheckBudgets() = %v, want %v", results, want)
+	}
+	for i, over := range []bool{false, true, false} {
+		if results[i].Over() != over {
+			t.Errorf("%s: Over() = %v, want %v", results[i].Dir, results[i].Over(), over)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package counter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// cacheVersion changes whenever the way files are measured changes, so
+// caches written by older versions are not reused
+const cacheVersion = 2
+
+// cacheEntry is the measurement of one file stored in the on-disk cache
+type cacheEntry struct {
+	Size     int64     `json:"size"`
+	ModTime  int64     `json:"mod_time"`
+	Ext      string    `json:"ext"`
+	Language string    `json:"language,omitempty"`
+	Stats    FileStats `json:"stats"`
+	Kind     string    `json:"kind,omitempty"`
+	Ending   string    `json:"ending,omitempty"`
+	Dominant string    `json:"dominant,omitempty"`
+	Hash     string    `json:"hash,omitempty"`
+
+	Parts map[string]FileStats `json:"parts,omitempty"`
+}
+
+// fileCache holds the measurements of the files of one root between runs,
+// keyed by path. Files whose size or modification time changed are measured
+// again.
+type fileCache struct {
+	path string
+	// objects is set for a cache of git objects, keyed by object and name
+	// instead. Their content never changes, so entries not used in a run
+	// are kept when it is saved.
+	objects bool
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	// used holds the entries of the files seen in this run, which replace
+	// entries when the cache is saved
+	used map[string]cacheEntry
+}
+
+// openCache loads the cache for counting root with opts from dir. Each
+// root and set of options that affect measurements has its own file. A
+// missing or unreadable cache starts out empty.
+func openCache(dir, root string, opts Options) *fileCache {
+	c := &fileCache{
+		path:    filepath.Join(dir, cacheKey(root, opts)+".json"),
+		entries: make(map[string]cacheEntry),
+		used:    make(map[string]cacheEntry),
+	}
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return c
+	}
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		opts.logger().Warn("ignoring corrupt cache", "path", c.path, "err", err)
+		c.entries = make(map[string]cacheEntry)
+	}
+	return c
+}
+
+// openObjectCache loads the cache of the files of the git repository at
+// repoPath, read from its object database, from dir
+func openObjectCache(dir, repoPath string, opts Options) *fileCache {
+	if abs, err := filepath.Abs(repoPath); err == nil {
+		repoPath = abs
+	}
+	c := openCache(dir, repoPath+"#objects", opts)
+	c.objects = true
+	return c
+}
+
+// cacheKey identifies the cache file for root and the options of opts
+// that change how a file is measured
+func cacheKey(root string, opts Options) string {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	key, _ := json.Marshal(struct {
+		Version          int
+		Root             string
+		MixedAs          string
+		MaxLineBytes     int
+		Complexity       bool
+		GoGenerics       bool
+		ModelicaDetail   bool
+		LicenseHeaders   bool
+		MetaAs           string
+		StyleMetrics     bool
+		LongLine         int
+		CheckLineEndings bool
+		LineEnding       string
+		IncludeGenerated bool
+		GeneratedMarkers []string
+		GeneratedNames   []string
+		NoHeuristics     bool
+		Unique           bool
+		EmbeddedAs       string
+		Languages        []Language
+	}{
+		cacheVersion, root, opts.MixedAs, opts.MaxLineBytes, opts.Complexity,
+		opts.GoGenerics, opts.ModelicaDetail, opts.LicenseHeaders, opts.MetaAs, opts.StyleMetrics, opts.LongLine, opts.CheckLineEndings,
+		opts.LineEnding, opts.IncludeGenerated, opts.GeneratedMarkers, opts.GeneratedNames, opts.NoHeuristics, opts.Unique, opts.EmbeddedAs,
+		registeredLanguages,
+	})
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// lookup returns the cached analysis of the file at path if its size and
+// modification time are unchanged
+func (c *fileCache) lookup(path string, info os.FileInfo) (fileAnalysis, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[path]
+	if !ok || entry.Size != info.Size() || entry.ModTime != info.ModTime().UnixNano() {
+		return fileAnalysis{}, false
+	}
+	c.used[path] = entry
+	return entry.analysis(path), true
+}
+
+// store records the analysis of the file at path, described by info
+func (c *fileCache) store(a fileAnalysis, info os.FileInfo) {
+	entry := entryOf(a)
+	entry.Size = info.Size()
+	entry.ModTime = info.ModTime().UnixNano()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.used[a.path] = entry
+}
+
+// lookupObject returns the cached analysis of the file with the git
+// object id, counted as path
+func (c *fileCache) lookupObject(id, path string) (fileAnalysis, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[id]
+	if !ok {
+		return fileAnalysis{}, false
+	}
+	c.used[id] = entry
+	return entry.analysis(path), true
+}
+
+// storeObject records the analysis of the file with the git object id
+func (c *fileCache) storeObject(id string, a fileAnalysis) {
+	entry := entryOf(a)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.used[id] = entry
+}
+
+// analysis returns the analysis the entry holds for the file at path
+func (e cacheEntry) analysis(path string) fileAnalysis {
+	a := fileAnalysis{
+		path:     path,
+		ext:      e.Ext,
+		language: e.Language,
+		stats:    e.Stats,
+		kind:     e.Kind,
+		ending:   e.Ending,
+		dominant: e.Dominant,
+		parts:    e.Parts,
+	}
+	hex.Decode(a.hash[:], []byte(e.Hash))
+	return a
+}
+
+// entryOf returns the cache entry holding a, without the size and
+// modification time of the file
+func entryOf(a fileAnalysis) cacheEntry {
+	entry := cacheEntry{
+		Ext:      a.ext,
+		Language: a.language,
+		Stats:    a.stats,
+		Kind:     a.kind,
+		Ending:   a.ending,
+		Dominant: a.dominant,
+		Parts:    a.parts,
+	}
+	if a.hash != ([sha256.Size]byte{}) {
+		entry.Hash = hex.EncodeToString(a.hash[:])
+	}
+	return entry
+}
+
+// save writes the entries of the files seen in this run back to disk,
+// dropping those of files that are gone unless the cache is of git objects
+func (c *fileCache) save() error {
+	if c.objects {
+		for id, entry := range c.entries {
+			if _, ok := c.used[id]; !ok {
+				c.used[id] = entry
+			}
+		}
+	}
+	data, err := json.Marshal(c.used)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	// Write to a temporary file first so an interrupted save cannot leave
+	// a truncated cache behind
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path)
+}
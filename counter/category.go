@@ -0,0 +1,47 @@
+package counter
+
+// Category names a bucket of files defined by a Classifier, such as
+// "migrations" or "fixtures"
+type Category string
+
+// Classifier assigns a file to a Category given its path and up to the
+// first ClassifierHeadSize bytes of its content, or returns "" to leave it
+// to the next classifier. Classifiers are called from the worker
+// goroutines, several files at once, so they must be safe for concurrent
+// use.
+type Classifier func(path string, firstKB []byte) Category
+
+// ClassifierHeadSize is how much of a file's content is passed to a
+// Classifier
+const ClassifierHeadSize = 1024
+
+// CategoryStats counts the files of one Category
+type CategoryStats struct {
+	Files int
+	Stats FileStats
+}
+
+// classify returns the category of the first of classifiers that puts the
+// file at path, starting with head, in one, or ""
+func classify(path string, head []byte, classifiers []Classifier) Category {
+	if len(head) > ClassifierHeadSize {
+		head = head[:ClassifierHeadSize]
+	}
+	for _, classifier := range classifiers {
+		if category := classifier(path, head); category != "" {
+			return category
+		}
+	}
+	return ""
+}
+
+// addCategory counts a file with stats in category
+func (p *ProjectStats) addCategory(category Category, stats FileStats) {
+	if p.Categories == nil {
+		p.Categories = make(map[Category]CategoryStats)
+	}
+	c := p.Categories[category]
+	c.Files++
+	c.Stats.Add(stats)
+	p.Categories[category] = c
+}
@@ -0,0 +1,353 @@
+package counter
+
+import (
+	"bytes"
+	"sort"
+)
+
+// LineKind is the classification of a single source line
+type LineKind int
+
+const (
+	BlankLine LineKind = iota
+	CodeLine
+	CommentLine
+	// MixedLine holds code together with a comment, e.g. "x := 1 // set x"
+	MixedLine
+)
+
+// blockComment is a pair of block comment delimiters
+type blockComment struct {
+	start, end string
+}
+
+// quoteSpec describes a string literal delimiter
+type quoteSpec struct {
+	delim string
+	// escapes is set if a backslash escapes the next character
+	escapes bool
+	// multiline is set if the literal may span several lines
+	multiline bool
+}
+
+// syntax describes the comment and string tokens of a language
+type syntax struct {
+	lineComments []string
+	// ownLineComments only start a comment at the beginning of a line
+	ownLineComments []string
+	blockComments   []blockComment
+	// nested is set if block comments nest, as in Rust and Scala
+	nested bool
+	// quotes are tried in order, so longer delimiters must come first
+	quotes []quoteSpec
+	// docStrings are string delimiters that make a string starting a line
+	// a comment, like Python docstrings
+	docStrings []string
+}
+
+var (
+	cBlock  = []blockComment{{"/*", "*/"}}
+	dquote  = quoteSpec{delim: `"`, escapes: true}
+	squote  = quoteSpec{delim: `'`, escapes: true}
+	cQuotes = []quoteSpec{dquote, squote}
+	triple  = quoteSpec{delim: `"""`, multiline: true}
+
+	cSyntax       = &syntax{lineComments: []string{"//"}, blockComments: cBlock, quotes: cQuotes}
+	goSyntax      = &syntax{lineComments: []string{"//"}, blockComments: cBlock, quotes: []quoteSpec{dquote, squote, {delim: "`", multiline: true}}}
+	jsSyntax      = &syntax{lineComments: []string{"//"}, blockComments: cBlock, quotes: []quoteSpec{dquote, squote, {delim: "`", escapes: true, multiline: true}}}
+	phpSyntax     = &syntax{lineComments: []string{"//", "#"}, blockComments: cBlock, quotes: cQuotes}
+	rustSyntax    = &syntax{lineComments: []string{"//"}, blockComments: cBlock, nested: true, quotes: []quoteSpec{{delim: `"`, escapes: true, multiline: true}, squote}}
+	swiftSyntax   = &syntax{lineComments: []string{"//"}, blockComments: cBlock, nested: true, quotes: []quoteSpec{{delim: `"""`, escapes: true, multiline: true}, dquote}}
+	kotlinSyntax  = &syntax{lineComments: []string{"//"}, blockComments: cBlock, nested: true, quotes: []quoteSpec{triple, dquote, squote}}
+	scalaSyntax   = &syntax{lineComments: []string{"//"}, blockComments: cBlock, nested: true, quotes: []quoteSpec{triple, dquote, squote}}
+	cssSyntax     = &syntax{blockComments: cBlock, quotes: cQuotes}
+	scssSyntax    = &syntax{lineComments: []string{"//"}, blockComments: cBlock, quotes: cQuotes}
+	sqlSyntax     = &syntax{lineComments: []string{"--"}, blockComments: cBlock, quotes: []quoteSpec{{delim: `'`}, {delim: `"`}}}
+	pklSyntax     = &syntax{lineComments: []string{"//"}, blockComments: cBlock, quotes: []quoteSpec{triple, dquote}}
+	cueSyntax     = &syntax{lineComments: []string{"//"}, quotes: []quoteSpec{triple, dquote}}
+	nixSyntax     = &syntax{lineComments: []string{"#"}, blockComments: cBlock, quotes: []quoteSpec{{delim: "''", multiline: true}, {delim: `"`, escapes: true, multiline: true}}}
+	hclSyntax     = &syntax{lineComments: []string{"//", "#"}, blockComments: cBlock, quotes: []quoteSpec{dquote}}
+	jsonnetSyntax = &syntax{lineComments: []string{"//", "#"}, blockComments: cBlock, quotes: []quoteSpec{{delim: "|||", multiline: true}, dquote, squote}}
+	pythonSyntax  = &syntax{lineComments: []string{"#"}, quotes: []quoteSpec{{delim: `"""`, escapes: true, multiline: true}, {delim: `'''`, escapes: true, multiline: true}, dquote, squote}, docStrings: []string{`"""`, `'''`}}
+	hashSyntax    = &syntax{lineComments: []string{"#"}, quotes: cQuotes}
+	// Apostrophes are common in prose and shell words here, so only double
+	// quotes delimit strings
+	hashDQSyntax    = &syntax{lineComments: []string{"#"}, quotes: []quoteSpec{dquote}}
+	markupSyntax    = &syntax{blockComments: []blockComment{{"<!--", "-->"}}}
+	mermaidSyntax   = &syntax{ownLineComments: []string{"%%"}}
+	plantumlSyntax  = &syntax{ownLineComments: []string{"'"}, blockComments: []blockComment{{"/'", "'/"}}}
+	matlabSyntax    = &syntax{lineComments: []string{"%"}, blockComments: []blockComment{{"%{", "%}"}}, quotes: []quoteSpec{dquote}}
+	cmakeSyntax     = &syntax{lineComments: []string{"#"}, blockComments: []blockComment{{"#[[", "]]"}}, quotes: []quoteSpec{{delim: `"`, escapes: true, multiline: true}}}
+	graphqlSyntax   = &syntax{lineComments: []string{"#"}, quotes: []quoteSpec{{delim: `"""`, multiline: true}, dquote}}
+	prologSyntax    = &syntax{lineComments: []string{"%"}, blockComments: cBlock, quotes: cQuotes}
+	modelicaSyntax  = &syntax{lineComments: []string{"//"}, blockComments: cBlock, quotes: []quoteSpec{dquote}}
+	plainCodeSyntax = &syntax{}
+)
+
+// languageSyntax maps code extensions to their comment and string syntax.
+// Extensions without an entry count every non-blank line as code.
+var languageSyntax = map[string]*syntax{
+	".go":        goSyntax,
+	".js":        jsSyntax,
+	".ts":        jsSyntax,
+	".jsx":       jsSyntax,
+	".tsx":       jsSyntax,
+	".java":      cSyntax,
+	".c":         cSyntax,
+	".cpp":       cSyntax,
+	".cc":        cSyntax,
+	".h":         cSyntax,
+	".hpp":       cSyntax,
+	".cs":        cSyntax,
+	".m":         cSyntax,
+	".php":       phpSyntax,
+	".rs":        rustSyntax,
+	".swift":     swiftSyntax,
+	".kt":        kotlinSyntax,
+	".scala":     scalaSyntax,
+	".css":       cssSyntax,
+	".scss":      scssSyntax,
+	".sql":       sqlSyntax,
+	".mo":        modelicaSyntax,
+	".pkl":       pklSyntax,
+	".cue":       cueSyntax,
+	".nix":       nixSyntax,
+	".jsonnet":   jsonnetSyntax,
+	".libsonnet": jsonnetSyntax,
+	".tf":        hclSyntax,
+	".tfvars":    hclSyntax,
+	".hcl":       hclSyntax,
+	".py":        pythonSyntax,
+	".r":         hashSyntax,
+	".sh":        hashSyntax,
+	".bash":      hashSyntax,
+	".rb":        hashSyntax,
+	".pl":        hashSyntax,
+	".pm":        hashSyntax,
+	".yaml":      hashSyntax,
+	".yml":       hashSyntax,
+	".toml":      hashSyntax,
+	".rego":      hashSyntax,
+	".kcl":       hashSyntax,
+	".feature":   hashDQSyntax,
+	"Makefile":   hashDQSyntax,
+	"Dockerfile": hashDQSyntax,
+	".mk":        hashDQSyntax,
+	".cmake":     cmakeSyntax,
+	".proto":     cSyntax,
+	".graphql":   graphqlSyntax,
+	".gql":       graphqlSyntax,
+	".html":      markupSyntax,
+	".xml":       markupSyntax,
+	".bpmn":      markupSyntax,
+	".cmmn":      markupSyntax,
+	".mmd":       mermaidSyntax,
+	".mermaid":   mermaidSyntax,
+	keyMATLAB:    matlabSyntax,
+	keyProlog:    prologSyntax,
+	".puml":      plantumlSyntax,
+	".plantuml":  plantumlSyntax,
+}
+
+// lineClassifier classifies the lines of one file in order. It scans each
+// line token by token, carrying open block comments (and their nesting
+// depth) and multi-line strings over to the next line.
+type lineClassifier struct {
+	syntax *syntax
+
+	// block is the open block comment while depth > 0
+	block blockComment
+	depth int
+	// quote is the open multi-line string, if any
+	quote *quoteSpec
+
+	// starts marks the bytes that may begin a comment or string, so runs
+	// of other code can be skipped at once
+	starts [256]bool
+}
+
+func newLineClassifier(ext string) *lineClassifier {
+	c := &lineClassifier{syntax: plainCodeSyntax}
+	if s, ok := customLanguages[ext]; ok {
+		c.syntax = s
+	} else if s, ok := languageSyntax[ext]; ok {
+		c.syntax = s
+	}
+	for _, block := range c.syntax.blockComments {
+		c.starts[block.start[0]] = true
+	}
+	for _, comment := range c.syntax.lineComments {
+		c.starts[comment[0]] = true
+	}
+	for _, quote := range c.syntax.quotes {
+		c.starts[quote.delim[0]] = true
+	}
+	return c
+}
+
+// customSyntax returns the syntax of a language registered with
+// RegisterLanguage
+func customSyntax(lang Language) *syntax {
+	s := &syntax{lineComments: lang.LineComments, nested: lang.NestedComments, quotes: []quoteSpec{dquote}}
+	if len(lang.BlockComment) == 2 {
+		s.blockComments = []blockComment{{lang.BlockComment[0], lang.BlockComment[1]}}
+	}
+	if len(lang.Quotes) > 0 {
+		s.quotes = nil
+		for _, delim := range lang.Quotes {
+			s.quotes = append(s.quotes, quoteSpec{delim: delim, escapes: true})
+		}
+		// Longer delimiters must be tried first
+		sort.SliceStable(s.quotes, func(i, j int) bool { return len(s.quotes[i].delim) > len(s.quotes[j].delim) })
+	}
+	return s
+}
+
+// classify returns the kind of line, which must already be trimmed of
+// surrounding whitespace. A line counts as a comment only if it holds
+// nothing but comments; any code outside comments makes it a mixed line.
+func (c *lineClassifier) classify(line []byte) LineKind {
+	if len(line) == 0 {
+		return BlankLine
+	}
+
+	s := c.syntax
+	if c.depth == 0 && c.quote == nil && hasAnyPrefix(line, s.ownLineComments) != "" {
+		return CommentLine
+	}
+
+	code, comment := false, false
+	i := 0
+	if c.depth == 0 && c.quote == nil {
+		if start, delim := docStringStart(line, s.docStrings); delim != "" {
+			comment = true
+			c.block = blockComment{delim, delim}
+			c.depth = 1
+			i = start + len(delim)
+		}
+	}
+	for i < len(line) {
+		switch {
+		case c.depth > 0:
+			comment = true
+			if !s.nested {
+				// Only the end of the comment matters
+				end := bytes.Index(line[i:], []byte(c.block.end))
+				if end < 0 {
+					i = len(line)
+				} else {
+					c.depth--
+					i += end + len(c.block.end)
+				}
+			} else if bytes.HasPrefix(line[i:], []byte(c.block.start)) {
+				c.depth++
+				i += len(c.block.start)
+			} else if bytes.HasPrefix(line[i:], []byte(c.block.end)) {
+				c.depth--
+				i += len(c.block.end)
+			} else {
+				i++
+			}
+		case c.quote != nil:
+			code = true
+			if c.quote.escapes && line[i] == '\\' {
+				i += 2
+			} else if bytes.HasPrefix(line[i:], []byte(c.quote.delim)) {
+				i += len(c.quote.delim)
+				c.quote = nil
+			} else {
+				// Skip to the next escape or possible end of the string
+				i++
+				for i < len(line) && line[i] != c.quote.delim[0] && (line[i] != '\\' || !c.quote.escapes) {
+					i++
+				}
+			}
+		case line[i] == ' ' || line[i] == '\t':
+			i++
+		case !c.starts[line[i]]:
+			// Nothing up to the next byte that may begin a comment or
+			// string can change the kind of line
+			code = true
+			i++
+			for i < len(line) && !c.starts[line[i]] {
+				i++
+			}
+		default:
+			// Block comments are tried first, since their start may begin
+			// with a line comment marker, as with Lua's --[[
+			if block, ok := c.openBlock(line[i:]); ok {
+				comment = true
+				c.block = block
+				c.depth = 1
+				i += len(block.start)
+				continue
+			}
+			if hasAnyPrefix(line[i:], s.lineComments) != "" {
+				comment = true
+				i = len(line)
+				continue
+			}
+			code = true
+			if q := c.openQuote(line[i:]); q != nil {
+				c.quote = q
+				i += len(q.delim)
+				continue
+			}
+			i++
+		}
+	}
+
+	// Ordinary strings end with the line even if unterminated
+	if c.quote != nil && !c.quote.multiline {
+		c.quote = nil
+	}
+
+	switch {
+	case code && comment:
+		return MixedLine
+	case comment:
+		return CommentLine
+	default:
+		return CodeLine
+	}
+}
+
+// openBlock returns the block comment starting at the beginning of rest
+func (c *lineClassifier) openBlock(rest []byte) (blockComment, bool) {
+	for _, block := range c.syntax.blockComments {
+		if bytes.HasPrefix(rest, []byte(block.start)) {
+			return block, true
+		}
+	}
+	return blockComment{}, false
+}
+
+// openQuote returns the string delimiter starting at the beginning of rest
+func (c *lineClassifier) openQuote(rest []byte) *quoteSpec {
+	for i := range c.syntax.quotes {
+		if bytes.HasPrefix(rest, []byte(c.syntax.quotes[i].delim)) {
+			return &c.syntax.quotes[i]
+		}
+	}
+	return nil
+}
+
+// docStringStart returns the offset and delimiter of a docstring opening
+// line, allowing Python's r and u string prefixes, or "" if there is none
+func docStringStart(line []byte, delims []string) (int, string) {
+	start := 0
+	if len(delims) > 0 && bytes.ContainsAny(line[:1], "rRuU") {
+		start = 1
+	}
+	return start, hasAnyPrefix(line[start:], delims)
+}
+
+// hasAnyPrefix returns the first of prefixes that s starts with, or ""
+func hasAnyPrefix(s []byte, prefixes []string) string {
+	for _, prefix := range prefixes {
+		if bytes.HasPrefix(s, []byte(prefix)) {
+			return prefix
+		}
+	}
+	return ""
+}
@@ -0,0 +1,148 @@
+package counter
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// CodeownersFiles are the places of the CODEOWNERS file below a project
+// root, in the order GitHub looks for it
+var CodeownersFiles = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// Unowned is the owner of the files no CODEOWNERS rule matches
+const Unowned = "(unowned)"
+
+// ownerRule is a pattern of a CODEOWNERS file and the owners of the files
+// it matches
+type ownerRule struct {
+	pattern ignorePattern
+	owners  []string
+}
+
+// Codeowners assigns files to owners with the rules of a CODEOWNERS file.
+// As in the file, the last rule matching a file wins.
+type Codeowners struct {
+	rules []ownerRule
+}
+
+// FindCodeowners returns the path of the CODEOWNERS file of the project at
+// root, or "" if it has none
+func FindCodeowners(root string) string {
+	for _, name := range CodeownersFiles {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path
+		}
+	}
+	return ""
+}
+
+// LoadCodeowners reads the CODEOWNERS file at path. Each line holds a
+// gitignore-style pattern followed by its owners, such as teams or users;
+// a pattern without owners leaves its files unowned. GitLab section
+// headers are skipped.
+func LoadCodeowners(path string) (*Codeowners, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	c := &Codeowners{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") ||
+			strings.HasPrefix(fields[0], "[") || strings.HasPrefix(fields[0], "^[") {
+			continue
+		}
+		pattern, ok := compileIgnorePattern(fields[0])
+		if !ok || pattern.negate {
+			continue
+		}
+		rule := ownerRule{pattern: pattern}
+		for _, owner := range fields[1:] {
+			if strings.HasPrefix(owner, "#") {
+				break
+			}
+			rule.owners = append(rule.owners, owner)
+		}
+		c.rules = append(c.rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Owners returns the owners of the file at relPath, slash-separated and
+// relative to the project root, or nil if it is unowned
+func (c *Codeowners) Owners(relPath string) []string {
+	parts := strings.Split(strings.Trim(relPath, "/"), "/")
+	for i := len(c.rules) - 1; i >= 0; i-- {
+		rule := c.rules[i]
+		// A rule matches the file or any of its directories
+		for j := len(parts); j > 0; j-- {
+			isDir := j < len(parts)
+			if rule.pattern.dirOnly && !isDir {
+				continue
+			}
+			if rule.pattern.re.MatchString(strings.Join(parts[:j], "/")) {
+				return rule.owners
+			}
+		}
+	}
+	return nil
+}
+
+// OwnerStats counts the files of one owner
+type OwnerStats struct {
+	Owner string
+	Files int
+	Stats FileStats
+}
+
+// CountByOwner returns the counts of the files of stats, counted below
+// root, by owner, most code lines first with the Unowned files last. A
+// file with several owners counts fully for each. stats must have been
+// counted with Options.CollectFiles.
+func CountByOwner(stats *ProjectStats, root string, owners *Codeowners) []OwnerStats {
+	byOwner := make(map[string]*OwnerStats)
+	add := func(owner string, file FileResult) {
+		s := byOwner[owner]
+		if s == nil {
+			s = &OwnerStats{Owner: owner}
+			byOwner[owner] = s
+		}
+		s.Files++
+		s.Stats.Add(file.Stats)
+	}
+	for _, file := range stats.Files {
+		fileOwners := owners.Owners(relativePath(root, file.Path))
+		if len(fileOwners) == 0 {
+			add(Unowned, file)
+		}
+		for _, owner := range fileOwners {
+			add(owner, file)
+		}
+	}
+
+	results := make([]OwnerStats, 0, len(byOwner))
+	for _, s := range byOwner {
+		results = append(results, *s)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if (a.Owner == Unowned) != (b.Owner == Unowned) {
+			return b.Owner == Unowned
+		}
+		if a.Stats.CodeLines != b.Stats.CodeLines {
+			return a.Stats.CodeLines > b.Stats.CodeLines
+		}
+		return a.Owner < b.Owner
+	})
+	return results
+}
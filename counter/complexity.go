@@ -0,0 +1,73 @@
+package counter
+
+import "bytes"
+
+// Branch tokens counted by Options.Complexity. Words only match as whole
+// words; operators match anywhere on a code line.
+var (
+	cBranches      = []string{"if", "for", "while", "case", "catch", "&&", "||"}
+	goBranches     = []string{"if", "for", "case", "&&", "||"}
+	rustBranches   = []string{"if", "for", "while", "loop", "match", "&&", "||"}
+	swiftBranches  = []string{"if", "guard", "for", "while", "case", "catch", "&&", "||"}
+	kotlinBranches = []string{"if", "for", "while", "when", "catch", "&&", "||"}
+	pythonBranches = []string{"if", "elif", "for", "while", "except", "and", "or"}
+	rubyBranches   = []string{"if", "elsif", "unless", "for", "while", "until", "when", "rescue", "&&", "||"}
+	shellBranches  = []string{"if", "elif", "for", "while", "until", "case", "&&", "||"}
+	sqlBranches    = []string{"WHEN", "when", "AND", "and", "OR", "or"}
+)
+
+// complexityBranches maps code extensions to the tokens that open a branch
+// in their language. Extensions without an entry have no complexity.
+var complexityBranches = map[string][]string{
+	".go":    goBranches,
+	".js":    cBranches,
+	".ts":    cBranches,
+	".jsx":   cBranches,
+	".tsx":   cBranches,
+	".java":  cBranches,
+	".c":     cBranches,
+	".cpp":   cBranches,
+	".cc":    cBranches,
+	".h":     cBranches,
+	".hpp":   cBranches,
+	".cs":    cBranches,
+	".m":     cBranches,
+	".php":   append([]string{"elseif"}, cBranches...),
+	".scala": append([]string{"match"}, cBranches...),
+	".rs":    rustBranches,
+	".swift": swiftBranches,
+	".kt":    kotlinBranches,
+	".py":    pythonBranches,
+	".rb":    rubyBranches,
+	".sh":    shellBranches,
+	".bash":  shellBranches,
+	".sql":   sqlBranches,
+}
+
+// countBranches returns how many of branches occur in line
+func countBranches(line []byte, branches []string) int {
+	count := 0
+	for _, branch := range branches {
+		if !isWordByte(branch[0]) {
+			count += bytes.Count(line, []byte(branch))
+			continue
+		}
+		for from := 0; ; {
+			i := bytes.Index(line[from:], []byte(branch))
+			if i < 0 {
+				break
+			}
+			start := from + i
+			end := start + len(branch)
+			if (start == 0 || !isWordByte(line[start-1])) && (end == len(line) || !isWordByte(line[end])) {
+				count++
+			}
+			from = end
+		}
+	}
+	return count
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z') || ('0' <= b && b <= '9')
+}
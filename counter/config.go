@@ -0,0 +1,238 @@
+package counter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ConfigFileNames are the config files looked for in a project root, in order
+var ConfigFileNames = []string{".linecounter.yaml", ".linecounter.yml", ".linecounter.json"}
+
+// Config customises the extensions, languages and ignored directories.
+// It is read from YAML or JSON, e.g.
+//
+//	extensions:
+//	  add: [.ex, .exs]
+//	  remove: [.json]
+//	languages:
+//	  - name: Erlang
+//	    extensions: [.erl, .hrl]
+//	    line_comments: ["%"]
+//	ignore_dirs: [_build, deps]
+//	groups:
+//	  Erlang: [.erl, .hrl, Elixir]
+//	nested_git: skip
+type Config struct {
+	Extensions struct {
+		// Add counts files with these extensions as code
+		Add []string `json:"add"`
+		// Remove stops counting files with these extensions
+		Remove []string `json:"remove"`
+	} `json:"extensions"`
+
+	// Languages defines the comment syntax of new or existing extensions
+	Languages []Language `json:"languages"`
+
+	// IgnoreDirs adds directory names to IgnoreDirs
+	IgnoreDirs []string `json:"ignore_dirs"`
+
+	// Groups reports the members of each group, extensions or language
+	// names, in one row named after the group
+	Groups map[string][]string `json:"groups"`
+
+	// NestedGit is NestedGitSkip to skip nested git repositories, as
+	// Options.SkipNestedGit does, or NestedGitRoots to count them as
+	// separate roots; "" counts them like any directory
+	NestedGit string `json:"nested_git"`
+}
+
+// Values of Config.NestedGit
+const (
+	NestedGitSkip  = "skip"
+	NestedGitRoots = "roots"
+)
+
+// Language describes the comment syntax shared by a set of extensions
+type Language struct {
+	Name       string   `json:"name"`
+	Extensions []string `json:"extensions"`
+
+	// LineComments are the markers that start a comment running to the end
+	// of the line, e.g. "#"
+	LineComments []string `json:"line_comments"`
+
+	// BlockComment holds the start and end markers of block comments,
+	// e.g. ["/*", "*/"], or is empty if the language has none
+	BlockComment []string `json:"block_comment"`
+
+	// NestedComments is set if block comments nest, as in Rust
+	NestedComments bool `json:"nested_comments"`
+
+	// Quotes are the delimiters of string literals, in which comment
+	// markers do not count, e.g. ["\"", "'"]. A backslash escapes the next
+	// character. Double quotes are used if Quotes is empty.
+	Quotes []string `json:"quotes"`
+}
+
+// validate checks that lang can be registered
+func (lang Language) validate() error {
+	if lang.Name == "" {
+		return fmt.Errorf("language without a name")
+	}
+	if len(lang.Extensions) == 0 {
+		return fmt.Errorf("language %s has no extensions", lang.Name)
+	}
+	if len(lang.BlockComment) != 0 && len(lang.BlockComment) != 2 {
+		return fmt.Errorf("language %s: block_comment needs a start and an end marker", lang.Name)
+	}
+	for _, markers := range [][]string{lang.LineComments, lang.BlockComment, lang.Quotes} {
+		for _, marker := range markers {
+			if marker == "" {
+				return fmt.Errorf("language %s: empty comment or quote marker", lang.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// customLanguages holds the syntax of the languages registered with
+// RegisterLanguage, by extension. They take precedence over the built-in
+// comment syntax.
+var customLanguages = map[string]*syntax{}
+
+// registeredLanguages lists the languages passed to RegisterLanguage, in
+// order, for cache keys
+var registeredLanguages []Language
+
+// RegisterLanguage counts files with the extensions of lang as code under
+// the name and comment syntax of lang, replacing the built-in syntax of an
+// existing extension. Config files register their languages this way.
+//
+// The registration changes package-level tables shared by every Counter
+// for the rest of the process, so it is not safe for concurrent use: call
+// it before any counting starts, never while a count is running.
+func RegisterLanguage(lang Language) error {
+	if err := lang.validate(); err != nil {
+		return err
+	}
+	registeredLanguages = append(registeredLanguages, lang)
+	s := customSyntax(lang)
+	for _, ext := range lang.Extensions {
+		ext = normalizeExt(ext)
+		CodeExtensions[ext] = true
+		LanguageNames[ext] = lang.Name
+		customLanguages[ext] = s
+	}
+	return nil
+}
+
+// FindConfig returns the path of the first of ConfigFileNames present in
+// root, or "" if there is none
+func FindConfig(root string) string {
+	for _, name := range ConfigFileNames {
+		path := filepath.Join(root, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// LoadConfig reads the config file at path. Files ending in .json are
+// decoded as JSON, anything else as YAML.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.EqualFold(filepath.Ext(path), ".json") {
+		value, err := parseYAML(data)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse config %s: %v", path, err)
+		}
+		if data, err = json.Marshal(value); err != nil {
+			return nil, err
+		}
+	}
+
+	var cfg Config
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("could not parse config %s: %v", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("config %s: %v", path, err)
+	}
+	return &cfg, nil
+}
+
+func (c *Config) validate() error {
+	for _, lang := range c.Languages {
+		if err := lang.validate(); err != nil {
+			return err
+		}
+	}
+	for group, members := range c.Groups {
+		if group == "" {
+			return fmt.Errorf("group without a name")
+		}
+		for _, member := range members {
+			if member == "" || member == "." {
+				return fmt.Errorf("group %s: empty member", group)
+			}
+		}
+	}
+	switch c.NestedGit {
+	case "", NestedGitSkip, NestedGitRoots:
+	default:
+		return fmt.Errorf("invalid nested_git %q (expected skip or roots)", c.NestedGit)
+	}
+	return nil
+}
+
+// Apply registers the extensions, languages and ignored directories of c
+// with the package. NestedGit is left to the caller, as it sets Options.
+//
+// Like RegisterLanguage, Apply changes the package-level CodeExtensions,
+// IgnoreDirs and group tables shared by every Counter, so it is not safe
+// for concurrent use and must be called before any counting starts.
+func (c *Config) Apply() {
+	for _, ext := range c.Extensions.Add {
+		CodeExtensions[normalizeExt(ext)] = true
+	}
+	for _, lang := range c.Languages {
+		// Validated by LoadConfig
+		RegisterLanguage(lang)
+	}
+	// Removals win, so a config can drop a built-in extension outright
+	for _, ext := range c.Extensions.Remove {
+		delete(CodeExtensions, normalizeExt(ext))
+	}
+	for _, dir := range c.IgnoreDirs {
+		IgnoreDirs[dir] = true
+	}
+	for group, members := range c.Groups {
+		for _, member := range members {
+			if strings.HasPrefix(member, ".") {
+				ExtensionGroups[normalizeExt(member)] = group
+			} else {
+				LanguageGroups[member] = group
+			}
+		}
+	}
+}
+
+// normalizeExt lowercases ext and adds the leading dot if it is missing
+func normalizeExt(ext string) string {
+	ext = strings.ToLower(ext)
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
@@ -0,0 +1,581 @@
+// Package counter counts lines of code, comments and blank lines in a
+// source tree. It is the engine behind the line-counter command and can be
+// embedded by other Go programs:
+//
+//	stats, err := counter.Count(ctx, ".", counter.Options{})
+//	if err != nil {
+//		return err
+//	}
+//	fmt.Println(stats.TotalStats.CodeLines)
+package counter
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Options selects which files are counted and what is measured. The zero
+// value counts every code file below the root, honouring .gitignore files,
+// with one worker per CPU.
+type Options struct {
+	// IgnorePathsFrom names a file of gitignore-style patterns to skip
+	IgnorePathsFrom string
+	// GitTracked only counts files in the git index
+	GitTracked bool
+	// DiffRange only counts files changed in a git range, either "A..B" or
+	// a single ref compared to the working tree, and records the lines the
+	// diff adds and removes in ProjectStats.Changes. The files are counted
+	// as they are on disk.
+	DiffRange string
+	// Git lists the files to count with git ls-files instead of walking
+	// the filesystem, falling back to the walk outside a git repository
+	Git bool
+	// MaxDepth only counts files at most this many levels below the root:
+	// 1 counts the files directly in the root, 2 also those in its
+	// subdirectories and so on. 0 means no limit.
+	MaxDepth int
+	// SkipTestDirs skips the directories listed in TestDirs
+	SkipTestDirs bool
+	// SkipNestedGit skips the directories below the root holding a .git
+	// directory or file, such as submodules and vendored repositories,
+	// and lists them in ProjectStats.NestedRepos
+	SkipNestedGit bool
+	// NoGitignore counts files even if .gitignore files exclude them
+	NoGitignore bool
+	// FollowSymlinks walks into symlinked directories, skipping links that
+	// lead back to a directory already walked. By default symlinked files
+	// are counted but symlinked directories are not.
+	FollowSymlinks bool
+	// NoSymlinks skips symlinks altogether
+	NoSymlinks bool
+	// AllText also counts the text files that are not code by name or
+	// shebang, every non-blank line as code, under KeyOther. Binary files
+	// are still left out by their content.
+	AllText bool
+	// Markdown counts the files of MarkdownExtensions, which are mostly
+	// prose that would otherwise dwarf the comments of a project
+	Markdown bool
+	// IncludeGenerated counts minified and generated files like any other;
+	// by default they are only tallied in ProjectStats.Generated
+	IncludeGenerated bool
+	// GeneratedMarkers and GeneratedNames add to the markers and the
+	// GeneratedNames patterns that make a file generated
+	GeneratedMarkers []string
+	GeneratedNames   []string
+	// NoHeuristics counts files under their extension alone, without
+	// looking at their content to tell languages sharing an extension
+	// apart, e.g. C, C++ and Objective-C headers
+	NoHeuristics bool
+	// Unique counts files with identical content, such as vendored copies
+	// and hard links, once; the copies are tallied in
+	// ProjectStats.Duplicates
+	Unique bool
+	// Profile restricts the count to the extensions of a Profiles entry
+	Profile string
+	// Exclude skips paths matching any of these doublestar globs
+	Exclude []string
+	// Include, if non-empty, only counts files matching one of these globs
+	Include []string
+	// FileMinLines and FileMaxLines, if non-zero, only count files with at
+	// least and at most this many lines; the others are tallied in
+	// ProjectStats.FilteredFiles
+	FileMinLines int
+	FileMaxLines int
+
+	// RandomSample estimates the line counts from this many randomly
+	// chosen files when non-zero. ProjectStats.Errors then lists the
+	// directories that could not be walked and the sampled files that
+	// could not be read.
+	RandomSample int
+	// Jobs is the number of files counted in parallel; 0 means one per CPU
+	Jobs int
+
+	// HCLDetail counts Terraform resource and data blocks
+	HCLDetail bool
+	// GoGenerics counts Go lines declaring type parameters
+	GoGenerics bool
+	// ByAuthor attributes lines to their git blame author
+	ByAuthor bool
+	// MixedAs counts lines holding code and a comment as "code" (the
+	// default), "comment" or only as "mixed"
+	MixedAs string
+	// ModelicaDetail counts Modelica annotation(...) lines separately
+	ModelicaDetail bool
+	// MetaAs selects how the "#!" first lines of scripts and the YAML or
+	// TOML front matter of Markdown files are counted: MetaAsSyntax ("" or
+	// "syntax") like any other line of the file, or in FileStats.MetaLines
+	// and, with MetaAsCode or MetaAsComment, also as code or comments
+	MetaAs string
+	// LicenseHeaders counts the comment blocks at the top of files that
+	// mention a license or copyright in FileStats.LicenseHeaderLines
+	LicenseHeaders bool
+	// StyleMetrics counts long lines, lines with trailing whitespace and
+	// lines indented with both tabs and spaces. LongLine is the length in
+	// characters above which a line is long (0 for DefaultLongLine).
+	StyleMetrics bool
+	LongLine     int
+
+	// EmbeddedAs selects where the lines of languages embedded in other
+	// files, such as the fenced code blocks of Markdown files and the
+	// scripts and styles of Vue and Svelte components, are counted:
+	// EmbeddedAsLanguage ("" or "language") counts them under their own
+	// language, EmbeddedAsContainer under the containing file's
+	EmbeddedAs string
+
+	// Complexity estimates the cyclomatic complexity of each file by
+	// counting branch keywords
+	Complexity bool
+	// MaxLineBytes is the length up to which a line is classified; longer
+	// lines are still counted, by their start. 0 means DefaultMaxLineBytes.
+	MaxLineBytes int
+	// Tests selects how test files (see IsTestFile) are counted:
+	// TestsInclude ("" or "include") counts them like any other file,
+	// TestsSeparate counts them into ProjectStats.Tests and TestsExclude
+	// skips them
+	Tests string
+	// CheckLineEndings records the line endings of every file
+	CheckLineEndings bool
+	// LineEnding only counts files whose line endings are "lf" or "crlf";
+	// "" or "any" counts all files
+	LineEnding string
+
+	// CollectFiles fills in ProjectStats.Files
+	CollectFiles bool
+	// OnFile, if set, is called with the result of each counted file as it
+	// is added to the totals, in walk order and from a single goroutine.
+	// Without CollectFiles it lets the results of huge trees be written out
+	// while only the totals are kept in memory.
+	OnFile func(FileResult)
+
+	// Classifiers put counted files into caller-defined categories, totaled
+	// in ProjectStats.Categories; the first one returning a category wins.
+	// Files are still counted under their language. Since the classifiers
+	// cannot be compared between runs, Cache is not used with them.
+	Classifiers []Classifier
+
+	// Cache names a directory keeping the measurements of files between
+	// counts, so files whose size and modification time are unchanged are
+	// not read again. It is not used with ByAuthor or RandomSample.
+	Cache string
+	// cache is the cache opened for the current count
+	cache *fileCache
+
+	// Strict stops a walk at the first directory that cannot be read and
+	// fails a count if any file could not be read. By default they are
+	// skipped with a warning and listed in ProjectStats.Errors.
+	Strict bool
+	// errors collects the paths skipped in the current count
+	errors *errorLog
+	// nestedRepos collects the repositories skipped with SkipNestedGit in
+	// the current walk
+	nestedRepos *[]string
+	// walkedDirs collects the directories entered by the current walk
+	walkedDirs *[]string
+	// lineKinds collects the kind of each line of the file being counted,
+	// in the order they are counted, for ByAuthor
+	lineKinds *[]LineKind
+
+	// Logger receives warnings about files that could not be read and, at
+	// slog.LevelInfo and below, why files and directories were skipped and
+	// how each file's language was detected. nil prints warnings to stderr
+	// with NewLogHandler.
+	Logger *slog.Logger
+
+	// Progress, if set, is called after each file is counted. Calls are
+	// made from a single goroutine.
+	Progress func(Progress)
+}
+
+// Progress reports how far a count has got
+type Progress struct {
+	// Files and Lines are the files and lines counted so far
+	Files int
+	Lines int
+	// Path is the file counted last
+	Path string
+}
+
+// Validate reports an error for option values that are not understood
+func (o Options) Validate() error {
+	switch o.MixedAs {
+	case "", "code", "comment", "mixed":
+	default:
+		return fmt.Errorf("invalid --mixed-as %q (expected code, comment or mixed)", o.MixedAs)
+	}
+
+	if o.LongLine < 0 {
+		return fmt.Errorf("--long-line must not be negative")
+	}
+
+	switch o.MetaAs {
+	case "", MetaAsSyntax, MetaAsCode, MetaAsComment, MetaAsMeta:
+	default:
+		return fmt.Errorf("invalid --meta-as %q (expected syntax, code, comment or meta)", o.MetaAs)
+	}
+
+	switch o.LineEnding {
+	case "", EndingLF, EndingCRLF, "any":
+	default:
+		return fmt.Errorf("invalid --line-ending %q (expected lf, crlf or any)", o.LineEnding)
+	}
+
+	switch o.EmbeddedAs {
+	case "", EmbeddedAsLanguage, EmbeddedAsContainer:
+	default:
+		return fmt.Errorf("invalid --embedded-as %q (expected language or container)", o.EmbeddedAs)
+	}
+
+	switch o.Tests {
+	case "", TestsInclude, TestsSeparate, TestsExclude:
+	default:
+		return fmt.Errorf("invalid --tests %q (expected include, separate or exclude)", o.Tests)
+	}
+
+	if o.FileMinLines < 0 || o.FileMaxLines < 0 {
+		return fmt.Errorf("--file-min-lines and --file-max-lines must not be negative")
+	}
+	if o.FileMaxLines > 0 && o.FileMinLines > o.FileMaxLines {
+		return fmt.Errorf("--file-min-lines %d is above --file-max-lines %d", o.FileMinLines, o.FileMaxLines)
+	}
+
+	if o.MaxDepth < 0 {
+		return fmt.Errorf("invalid --max-depth %d (expected 0 or more)", o.MaxDepth)
+	}
+
+	if o.FollowSymlinks && o.NoSymlinks {
+		return fmt.Errorf("--follow-symlinks and --no-symlinks cannot be combined")
+	}
+	return nil
+}
+
+// sizeSkipReason returns the size filter a file with stats fails, or "" if
+// it passes them
+func (o Options) sizeSkipReason(stats FileStats) string {
+	if o.FileMinLines > 0 && stats.TotalLines < o.FileMinLines {
+		return "--file-min-lines"
+	}
+	if o.FileMaxLines > 0 && stats.TotalLines > o.FileMaxLines {
+		return "--file-max-lines"
+	}
+	return ""
+}
+
+// addErrors records the paths skipped in the count made with opts in
+// p.Errors. With opts.Strict, it returns an error for the first of them
+// instead.
+func (p *ProjectStats) addErrors(opts Options) error {
+	p.Errors = opts.errors.sorted()
+	if opts.Strict && len(p.Errors) > 0 {
+		first := p.Errors[0]
+		return fmt.Errorf("could not read %s: %s", first.Path, first.Err)
+	}
+	return nil
+}
+
+// filterLineEnding reports whether files are filtered by line ending
+func (o Options) filterLineEnding() bool {
+	return o.LineEnding != "" && o.LineEnding != "any"
+}
+
+// Counter counts source trees with a fixed set of Options
+type Counter struct {
+	opts Options
+}
+
+// New returns a Counter using opts
+func New(opts Options) (*Counter, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	return &Counter{opts: opts}, nil
+}
+
+// Count counts the tree at root with opts. It is shorthand for New
+// followed by Counter.Count.
+func Count(ctx context.Context, root string, opts Options) (*ProjectStats, error) {
+	c, err := New(opts)
+	if err != nil {
+		return nil, err
+	}
+	return c.Count(ctx, root)
+}
+
+// Count walks the tree at root and counts every code file that passes the
+// configured filters. If root is an archive (see IsArchive), its members
+// are counted with CountArchive instead. If ctx is done before the count
+// completes, it returns the files counted so far, marked Partial, along
+// with ctx.Err(); random sample estimates are not returned partially.
+func (c *Counter) Count(ctx context.Context, root string) (*ProjectStats, error) {
+	if IsArchive(root) {
+		return c.CountArchive(ctx, root)
+	}
+	opts := c.countOptions()
+	opts.errors = &errorLog{}
+	opts.nestedRepos = &[]string{}
+	walk := func(emit func(string)) error {
+		return walkProject(ctx, root, opts, emit)
+	}
+	var stats *ProjectStats
+	var files []string
+	var err error
+	if opts.RandomSample > 0 {
+		if files, err = discover(walk); err != nil {
+			return nil, err
+		}
+		if opts.RandomSample < len(files) {
+			stats = estimateFromSample(ctx, files, opts.RandomSample, opts)
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		} else {
+			stats, _, err = countPaths(ctx, root, emitPaths(files), opts)
+		}
+	} else {
+		opts = withCache(opts, root)
+		stats, files, err = countPaths(ctx, root, walk, opts)
+	}
+	if err != nil && ctx.Err() == nil {
+		return nil, err
+	}
+	if err := stats.addErrors(opts); err != nil {
+		return nil, err
+	}
+	stats.NestedRepos = *opts.nestedRepos
+	if err := ctx.Err(); err != nil {
+		stats.Partial = true
+		return stats, err
+	}
+	saveCache(opts)
+
+	if opts.DiffRange != "" {
+		if err := stats.addChanges(root, opts); err != nil {
+			return nil, err
+		}
+	}
+	stats.Terraform = detectTerraform(root, files, opts)
+	return stats, nil
+}
+
+// CountFiles counts the files at paths instead of walking a tree. They are
+// filtered like the files of a walk, except that directory rules and
+// .gitignore files do not apply; relative globs match the paths as given.
+// Paths listed more than once are counted once. Like Count, it returns
+// partial stats along with ctx.Err() if ctx is done first.
+func (c *Counter) CountFiles(ctx context.Context, paths []string) (*ProjectStats, error) {
+	opts := c.countOptions()
+	filter, err := newPathFilter(".", opts)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var files []string
+	for _, path := range paths {
+		relPath := filepath.ToSlash(filepath.Clean(path))
+		if seen[relPath] || !filter.countFile(path, relPath) {
+			continue
+		}
+		seen[relPath] = true
+		files = append(files, path)
+	}
+
+	opts.errors = &errorLog{}
+	if opts.RandomSample > 0 && opts.RandomSample < len(files) {
+		stats := estimateFromSample(ctx, files, opts.RandomSample, opts)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := stats.addErrors(opts); err != nil {
+			return nil, err
+		}
+		stats.Terraform = detectTerraform(".", files, opts)
+		return stats, nil
+	}
+
+	opts = withCache(opts, ".")
+	stats, _, _ := countPaths(ctx, ".", emitPaths(files), opts)
+	if err := stats.addErrors(opts); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		stats.Partial = true
+		return stats, err
+	}
+	saveCache(opts)
+
+	if opts.DiffRange != "" {
+		if err := stats.addChanges(".", opts); err != nil {
+			return nil, err
+		}
+	}
+	stats.Terraform = detectTerraform(".", files, opts)
+	return stats, nil
+}
+
+// Walk walks the tree at root with opts and calls fn with the result of
+// each counted file. It is shorthand for New followed by Counter.Walk.
+func Walk(ctx context.Context, root string, opts Options, fn func(FileResult) error) error {
+	c, err := New(opts)
+	if err != nil {
+		return err
+	}
+	return c.Walk(ctx, root, fn)
+}
+
+// Walk counts the files Count would, but instead of aggregating them calls
+// fn with the result of each file, in walk order, as soon as it is known.
+// Files left out of the totals, such as binary files and, with Unique,
+// copies, are not passed to fn. If fn returns an error, the walk stops and
+// Walk returns that error. RandomSample is ignored.
+func (c *Counter) Walk(ctx context.Context, root string, fn func(FileResult) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var fnErr error
+	var hashes map[[sha256.Size]byte]string
+	sink := func(a fileAnalysis) {
+		if fnErr != nil || a.skipped {
+			return
+		}
+		if a.kind != KindBinary && a.duplicateOf == "" && c.opts.sizeSkipReason(a.stats) != "" {
+			return
+		}
+		if c.opts.Unique {
+			if hashes == nil {
+				hashes = make(map[[sha256.Size]byte]string)
+			}
+			if duplicateOf(a, hashes) != "" {
+				return
+			}
+		}
+		if a.kind != "" {
+			return
+		}
+		if err := fn(a.result()); err != nil {
+			fnErr = err
+			cancel()
+		}
+	}
+
+	var err error
+	if IsArchive(root) {
+		opts := c.archiveOptions()
+		walk, jobsErr := archiveJobs(ctx, root, opts)
+		if jobsErr != nil {
+			return jobsErr
+		}
+		_, err = analyzeJobs(ctx, walk, opts, sink)
+	} else {
+		opts := c.countOptions()
+		_, err = analyzePaths(ctx, func(emit func(string)) error {
+			return walkProject(ctx, root, opts, emit)
+		}, opts, sink)
+	}
+	if fnErr != nil {
+		return fnErr
+	}
+	if err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// countOptions returns the options for a count, turning off ByAuthor with
+// a warning if git is missing
+func (c *Counter) countOptions() Options {
+	opts := c.opts
+	if opts.ByAuthor {
+		if _, err := exec.LookPath("git"); err != nil {
+			opts.logger().Warn("git not found in PATH, skipping --by-author")
+			opts.ByAuthor = false
+		}
+	}
+	return opts
+}
+
+// withCache returns opts with the cache for counting root opened, if
+// opts.Cache is set
+func withCache(opts Options, root string) Options {
+	if opts.Cache != "" && !opts.ByAuthor && len(opts.Classifiers) == 0 {
+		opts.cache = openCache(opts.Cache, root, opts)
+	}
+	return opts
+}
+
+// saveCache writes the cache of a completed count back to disk
+func saveCache(opts Options) {
+	if opts.cache == nil {
+		return
+	}
+	if err := opts.cache.save(); err != nil {
+		opts.logger().Warn("could not save cache", "err", err)
+	}
+}
+
+// CountReader counts the single file read from r, classifying comments
+// with the syntax of the code extension ext, or of a key of LanguageNames
+// such as "Makefile". UTF-16 input and byte order marks are handled as for
+// files.
+func (c *Counter) CountReader(r io.Reader, ext string) (FileStats, error) {
+	if _, ok := LanguageNames[ext]; !ok {
+		ext = strings.ToLower(ext)
+	}
+	return countLines(decodeText(r), ext, c.opts)
+}
+
+// CountFile counts the single file at path, whatever its name. Files that
+// are not code by name or shebang are counted under their lowercased
+// extension, or their name if they have none, with every non-blank line as
+// code. Generated and minified files are counted like any other; binary
+// files are an error.
+func (c *Counter) CountFile(path string) (FileResult, error) {
+	info, err := os.Stat(longPath(path))
+	if err != nil {
+		return FileResult{}, err
+	}
+	if info.IsDir() {
+		return FileResult{}, fmt.Errorf("%s is a directory", path)
+	}
+
+	ext, ok := detectFileType(path)
+	if !ok {
+		ext = strings.ToLower(filepath.Ext(path))
+		if ext == "" {
+			ext = filepath.Base(path)
+		}
+	}
+	opts := c.countOptions()
+	opts.IncludeGenerated = true
+	a := analyze(path, ext, fileSource(path), opts)
+	if a.skipped {
+		return FileResult{}, fmt.Errorf("could not count %s", path)
+	}
+	if a.kind == KindBinary {
+		return FileResult{}, fmt.Errorf("%s is a binary file", path)
+	}
+	return a.result(), nil
+}
+
+// Discover walks root and returns the code files that pass the configured
+// filters, in walk order
+func (c *Counter) Discover(ctx context.Context, root string) ([]string, error) {
+	return discover(func(emit func(string)) error {
+		return walkProject(ctx, root, c.opts, emit)
+	})
+}
+
+// discover returns the paths emitted by walk, in order
+func discover(walk func(emit func(path string)) error) ([]string, error) {
+	var files []string
+	err := walk(func(path string) {
+		files = append(files, path)
+	})
+	return files, err
+}
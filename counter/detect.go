@@ -0,0 +1,138 @@
+package counter
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SpecialFilenames maps well-known file names that have no code extension
+// to the key their statistics are recorded under. Keys are either a code
+// extension sharing the file's syntax or a key of their own.
+var SpecialFilenames = map[string]string{
+	"Makefile":       "Makefile",
+	"makefile":       "Makefile",
+	"GNUmakefile":    "Makefile",
+	"Dockerfile":     "Dockerfile",
+	"dockerfile":     "Dockerfile",
+	"Containerfile":  "Dockerfile",
+	"CMakeLists.txt": ".cmake",
+	"Rakefile":       ".rb",
+	"Gemfile":        ".rb",
+	"Vagrantfile":    ".rb",
+}
+
+// SpecialFilePrefixes maps the prefixes of file names such as
+// Dockerfile.dev, whose extension only names a variant, to the key they are
+// counted under
+var SpecialFilePrefixes = map[string]string{
+	"Dockerfile.":    "Dockerfile",
+	"Containerfile.": "Dockerfile",
+}
+
+// ShebangInterpreters maps interpreters named on a "#!" line to the code
+// extension of their language
+var ShebangInterpreters = map[string]string{
+	"sh":      ".sh",
+	"dash":    ".sh",
+	"ksh":     ".sh",
+	"zsh":     ".sh",
+	"bash":    ".bash",
+	"python":  ".py",
+	"python2": ".py",
+	"python3": ".py",
+	"ruby":    ".rb",
+	"node":    ".js",
+	"php":     ".php",
+	"perl":    ".pl",
+	"Rscript": ".r",
+	"swipl":   keyProlog,
+}
+
+// detectFileType returns the key the file at path is counted under: its
+// lowercased extension if that is a code extension, otherwise the key for a
+// special file name or, for files without an extension, the language named
+// by a shebang line. ok is false for files that are not code.
+func detectFileType(path string) (key string, ok bool) {
+	key, ok, script := typeByName(filepath.Base(path))
+	if script {
+		return shebangType(path)
+	}
+	return key, ok
+}
+
+// KeyOther is the key of the files counted with Options.AllText that are
+// not code by name or shebang
+const KeyOther = "other"
+
+// fileType returns the key the file at path is counted under, falling back
+// to KeyOther with opts.AllText, or "" if it is not code
+func fileType(path string, opts Options) string {
+	key, ok := detectFileType(path)
+	if !ok && opts.AllText {
+		return KeyOther
+	}
+	return key
+}
+
+// typeByName returns the key a file named name is counted under from its
+// extension or special file name. script is set instead for names without
+// an extension, which a shebang line may still make code.
+func typeByName(name string) (key string, ok, script bool) {
+	ext := strings.ToLower(filepath.Ext(name))
+	if CodeExtensions[ext] {
+		return ext, true, false
+	}
+	if key, ok := SpecialFilenames[name]; ok {
+		return key, true, false
+	}
+	for prefix, key := range SpecialFilePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return key, true, false
+		}
+	}
+	return "", false, ext == ""
+}
+
+// shebangType reads the first line of the file at path and maps the
+// interpreter it names to a code extension
+func shebangType(path string) (string, bool) {
+	file, err := os.Open(longPath(path))
+	if err != nil {
+		return "", false
+	}
+	defer file.Close()
+
+	line, err := bufio.NewReader(file).ReadString('\n')
+	if err != nil && line == "" {
+		return "", false
+	}
+	return parseShebang(line)
+}
+
+// parseShebang extracts the interpreter from a line such as
+// "#!/usr/bin/env python3" or "#!/bin/sh -e"
+func parseShebang(line string) (string, bool) {
+	if !strings.HasPrefix(line, "#!") {
+		return "", false
+	}
+	fields := strings.Fields(line[2:])
+	if len(fields) == 0 {
+		return "", false
+	}
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		fields = fields[1:]
+		// Skip env options such as -S
+		for len(fields) > 0 && strings.HasPrefix(fields[0], "-") {
+			fields = fields[1:]
+		}
+		if len(fields) == 0 {
+			return "", false
+		}
+		interpreter = fields[0]
+	}
+	ext, ok := ShebangInterpreters[interpreter]
+	return ext, ok
+}
@@ -0,0 +1,290 @@
+package counter
+
+import (
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Values of Options.EmbeddedAs
+const (
+	EmbeddedAsLanguage  = "language"
+	EmbeddedAsContainer = "container"
+)
+
+// region is a run of lines of a file embedding other languages, such as a
+// fenced code block of a Markdown file or the script of a Vue component
+type region struct {
+	// ext is the key whose syntax the lines are classified with
+	ext string
+	// embedded is set when the lines belong to the language of ext rather
+	// than to the containing file
+	embedded bool
+	// prose marks documentation, counted as comment lines
+	prose bool
+	// meta marks front matter, counted as Options.MetaAs selects
+	meta bool
+	text string
+}
+
+// regionSplitters split the content of the files under a key, which embed
+// other languages, into regions
+var regionSplitters = map[string]func(text string) []region{
+	".md":       splitMarkdown,
+	".markdown": splitMarkdown,
+	".rmd":      splitMarkdown,
+	".qmd":      splitMarkdown,
+	".vue":      func(text string) []region { return splitComponent(text, false) },
+	".svelte":   func(text string) []region { return splitComponent(text, true) },
+}
+
+// regionList builds the regions of a file line by line, joining
+// consecutive lines of the same kind
+type regionList []region
+
+func (l *regionList) add(r region, line string) {
+	if n := len(*l); n > 0 {
+		last := &(*l)[n-1]
+		if last.ext == r.ext && last.embedded == r.embedded && last.prose == r.prose && last.meta == r.meta {
+			last.text += line
+			return
+		}
+	}
+	r.text = line
+	*l = append(*l, r)
+}
+
+// splitLines splits text into lines, keeping their line breaks
+func splitLines(text string) []string {
+	return strings.SplitAfter(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// countRegions counts the file of ext read from r, splitting it into
+// regions with the regionSplitters entry for ext and classifying each with
+// the syntax of its language. It returns the stats of the whole file and,
+// unless opts.EmbeddedAs is EmbeddedAsContainer, the stats of each key its
+// lines belong to, with the containing file's own lines under ext.
+func countRegions(r io.Reader, ext string, opts Options) (FileStats, map[string]FileStats, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return FileStats{}, nil, err
+	}
+
+	var stats FileStats
+	var parts map[string]FileStats
+	if opts.EmbeddedAs != EmbeddedAsContainer {
+		parts = make(map[string]FileStats)
+	}
+	// License headers are only looked for at the top of files, not of
+	// their regions
+	opts.LicenseHeaders = false
+	for _, reg := range regionSplitters[ext](string(data)) {
+		var regionStats FileStats
+		switch {
+		case reg.meta:
+			regionStats = countMeta(reg.text, opts)
+		case reg.prose:
+			regionStats = countProse(reg.text, opts)
+		default:
+			regionStats, err = countLines(strings.NewReader(reg.text), reg.ext, opts)
+			if err != nil {
+				return FileStats{}, nil, err
+			}
+		}
+		stats.Add(regionStats)
+		if parts != nil {
+			key := ext
+			if reg.embedded {
+				key = reg.ext
+			}
+			partStats := parts[key]
+			partStats.Add(regionStats)
+			parts[key] = partStats
+		}
+	}
+	stats.MaxComplexity = stats.Complexity
+	return stats, parts, nil
+}
+
+// splitMarkdown splits a Markdown file into its front matter, prose and
+// fenced code blocks. Blocks whose info string names a known language are
+// embedded regions of that language; the fences and blocks in other
+// languages, such as program output, count as prose.
+func splitMarkdown(text string) []region {
+	var regions regionList
+	var fence string
+	var block region
+	lines := splitLines(text)
+	frontMatter := frontMatterLines(lines)
+	for _, line := range lines[:frontMatter] {
+		regions.add(region{meta: true}, line)
+	}
+	for _, line := range lines[frontMatter:] {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+				regions.add(region{prose: true}, line)
+				continue
+			}
+			regions.add(block, line)
+			continue
+		}
+
+		if marker := fenceMarker(line); marker != "" {
+			fence = marker
+			block = region{prose: true}
+			if key := fenceLanguage(strings.TrimSpace(trimmed[len(marker):])); key != "" {
+				block = region{ext: key, embedded: true}
+			}
+			regions.add(region{prose: true}, line)
+			continue
+		}
+		regions.add(region{prose: true}, line)
+	}
+	return regions
+}
+
+// fenceMarker returns the backticks or tildes opening a fenced code block
+// on line, or "" if it does not open one
+func fenceMarker(line string) string {
+	indent := len(line) - len(strings.TrimLeft(line, " "))
+	if indent > 3 {
+		return ""
+	}
+	line = line[indent:]
+	for _, c := range []string{"`", "~"} {
+		n := len(line) - len(strings.TrimLeft(line, c))
+		if n >= 3 {
+			return line[:n]
+		}
+	}
+	return ""
+}
+
+// fenceAliases maps info strings of fenced code blocks that are neither an
+// extension nor a language name to the key of their language
+var fenceAliases = map[string]string{
+	"golang":     ".go",
+	"shell":      ".sh",
+	"zsh":        ".sh",
+	"console":    "",
+	"c++":        ".cpp",
+	"csharp":     ".cs",
+	"terraform":  ".tf",
+	"protobuf":   ".proto",
+	"objc":       ".m",
+	"objectivec": ".m",
+}
+
+// fenceLanguage returns the key of the language an info string such as
+// "go", "python title=x.py" or "{r}" names, or "" if it names none
+func fenceLanguage(info string) string {
+	fields := strings.Fields(info)
+	if len(fields) == 0 {
+		return ""
+	}
+	word := strings.ToLower(strings.Trim(fields[0], "{}."))
+	if i := strings.IndexAny(word, ",{}"); i >= 0 {
+		word = word[:i]
+	}
+	if word == "" {
+		return ""
+	}
+	if key, ok := fenceAliases[word]; ok {
+		return key
+	}
+	if CodeExtensions["."+word] {
+		return "." + word
+	}
+	// Fall back to language names, taking the first key of a name shared
+	// by several for a stable result
+	var keys []string
+	for key, name := range LanguageNames {
+		if strings.ToLower(name) == word {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if len(keys) > 0 {
+		return keys[0]
+	}
+	return ""
+}
+
+// componentTag matches the opening tag of a top-level block of a Vue or
+// Svelte component and its lang attribute
+var componentTag = regexp.MustCompile(`^<(template|script|style)\b([^>]*)>`)
+
+// componentLang matches the lang attribute of a block
+var componentLang = regexp.MustCompile(`\blang\s*=\s*["']?([\w-]+)`)
+
+// blockLanguages maps the blocks of components to the key of their
+// language by their lang attribute, with "" for the default
+var blockLanguages = map[string]map[string]string{
+	"template": {"": ".html", "html": ".html"},
+	"script":   {"": ".js", "js": ".js", "javascript": ".js", "ts": ".ts", "typescript": ".ts", "jsx": ".jsx", "tsx": ".tsx"},
+	"style":    {"": ".css", "css": ".css", "scss": ".scss"},
+}
+
+// blockSyntax is the syntax used for the lines of blocks in a language
+// without its own syntax, e.g. <style lang="less">
+var blockSyntax = map[string]string{"template": ".html", "script": ".js", "style": ".scss"}
+
+// splitComponent splits a Vue or Svelte single-file component into its
+// template, script and style blocks. The tags of the blocks belong to the
+// component itself. Markup outside the blocks is HTML if markupOutside is
+// set, as in Svelte, and belongs to the component otherwise.
+func splitComponent(text string, markupOutside bool) []region {
+	outside := region{ext: ".html"}
+	if markupOutside {
+		outside.embedded = true
+	}
+
+	var regions regionList
+	var block string
+	var content region
+	depth := 0
+	for _, line := range splitLines(text) {
+		trimmed := strings.TrimSpace(line)
+		if block != "" {
+			// Templates may nest <template> tags, so only the closing tag
+			// of the outermost one ends the block
+			if block == "template" {
+				depth += strings.Count(trimmed, "<template") - strings.Count(trimmed, "</template>")
+			}
+			if strings.HasPrefix(trimmed, "</"+block+">") && (block != "template" || depth <= 0) {
+				block = ""
+				regions.add(region{ext: ".html"}, line)
+				continue
+			}
+			regions.add(content, line)
+			continue
+		}
+
+		m := componentTag.FindStringSubmatch(trimmed)
+		if m == nil || (markupOutside && m[1] == "template") {
+			regions.add(outside, line)
+			continue
+		}
+		regions.add(region{ext: ".html"}, line)
+		if strings.Contains(trimmed, "</"+m[1]+">") {
+			// The whole block is on one line
+			continue
+		}
+		block = m[1]
+		depth = 1
+
+		lang := ""
+		if lm := componentLang.FindStringSubmatch(m[2]); lm != nil {
+			lang = strings.ToLower(lm[1])
+		}
+		if key, ok := blockLanguages[block][lang]; ok {
+			content = region{ext: key, embedded: true}
+		} else {
+			content = region{ext: blockSyntax[block]}
+		}
+	}
+	return regions
+}
@@ -0,0 +1,138 @@
+package counter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+// encodingSniffSize is how much of a file decodeText inspects to detect
+// UTF-16 without a byte order mark
+const encodingSniffSize = 512
+
+// decodeText returns a reader yielding the content of r as UTF-8. A UTF-8
+// byte order mark is dropped, and UTF-16 text, marked by a byte order mark
+// or recognised by its pattern of NUL bytes, is decoded. Anything else is
+// passed through unchanged.
+func decodeText(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(encodingSniffSize)
+	switch {
+	case bytes.HasPrefix(head, utf8BOM):
+		br.Discard(len(utf8BOM))
+		return br
+	case bytes.HasPrefix(head, utf16LEBOM):
+		br.Discard(len(utf16LEBOM))
+		return &utf16Reader{r: br, order: binary.LittleEndian}
+	case bytes.HasPrefix(head, utf16BEBOM):
+		br.Discard(len(utf16BEBOM))
+		return &utf16Reader{r: br, order: binary.BigEndian}
+	}
+	if order := guessUTF16(head); order != nil {
+		return &utf16Reader{r: br, order: order}
+	}
+	return br
+}
+
+// guessUTF16 returns the byte order of head if it looks like UTF-16 text
+// without a byte order mark: mostly ASCII text, where most of every other
+// byte is NUL and the bytes in between hardly ever are
+func guessUTF16(head []byte) binary.ByteOrder {
+	pairs := len(head) / 2
+	if pairs < 4 {
+		return nil
+	}
+	var evenZero, oddZero int
+	for i := 0; i < pairs*2; i += 2 {
+		if head[i] == 0 {
+			evenZero++
+		}
+		if head[i+1] == 0 {
+			oddZero++
+		}
+	}
+	switch {
+	case evenZero*10 <= pairs && oddZero*10 >= pairs*7:
+		return binary.LittleEndian
+	case oddZero*10 <= pairs && evenZero*10 >= pairs*7:
+		return binary.BigEndian
+	}
+	return nil
+}
+
+// utf16Reader decodes UTF-16 text read from r into UTF-8
+type utf16Reader struct {
+	r     *bufio.Reader
+	order binary.ByteOrder
+	// pending holds the bytes of a decoded rune that did not fit into the
+	// last Read
+	pending []byte
+}
+
+func (u *utf16Reader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(u.pending) > 0 {
+			copied := copy(p[n:], u.pending)
+			u.pending = u.pending[copied:]
+			n += copied
+			continue
+		}
+		r, err := u.readRune()
+		if err != nil {
+			if n > 0 {
+				return n, nil
+			}
+			return 0, err
+		}
+		if utf8.RuneLen(r) <= len(p)-n {
+			n += utf8.EncodeRune(p[n:], r)
+		} else {
+			u.pending = utf8.AppendRune(u.pending[:0], r)
+		}
+	}
+	return n, nil
+}
+
+// readRune decodes the next rune, joining surrogate pairs. Unpaired
+// surrogates and a trailing odd byte decode to utf8.RuneError.
+func (u *utf16Reader) readRune() (rune, error) {
+	unit, err := u.readUnit()
+	if err != nil {
+		return 0, err
+	}
+	r := rune(unit)
+	if !utf16.IsSurrogate(r) {
+		return r, nil
+	}
+	next, err := u.r.Peek(2)
+	if err != nil || len(next) < 2 {
+		return utf8.RuneError, nil
+	}
+	decoded := utf16.DecodeRune(r, rune(u.order.Uint16(next)))
+	if decoded != utf8.RuneError {
+		u.r.Discard(2)
+	}
+	return decoded, nil
+}
+
+func (u *utf16Reader) readUnit() (uint16, error) {
+	var unit [2]byte
+	n, err := io.ReadFull(u.r, unit[:])
+	if n == 1 {
+		return uint16(utf8.RuneError), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return u.order.Uint16(unit[:]), nil
+}
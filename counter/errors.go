@@ -0,0 +1,40 @@
+package counter
+
+import (
+	"sort"
+	"sync"
+)
+
+// PathError records a file or directory that could not be read and was
+// skipped
+type PathError struct {
+	Path string
+	Err  string
+}
+
+// errorLog collects the PathErrors of a count from the walk and the
+// workers
+type errorLog struct {
+	mu     sync.Mutex
+	errors []PathError
+}
+
+// add records err for path. It does nothing on a nil log, e.g. when
+// counting with Counter.Walk.
+func (l *errorLog) add(path string, err error) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errors = append(l.errors, PathError{Path: path, Err: err.Error()})
+}
+
+// sorted returns the errors recorded so far by path
+func (l *errorLog) sorted() []PathError {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	errors := append([]PathError(nil), l.errors...)
+	sort.Slice(errors, func(i, j int) bool { return errors[i].Path < errors[j].Path })
+	return errors
+}
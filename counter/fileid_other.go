@@ -0,0 +1,11 @@
+//go:build !unix
+
+package counter
+
+import "os"
+
+// fileIDOf reports false: hard links are only recognised by their content
+// on this platform
+func fileIDOf(info os.FileInfo) (fileID, bool) {
+	return fileID{}, false
+}
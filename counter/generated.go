@@ -0,0 +1,129 @@
+package counter
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+)
+
+// Kinds of files left out of the totals unless Options.IncludeGenerated
+// is set. Binary files are never counted.
+const (
+	KindBinary    = "binary"
+	KindMinified  = "minified"
+	KindGenerated = "generated"
+)
+
+// generatedMarkers flag a file as generated when they appear in its first
+// generatedMarkerLines lines, e.g. Go's "Code generated ... DO NOT EDIT."
+// Options.GeneratedMarkers adds to them.
+var generatedMarkers = []string{"@generated", "DO NOT EDIT"}
+
+// GeneratedNames are filepath.Match patterns of the names of generated files,
+// such as the output of protoc, Kubernetes deepcopy-gen and gRPC gateway
+// swagger files. Options.GeneratedNames adds to them.
+var GeneratedNames = []string{
+	"*.pb.go", "*.pb.gw.go", "*_grpc.pb.go", "*_gen.go", "zz_generated*",
+	"*_pb2.py", "*_pb2_grpc.py", "*.pb.cc", "*.pb.h", "*.swagger.json",
+}
+
+const (
+	generatedMarkerLines = 10
+	// sniffSize is how much of a file is read to detect its kind
+	sniffSize = 8192
+	// minifiedLineLength is the average line length above which a file is
+	// taken to be minified
+	minifiedLineLength = 300
+)
+
+// GeneratedStats counts the files left out of the totals by kind
+type GeneratedStats struct {
+	Binary    int
+	Minified  int
+	Generated int
+
+	// Stats holds the line counts of the minified and generated files, and
+	// StatsByExt the same by the key each would have been counted under
+	Stats      FileStats
+	StatsByExt map[string]FileStats
+}
+
+// Files returns the number of files left out
+func (g GeneratedStats) Files() int {
+	return g.Binary + g.Minified + g.Generated
+}
+
+// Add accumulates other into g
+func (g *GeneratedStats) Add(other GeneratedStats) {
+	g.Binary += other.Binary
+	g.Minified += other.Minified
+	g.Generated += other.Generated
+	g.Stats.Add(other.Stats)
+	for ext, stats := range other.StatsByExt {
+		g.addExt(ext, stats)
+	}
+}
+
+func (g *GeneratedStats) addExt(ext string, stats FileStats) {
+	if g.StatsByExt == nil {
+		g.StatsByExt = make(map[string]FileStats)
+	}
+	extStats := g.StatsByExt[ext]
+	extStats.Add(stats)
+	g.StatsByExt[ext] = extStats
+}
+
+func (g *GeneratedStats) add(kind, ext string, stats FileStats) {
+	switch kind {
+	case KindBinary:
+		g.Binary++
+	case KindMinified:
+		g.Minified++
+	default:
+		g.Generated++
+	}
+	g.Stats.Add(stats)
+	if kind != KindBinary {
+		g.addExt(ext, stats)
+	}
+}
+
+// sniffContent returns KindBinary if head, the start of the content of
+// path, holds a NUL byte, KindMinified for names like bundle.min.js,
+// KindGenerated for names matching GeneratedNames or opts.GeneratedNames
+// and if a generated marker appears near the top, or "" for ordinary source
+func sniffContent(path string, head []byte, opts Options) string {
+	if bytes.IndexByte(head, 0) >= 0 {
+		return KindBinary
+	}
+	name := filepath.Base(path)
+	if strings.Contains(strings.TrimSuffix(name, filepath.Ext(name)), ".min") {
+		return KindMinified
+	}
+	for _, names := range [][]string{GeneratedNames, opts.GeneratedNames} {
+		for _, pattern := range names {
+			if ok, _ := filepath.Match(pattern, name); ok {
+				return KindGenerated
+			}
+		}
+	}
+	for i, line := range bytes.SplitN(head, []byte("\n"), generatedMarkerLines+1) {
+		if i == generatedMarkerLines {
+			break
+		}
+		for _, markers := range [][]string{generatedMarkers, opts.GeneratedMarkers} {
+			for _, marker := range markers {
+				if bytes.Contains(line, []byte(marker)) {
+					return KindGenerated
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// looksMinified reports whether the measured lines are long enough on
+// average to come from a minifier
+func looksMinified(stats FileStats) bool {
+	return stats.AverageLineLength() > minifiedLineLength
+}
@@ -0,0 +1,58 @@
+package counter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// errGitNotFound is returned when the git executable is missing
+var errGitNotFound = errors.New("git not found in PATH")
+
+// gitListFiles returns the files in the git index under rootPath as
+// slash-separated paths relative to rootPath, in index order
+func gitListFiles(rootPath string) ([]string, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, errGitNotFound
+	}
+
+	cmd := exec.Command("git", "-C", rootPath, "ls-files", "-z")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("%s is not a git repository: %s", rootPath, msg)
+	}
+
+	var files []string
+	for _, name := range strings.Split(string(out), "\x00") {
+		if name != "" {
+			files = append(files, name)
+		}
+	}
+	return files, nil
+}
+
+// gitTrackedFiles returns the set of files in the git index under rootPath,
+// keyed by their slash-separated path relative to rootPath.
+func gitTrackedFiles(rootPath string) (map[string]bool, error) {
+	files, err := gitListFiles(rootPath)
+	if err == errGitNotFound {
+		return nil, errors.New("--git-tracked requires git, but it was not found in PATH")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("--git-tracked: %v", err)
+	}
+
+	tracked := make(map[string]bool, len(files))
+	for _, name := range files {
+		tracked[name] = true
+	}
+	return tracked, nil
+}
@@ -0,0 +1,138 @@
+package counter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LineChanges counts the lines a git diff adds and removes
+type LineChanges struct {
+	Added   int
+	Removed int
+}
+
+// Add accumulates the changes of other into c
+func (c *LineChanges) Add(other LineChanges) {
+	c.Added += other.Added
+	c.Removed += other.Removed
+}
+
+// diffEntry is one file of a git diff --numstat
+type diffEntry struct {
+	// path is slash-separated and relative to the counted root; for a
+	// rename it is the new path
+	path string
+	LineChanges
+}
+
+// gitDiffFiles returns the text files changed in the git range spec,
+// either "A..B" or a single ref compared to the working tree, below
+// rootPath with their added and removed lines
+func gitDiffFiles(rootPath, spec string) ([]diffEntry, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, errors.New("--since and --diff-range require git, but it was not found in PATH")
+	}
+	if spec == "" || strings.HasPrefix(spec, "-") {
+		return nil, fmt.Errorf("invalid git range %q", spec)
+	}
+
+	// --relative limits the diff to rootPath and reports paths relative to
+	// it, like the walk
+	cmd := exec.Command("git", "-C", rootPath, "diff", "--numstat", "-z", "--relative", spec, "--")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("could not diff %s in %s: %s", spec, rootPath, msg)
+	}
+	return parseNumstat(string(out)), nil
+}
+
+// parseNumstat parses the output of git diff --numstat -z. Each record is
+// "added\tremoved\tpath\x00", or "added\tremoved\t\x00old\x00new\x00" for
+// renames. Binary files, whose counts are "-", are left out.
+func parseNumstat(out string) []diffEntry {
+	var entries []diffEntry
+	fields := strings.Split(out, "\x00")
+	for i := 0; i < len(fields); i++ {
+		parts := strings.SplitN(fields[i], "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		name := parts[2]
+		if name == "" && i+2 < len(fields) {
+			name = fields[i+2]
+			i += 2
+		}
+		added, addErr := strconv.Atoi(parts[0])
+		removed, removeErr := strconv.Atoi(parts[1])
+		if addErr != nil || removeErr != nil {
+			continue
+		}
+		entries = append(entries, diffEntry{path: name, LineChanges: LineChanges{added, removed}})
+	}
+	return entries
+}
+
+// gitChangedFiles returns the set of files changed in the range of
+// opts.DiffRange below rootPath, keyed by their slash-separated path
+// relative to rootPath
+func gitChangedFiles(rootPath string, opts Options) (map[string]bool, error) {
+	entries, err := gitDiffFiles(rootPath, opts.DiffRange)
+	if err != nil {
+		return nil, err
+	}
+	changed := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		changed[entry.path] = true
+	}
+	return changed, nil
+}
+
+// addChanges records in p.Changes the lines added and removed in the range
+// of opts.DiffRange below rootPath, by the key each file is counted under.
+// Files that the filters skip or that are not code are left out; deleted
+// files are classified by name alone.
+func (p *ProjectStats) addChanges(rootPath string, opts Options) error {
+	entries, err := gitDiffFiles(rootPath, opts.DiffRange)
+	if err != nil {
+		return err
+	}
+	filter, err := newPathFilter(rootPath, opts)
+	if err != nil {
+		return err
+	}
+	// The range is what restricts the files here
+	filter.changed = nil
+
+	p.Changes = make(map[string]LineChanges)
+	for _, entry := range entries {
+		if !filter.matchPath(entry.path) {
+			continue
+		}
+		key, ok, script := typeByName(path.Base(entry.path))
+		if script {
+			key, ok = shebangType(filepath.Join(rootPath, filepath.FromSlash(entry.path)))
+		}
+		if !ok && opts.AllText {
+			key, ok = KeyOther, true
+		}
+		if !ok || !filter.matchType(key) {
+			continue
+		}
+		changes := p.Changes[key]
+		changes.Add(entry.LineChanges)
+		p.Changes[key] = changes
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package counter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// GlobSet matches slash-separated paths relative to the project root
+// against doublestar globs: "*" and "?" stay within a path segment, "**"
+// spans any number of directories and "{a,b}" matches either alternative.
+type GlobSet struct {
+	patterns []*regexp.Regexp
+}
+
+// CompileGlobs compiles patterns into a GlobSet, or returns nil if there
+// are none
+func CompileGlobs(patterns []string) (*GlobSet, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+
+	g := &GlobSet{}
+	for _, pattern := range patterns {
+		alternatives, err := expandBraces(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %v", pattern, err)
+		}
+		for _, alt := range alternatives {
+			re, err := regexp.Compile("^" + globToRegexp(strings.TrimPrefix(alt, "/")) + "$")
+			if err != nil {
+				return nil, fmt.Errorf("invalid glob %q: %v", pattern, err)
+			}
+			g.patterns = append(g.patterns, re)
+		}
+	}
+	return g, nil
+}
+
+// Match reports whether relPath matches any of the globs. A nil GlobSet
+// matches nothing.
+func (g *GlobSet) Match(relPath string) bool {
+	if g == nil {
+		return false
+	}
+	for _, re := range g.patterns {
+		if re.MatchString(relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandBraces expands the first "{a,b}" group in pattern, recursively, so
+// "src/{a,b}/*.go" yields "src/a/*.go" and "src/b/*.go"
+func expandBraces(pattern string) ([]string, error) {
+	start := strings.IndexByte(pattern, '{')
+	if start < 0 {
+		if strings.IndexByte(pattern, '}') >= 0 {
+			return nil, fmt.Errorf("unbalanced braces")
+		}
+		return []string{pattern}, nil
+	}
+
+	depth, end := 0, -1
+	var options []string
+	last := start + 1
+	for i := start; i < len(pattern) && end < 0; i++ {
+		switch pattern[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				options = append(options, pattern[last:i])
+				end = i
+			}
+		case ',':
+			if depth == 1 {
+				options = append(options, pattern[last:i])
+				last = i + 1
+			}
+		}
+	}
+	if end < 0 {
+		return nil, fmt.Errorf("unbalanced braces")
+	}
+
+	var expanded []string
+	for _, option := range options {
+		alternatives, err := expandBraces(pattern[:start] + option + pattern[end+1:])
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, alternatives...)
+	}
+	return expanded, nil
+}
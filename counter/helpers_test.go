@@ -0,0 +1,22 @@
+package counter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTree creates the files of tree, slash-separated paths mapped to
+// their content, below dir
+func writeTree(t *testing.T, dir string, tree map[string]string) {
+	t.Helper()
+	for name, content := range tree {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package counter
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+)
+
+// heuristicsSize is how much of a file the heuristics look at
+const heuristicsSize = 16 << 10
+
+// Keys of languages that are only reached through heuristics, since their
+// extensions are shared with another language
+const (
+	keyMATLAB = "MATLAB"
+	keyProlog = "Prolog"
+)
+
+var (
+	objcMarkers   = regexp.MustCompile(`(?m)^\s*(@(interface|class|protocol|property|end|synthesize|selector|implementation)\b|#import\s+.+\.h[">])`)
+	cppMarkers    = regexp.MustCompile(`(?m)^\s*(#\s*include\s*<(cstdint|cstdio|cstdlib|string|vector|map|list|array|memory|queue|stack|unordered_map|unordered_set|iostream|ostream|istream|sstream|fstream|algorithm|functional)>|template\s*<|namespace\s+\w*\s*\{|(class|struct)\s+\w+\s*(final\s*)?:\s*(public|private|protected)\b|(public|private|protected):\s*$)|\bstd::\w+`)
+	matlabMarkers = regexp.MustCompile(`(?m)^\s*(%|function\b.*=|end\s*$)`)
+	prologMarkers = regexp.MustCompile(`(?m)^[^#\n]*:-`)
+	qtTranslation = regexp.MustCompile(`^\s*(<\?xml\b|<!DOCTYPE TS>|<TS\b)`)
+)
+
+// heuristics refine the key of files whose extension several languages
+// share, by looking at the start of the file. Each returns the key the file
+// is counted under instead, or "" to keep the extension.
+var heuristics = map[string]func(head []byte) string{
+	// C headers may be C++ or Objective-C ones
+	".h": func(head []byte) string {
+		switch {
+		case objcMarkers.Match(head):
+			return ".m"
+		case cppMarkers.Match(head):
+			return ".hpp"
+		}
+		return ""
+	},
+	// Objective-C sources unless they look like MATLAB
+	".m": func(head []byte) string {
+		if !objcMarkers.Match(head) && matlabMarkers.Match(head) {
+			return keyMATLAB
+		}
+		return ""
+	},
+	// Perl scripts unless they hold Prolog clauses
+	".pl": func(head []byte) string {
+		if prologMarkers.Match(head) {
+			return keyProlog
+		}
+		return ""
+	},
+	// TypeScript unless it is a Qt Linguist translation file
+	".ts": func(head []byte) string {
+		if qtTranslation.Match(head) {
+			return ".xml"
+		}
+		return ""
+	},
+}
+
+// refineHead returns the language key the file starting with head, its
+// first heuristicsSize bytes, is counted as, given that its extension maps
+// to key. A shebang line naming a known interpreter decides; otherwise the
+// heuristics for key are applied, if any.
+func refineHead(key string, head []byte) string {
+	heuristic, ok := heuristics[key]
+	if !ok {
+		return key
+	}
+	if bytes.HasPrefix(head, []byte("#!")) {
+		line, _ := bufio.NewReader(bytes.NewReader(head)).ReadString('\n')
+		if shebangKey, ok := parseShebang(line); ok {
+			return shebangKey
+		}
+	}
+	if refined := heuristic(head); refined != "" {
+		return refined
+	}
+	return key
+}
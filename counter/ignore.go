@@ -0,0 +1,332 @@
+package counter
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignorePattern is a single compiled gitignore-style pattern
+type ignorePattern struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// IgnoreMatcher matches slash-separated relative paths against an ordered
+// list of gitignore-style patterns. Later patterns override earlier ones.
+type IgnoreMatcher struct {
+	patterns []ignorePattern
+}
+
+// LoadIgnoreFile reads gitignore-style patterns from path, one per line
+func LoadIgnoreFile(path string) (*IgnoreMatcher, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ParseIgnorePatterns(lines), nil
+}
+
+// ParseIgnorePatterns compiles gitignore-style pattern lines. Blank lines
+// and lines starting with "#" are skipped.
+func ParseIgnorePatterns(lines []string) *IgnoreMatcher {
+	m := &IgnoreMatcher{}
+	for _, line := range lines {
+		if p, ok := compileIgnorePattern(line); ok {
+			m.patterns = append(m.patterns, p)
+		}
+	}
+	return m
+}
+
+// Match reports whether relPath is ignored. A path is also ignored when
+// any of its parent directories is, mirroring git's rule that files
+// cannot be re-included from an excluded directory.
+func (m *IgnoreMatcher) Match(relPath string, isDir bool) bool {
+	if m == nil || len(m.patterns) == 0 {
+		return false
+	}
+	relPath = strings.Trim(relPath, "/")
+	if relPath == "" || relPath == "." {
+		return false
+	}
+
+	parts := strings.Split(relPath, "/")
+	for i := 1; i < len(parts); i++ {
+		if m.matchExact(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return m.matchExact(relPath, isDir)
+}
+
+// matchExact applies the patterns to relPath alone, ignoring its parents
+func (m *IgnoreMatcher) matchExact(relPath string, isDir bool) bool {
+	ignored, _ := m.lookup(relPath, isDir)
+	return ignored
+}
+
+// lookup returns the verdict of the last pattern matching relPath and
+// whether any pattern matched at all
+func (m *IgnoreMatcher) lookup(relPath string, isDir bool) (ignored, matched bool) {
+	for _, p := range m.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.re.MatchString(relPath) {
+			ignored, matched = !p.negate, true
+		}
+	}
+	return ignored, matched
+}
+
+// IgnoreTree applies ignore files found throughout a directory tree, such
+// as nested .gitignore files. Patterns are relative to the directory holding
+// their file, and files in deeper directories take precedence.
+type IgnoreTree struct {
+	root     string
+	fileName string
+	matchers map[string]*IgnoreMatcher
+
+	// parents holds the ignore files of the directories above root, nearest
+	// first, when loaded with LoadParents
+	parents []parentIgnore
+}
+
+// parentIgnore is the ignore file of a directory above the root. prefix is
+// the path from that directory down to the root, ending in a slash.
+type parentIgnore struct {
+	prefix  string
+	matcher *IgnoreMatcher
+}
+
+// NewIgnoreTree returns a tree that loads ignore files named fileName
+// from directories below root as they are visited
+func NewIgnoreTree(root, fileName string) *IgnoreTree {
+	return &IgnoreTree{root: root, fileName: fileName, matchers: make(map[string]*IgnoreMatcher)}
+}
+
+// LoadParents loads the ignore files of every directory above the root, so
+// patterns in them apply below the root as well
+func (t *IgnoreTree) LoadParents() {
+	t.loadParents(false)
+}
+
+// LoadRepoParents loads the ignore files of the directories above the root
+// up to the top of the git work tree holding it, as git applies the
+// .gitignore files of a repository to its subdirectories. Nothing is loaded
+// if the root is the top of a work tree or in none.
+func (t *IgnoreTree) LoadRepoParents() {
+	t.loadParents(true)
+}
+
+func (t *IgnoreTree) loadParents(repo bool) {
+	dir, err := filepath.Abs(t.root)
+	if err != nil || (repo && isWorkTree(dir)) {
+		return
+	}
+	var parents []parentIgnore
+	prefix := ""
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		prefix = filepath.Base(dir) + "/" + prefix
+		dir = parent
+		if matcher, err := LoadIgnoreFile(filepath.Join(dir, t.fileName)); err == nil {
+			parents = append(parents, parentIgnore{prefix: prefix, matcher: matcher})
+		}
+		if repo && isWorkTree(dir) {
+			t.parents = parents
+			return
+		}
+	}
+	if !repo {
+		t.parents = parents
+	}
+}
+
+// isWorkTree reports whether dir is the top of a git work tree, holding a
+// .git directory or, for worktrees and submodules, a .git file
+func isWorkTree(dir string) bool {
+	_, err := os.Lstat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+// Visit loads the ignore file of relDir, a slash-separated directory
+// relative to the root, if it has one. Unreadable files are skipped.
+func (t *IgnoreTree) Visit(relDir string) {
+	if t == nil {
+		return
+	}
+	relDir = cleanRelDir(relDir)
+	if _, done := t.matchers[relDir]; done {
+		return
+	}
+	matcher, err := LoadIgnoreFile(filepath.Join(t.root, filepath.FromSlash(relDir), t.fileName))
+	if err != nil {
+		matcher = nil
+	}
+	t.matchers[relDir] = matcher
+}
+
+// Match reports whether relPath is ignored by the ignore files visited so
+// far, either directly or because one of its parent directories is
+func (t *IgnoreTree) Match(relPath string, isDir bool) bool {
+	if t == nil {
+		return false
+	}
+	relPath = strings.Trim(relPath, "/")
+	if relPath == "" || relPath == "." {
+		return false
+	}
+
+	parts := strings.Split(relPath, "/")
+	for i := 1; i < len(parts); i++ {
+		if t.matchExact(parts[:i], true) {
+			return true
+		}
+	}
+	return t.matchExact(parts, isDir)
+}
+
+// matchExact lets the deepest ignore file with a matching pattern decide
+func (t *IgnoreTree) matchExact(parts []string, isDir bool) bool {
+	for depth := len(parts) - 1; depth >= 0; depth-- {
+		matcher := t.matchers[strings.Join(parts[:depth], "/")]
+		if matcher == nil {
+			continue
+		}
+		if ignored, matched := matcher.lookup(strings.Join(parts[depth:], "/"), isDir); matched {
+			return ignored
+		}
+	}
+	for _, parent := range t.parents {
+		if ignored, matched := parent.matcher.lookup(parent.prefix+strings.Join(parts, "/"), isDir); matched {
+			return ignored
+		}
+	}
+	return false
+}
+
+func cleanRelDir(relDir string) string {
+	relDir = strings.Trim(relDir, "/")
+	if relDir == "." {
+		return ""
+	}
+	return relDir
+}
+
+func compileIgnorePattern(line string) (ignorePattern, bool) {
+	line = strings.TrimRight(line, "\r")
+	// Trailing spaces are ignored unless escaped with a backslash
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignorePattern{}, false
+	}
+
+	var p ignorePattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, "\\#") || strings.HasPrefix(line, "\\!") {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return ignorePattern{}, false
+	}
+
+	// A slash anywhere but the end anchors the pattern to the base directory
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	if !anchored {
+		expr.WriteString("(?:.*/)?")
+	}
+	expr.WriteString(globToRegexp(line))
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return ignorePattern{}, false
+	}
+	p.re = re
+	return p, true
+}
+
+// globToRegexp translates a gitignore glob into a regular expression body
+func globToRegexp(glob string) string {
+	var expr strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				atStart := i == 0 || glob[i-1] == '/'
+				atEnd := i+2 == len(glob) || glob[i+2] == '/'
+				if atStart && atEnd {
+					i++
+					if i+1 < len(glob) {
+						// "**/" matches zero or more directories
+						i++
+						expr.WriteString("(?:.*/)?")
+					} else {
+						expr.WriteString(".*")
+					}
+					continue
+				}
+			}
+			expr.WriteString("[^/]*")
+		case '?':
+			expr.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				expr.WriteString(regexp.QuoteMeta("["))
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			negate := strings.HasPrefix(class, "!") || strings.HasPrefix(class, "^")
+			if negate {
+				class = class[1:]
+			}
+			class = strings.ReplaceAll(class, "/", "")
+			if negate {
+				// A negated class must not match the slash either
+				class = "^/" + class
+			}
+			expr.WriteString("[" + class + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				expr.WriteString(regexp.QuoteMeta(string(glob[i])))
+			}
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return expr.String()
+}
@@ -0,0 +1,176 @@
+package counter
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ignoreCases follow the pattern format of gitignore(5)
+var ignoreCases = []struct {
+	name     string
+	patterns []string
+	path     string
+	isDir    bool
+	want     bool
+}{
+	{"glob matches at any depth", []string{"*.log"}, "a.log", false, true},
+	{"glob matches in subdirectory", []string{"*.log"}, "dir/a.log", false, true},
+	{"glob is not a prefix", []string{"*.log"}, "a.log.txt", false, false},
+	{"leading slash anchors", []string{"/build"}, "build", true, true},
+	{"leading slash anchors to root", []string{"/build"}, "src/build", true, false},
+	{"trailing slash needs a directory", []string{"build/"}, "build", false, false},
+	{"trailing slash matches directory", []string{"build/"}, "build", true, true},
+	{"trailing slash matches at any depth", []string{"build/"}, "src/build", true, true},
+	{"files below an ignored directory", []string{"build/"}, "build/main.go", false, true},
+	{"middle slash anchors", []string{"doc/*.txt"}, "doc/a.txt", false, true},
+	{"star does not cross slashes", []string{"doc/*.txt"}, "doc/sub/a.txt", false, false},
+	{"leading double star", []string{"**/foo"}, "foo", false, true},
+	{"leading double star at depth", []string{"**/foo"}, "a/b/foo", false, true},
+	{"trailing double star", []string{"abc/**"}, "abc/x/y", false, true},
+	{"trailing double star is not the directory", []string{"abc/**"}, "abc", true, false},
+	{"middle double star matches no directory", []string{"a/**/b"}, "a/b", false, true},
+	{"middle double star matches directories", []string{"a/**/b"}, "a/x/y/b", false, true},
+	{"negation re-includes", []string{"*.txt", "!keep.txt"}, "keep.txt", false, false},
+	{"negation leaves others", []string{"*.txt", "!keep.txt"}, "x.txt", false, true},
+	{"no re-include below ignored directory", []string{"dir/", "!dir/keep.go"}, "dir/keep.go", false, true},
+	{"later pattern wins", []string{"!a.go", "a.go"}, "a.go", false, true},
+	{"question mark", []string{"?.go"}, "a.go", false, true},
+	{"question mark is one character", []string{"?.go"}, "ab.go", false, false},
+	{"question mark does not match slash", []string{"a?b"}, "a/b", false, false},
+	{"range", []string{"file[0-9].go"}, "file1.go", false, true},
+	{"range excludes others", []string{"file[0-9].go"}, "filex.go", false, false},
+	{"negated range", []string{"file[!0-9].go"}, "filex.go", false, true},
+	{"negated range excludes", []string{"file[!0-9].go"}, "file1.go", false, false},
+	{"negated range does not match slash", []string{"a[!x]b"}, "a/b", false, false},
+	{"caret negates too", []string{"a[^x]b"}, "a/b", false, false},
+	{"comment line", []string{"#a.go"}, "#a.go", false, false},
+	{"escaped hash", []string{`\#a.go`}, "#a.go", false, true},
+	{"escaped bang", []string{`\!a.go`}, "!a.go", false, true},
+	{"trailing spaces are ignored", []string{"a.go   "}, "a.go", false, true},
+	{"escaped trailing space", []string{`a\ `}, "a ", false, true},
+}
+
+func TestIgnoreMatcher(t *testing.T) {
+	for _, tc := range ignoreCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ParseIgnorePatterns(tc.patterns).Match(tc.path, tc.isDir)
+			if got != tc.want {
+				t.Errorf("%q: Match(%q, %v) = %v, want %v", tc.patterns, tc.path, tc.isDir, got, tc.want)
+			}
+		})
+	}
+}
+
+// TestIgnoreCasesAgainstGit checks the expectations of ignoreCases with
+// git check-ignore
+func TestIgnoreCasesAgainstGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found in PATH")
+	}
+	for _, tc := range ignoreCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTree(t, dir, map[string]string{".gitignore": strings.Join(tc.patterns, "\n") + "\n"})
+			path := filepath.Join(dir, filepath.FromSlash(tc.path))
+			if tc.isDir {
+				if err := os.MkdirAll(path, 0o755); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				writeTree(t, dir, map[string]string{tc.path: ""})
+			}
+			git(t, dir, "init", "-q")
+
+			err := exec.Command("git", "-C", dir, "check-ignore", "--no-index", "-q", tc.path).Run()
+			if got := err == nil; got != tc.want {
+				t.Errorf("git check-ignore %q with %q = %v, want %v", tc.path, tc.patterns, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIgnoreTreeNested(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		".gitignore":     "*.gen\n/top.txt\n",
+		"sub/.gitignore": "!keep.gen\nlocal/\n",
+	})
+	tree := NewIgnoreTree(dir, ".gitignore")
+	tree.Visit("")
+	tree.Visit("sub")
+
+	cases := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"a.gen", false, true},
+		{"sub/a.gen", false, true},
+		{"sub/keep.gen", false, false},
+		{"keep.gen", false, true},
+		{"top.txt", false, true},
+		{"sub/top.txt", false, false},
+		{"sub/local", true, true},
+		{"sub/local/a.go", false, true},
+		{"local", true, false},
+	}
+	for _, tc := range cases {
+		if got := tree.Match(tc.path, tc.isDir); got != tc.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tc.path, tc.isDir, got, tc.want)
+		}
+	}
+}
+
+// TestIgnoreTreeRepoParents checks that the .gitignore files above a root
+// within a repository apply, but not those above the repository
+func TestIgnoreTreeRepoParents(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		".gitignore":               "*.outside\n",
+		"repo/.git/HEAD":           "ref: refs/heads/main\n",
+		"repo/.gitignore":          "*.tmp\n/app/build/\n",
+		"repo/app/.gitignore":      "!keep.tmp\n",
+		"repo/app/src/.keep":       "",
+		"repo/app/build/a.go":      "",
+		"repo/app/src/a.outside":   "",
+		"repo/app/src/a.tmp":       "",
+		"repo/app/src/keep.tmp":    "",
+		"repo/app/src/ok.go":       "",
+		"repo/app/build/nested.go": "",
+	})
+	root := filepath.Join(dir, "repo", "app")
+	tree := NewIgnoreTree(root, ".gitignore")
+	tree.LoadRepoParents()
+	tree.Visit("")
+	tree.Visit("src")
+
+	cases := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"src/a.tmp", false, true},
+		{"src/keep.tmp", false, false},
+		{"build", true, true},
+		{"build/a.go", false, true},
+		{"src/ok.go", false, false},
+		{"src/a.outside", false, false},
+	}
+	for _, tc := range cases {
+		if got := tree.Match(tc.path, tc.isDir); got != tc.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tc.path, tc.isDir, got, tc.want)
+		}
+	}
+}
+
+// git runs git with args in dir, failing the test if it fails
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+}
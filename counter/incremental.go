@@ -0,0 +1,199 @@
+package counter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Incremental repeatedly counts one tree, re-reading only the files that
+// were added or whose size or modification time changed since the previous
+// count. Count walks the tree to find those files; Recount takes them from
+// a caller that already knows them, such as a watcher, which can find the
+// files below new paths with Discover. RandomSample is ignored.
+type Incremental struct {
+	root  string
+	opts  Options
+	cache map[string]cachedFile
+	// files are the counted files in walk order, followed by the files
+	// added by Recount
+	files []string
+	// dirs are the directories walked by the last Count and Discover
+	dirs []string
+
+	// Recounted is the number of files read by the last Count or Recount
+	Recounted int
+	// Removed is the number of counted files dropped by the last Recount
+	Removed int
+}
+
+// cachedFile is the analysis of one version of a file
+type cachedFile struct {
+	size     int64
+	modTime  time.Time
+	analysis fileAnalysis
+}
+
+// Incremental returns an Incremental counter for the tree at root
+func (c *Counter) Incremental(root string) *Incremental {
+	opts := c.countOptions()
+	opts.RandomSample = 0
+	return &Incremental{root: root, opts: opts, cache: make(map[string]cachedFile)}
+}
+
+// Count counts the tree, reusing the results of unchanged files from the
+// previous count
+func (inc *Incremental) Count(ctx context.Context) (*ProjectStats, error) {
+	var files, dirs []string
+	opts := inc.opts
+	opts.walkedDirs = &dirs
+	err := walkProject(ctx, inc.root, opts, func(path string) {
+		files = append(files, path)
+	})
+	if err != nil {
+		return nil, err
+	}
+	inc.dirs = withParentDirs(inc.root, dirs, files)
+
+	cache := make(map[string]cachedFile, len(files))
+	var changed []string
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			// Gone since the walk; analyzeFile reports it
+			changed = append(changed, path)
+			continue
+		}
+		if prev, ok := inc.cache[path]; ok && prev.size == info.Size() && prev.modTime.Equal(info.ModTime()) {
+			cache[path] = prev
+			continue
+		}
+		cache[path] = cachedFile{size: info.Size(), modTime: info.ModTime()}
+		changed = append(changed, path)
+	}
+	return inc.count(ctx, files, cache, changed)
+}
+
+// Dirs returns the directories holding the files of the last Count,
+// including the root, and those found by Discover since
+func (inc *Incremental) Dirs() []string {
+	return inc.dirs
+}
+
+// Discover walks the files and directories at paths below the root, such
+// as those created since the last count, as part of a walk of the root,
+// and returns the code files among them that pass the filters and the
+// directories it walked. The directories are added to Dirs.
+func (inc *Incremental) Discover(ctx context.Context, paths []string) (files, dirs []string, err error) {
+	opts := inc.opts
+	opts.walkedDirs = &dirs
+	err = walkPaths(ctx, inc.root, paths, opts, func(path string) {
+		files = append(files, path)
+	})
+	inc.dirs = append(inc.dirs, dirs...)
+	return files, dirs, err
+}
+
+// Recount counts the tree again without walking it: changed are the files
+// that passed the filters and were added or written since the previous
+// count, and removed the files that were deleted or no longer pass them,
+// or directories whose files are all gone. The other files keep their
+// results from the previous count.
+func (inc *Incremental) Recount(ctx context.Context, changed, removed []string) (*ProjectStats, error) {
+	gone := make(map[string]bool, len(removed))
+	for _, path := range removed {
+		gone[filepath.Clean(path)] = true
+	}
+	isGone := func(path string) bool {
+		for ; ; path = filepath.Dir(path) {
+			if gone[path] {
+				return true
+			}
+			if path == inc.root || path == filepath.Dir(path) {
+				return false
+			}
+		}
+	}
+	cache := make(map[string]cachedFile, len(inc.cache)+len(changed))
+	files := make([]string, 0, len(inc.files)+len(changed))
+	inc.Removed = 0
+	for _, path := range inc.files {
+		if isGone(path) {
+			inc.Removed++
+			continue
+		}
+		files = append(files, path)
+		cache[path] = inc.cache[path]
+	}
+	var reread []string
+	for _, path := range changed {
+		if isGone(path) {
+			continue
+		}
+		if _, ok := inc.cache[path]; !ok {
+			files = append(files, path)
+		}
+		entry := cachedFile{}
+		if info, err := os.Stat(path); err == nil {
+			entry = cachedFile{size: info.Size(), modTime: info.ModTime()}
+		}
+		cache[path] = entry
+		reread = append(reread, path)
+	}
+	return inc.count(ctx, files, cache, reread)
+}
+
+// count reads the changed files into cache, which holds an entry for each
+// of files, and totals files once all are read
+func (inc *Incremental) count(ctx context.Context, files []string, cache map[string]cachedFile, changed []string) (*ProjectStats, error) {
+	_, err := analyzePaths(ctx, emitPaths(changed), inc.opts, func(a fileAnalysis) {
+		entry := cache[a.path]
+		entry.analysis = a
+		cache[a.path] = entry
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	inc.cache = cache
+	inc.files = files
+	inc.Recounted = len(changed)
+
+	stats := NewProjectStats()
+	for _, path := range files {
+		stats.record(cache[path].analysis, inc.root, inc.opts)
+	}
+	stats.Terraform = detectTerraform(inc.root, files, inc.opts)
+	return stats, nil
+}
+
+// withParentDirs returns dirs followed by the directories between root
+// and each of files that are not in dirs, such as those of files listed by
+// git rather than walked
+func withParentDirs(root string, dirs, files []string) []string {
+	seen := make(map[string]bool, len(dirs))
+	for _, dir := range dirs {
+		seen[dir] = true
+	}
+	add := func(dir string) {
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	add(root)
+	for _, path := range files {
+		for dir := filepath.Dir(path); !seen[dir]; dir = filepath.Dir(dir) {
+			rel, err := filepath.Rel(root, dir)
+			if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+				break
+			}
+			add(dir)
+		}
+	}
+	return dirs
+}
@@ -0,0 +1,127 @@
+package counter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestIncrementalRecount checks that a Recount with the changed files
+// gives the counts of a full scan and reads only those files
+func TestIncrementalRecount(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"main.go":    "package main\n\nfunc main() {}\n",
+		"old.py":     "# old\nx = 1\n",
+		"pkg/lib.go": "package pkg\n",
+	})
+	c, err := New(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	inc := c.Incremental(dir)
+	if _, err := inc.Count(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if inc.Recounted != 3 {
+		t.Errorf("first count read %d files, want 3", inc.Recounted)
+	}
+
+	writeTree(t, dir, map[string]string{
+		"pkg/lib.go": "package pkg\n\n// X is one\nvar X = 1\n",
+		"new.css":    "body {}\n",
+	})
+	old := filepath.Join(dir, "old.py")
+	if err := os.Remove(old); err != nil {
+		t.Fatal(err)
+	}
+	changed := []string{filepath.Join(dir, "new.css"), filepath.Join(dir, "pkg", "lib.go")}
+	got, err := inc.Recount(ctx, changed, []string{old})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inc.Recounted != 2 {
+		t.Errorf("Recount read %d files, want 2", inc.Recounted)
+	}
+
+	want, err := c.Count(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.TotalFiles != want.TotalFiles || got.TotalStats != want.TotalStats {
+		t.Errorf("Recount = %d files %+v, want %d files %+v", got.TotalFiles, got.TotalStats, want.TotalFiles, want.TotalStats)
+	}
+	if !reflect.DeepEqual(got.StatsByExt, want.StatsByExt) {
+		t.Errorf("StatsByExt = %v, want %v", got.StatsByExt, want.StatsByExt)
+	}
+}
+
+// TestIncrementalDiscover checks that Discover applies the filters and
+// ignore files of a walk of the root, and that Recount drops the files of
+// a removed directory
+func TestIncrementalDiscover(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		".gitignore":   "gen/\n",
+		"main.go":      "package main\n",
+		"pkg/lib.go":   "package pkg\n",
+		"pkg/lib_test": "not code\n",
+	})
+	c, err := New(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	inc := c.Incremental(dir)
+	if _, err := inc.Count(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := inc.Dirs(), []string{dir, filepath.Join(dir, "pkg")}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Dirs() = %q, want %q", got, want)
+	}
+
+	writeTree(t, dir, map[string]string{
+		"pkg/sub/a.go":   "package sub\n",
+		"pkg/sub/a.log":  "log\n",
+		"pkg/gen/b.go":   "package gen\n",
+		"node_modules/c": "",
+	})
+	paths := []string{
+		filepath.Join(dir, "pkg", "sub"),
+		filepath.Join(dir, "pkg", "gen"),
+		filepath.Join(dir, "node_modules"),
+		filepath.Join(dir, "gone.go"),
+	}
+	files, dirs, err := inc.Discover(ctx, paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(dir, "pkg", "sub")
+	if want := []string{filepath.Join(sub, "a.go")}; !reflect.DeepEqual(files, want) {
+		t.Errorf("Discover found files %q, want %q", files, want)
+	}
+	if want := []string{sub}; !reflect.DeepEqual(dirs, want) {
+		t.Errorf("Discover walked %q, want %q", dirs, want)
+	}
+
+	stats, err := inc.Recount(ctx, files, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalFiles != 3 {
+		t.Errorf("Recount counted %d files, want 3", stats.TotalFiles)
+	}
+	if err := os.RemoveAll(filepath.Join(dir, "pkg")); err != nil {
+		t.Fatal(err)
+	}
+	stats, err = inc.Recount(ctx, nil, []string{filepath.Join(dir, "pkg")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalFiles != 1 || inc.Removed != 2 || inc.Recounted != 0 {
+		t.Errorf("Recount after removing pkg: %d files, %d removed, %d read, want 1, 2 and 0", stats.TotalFiles, inc.Removed, inc.Recounted)
+	}
+}
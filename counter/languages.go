@@ -0,0 +1,237 @@
+package counter
+
+import "regexp"
+
+// CodeExtensions defines file extensions to consider as code files
+var CodeExtensions = map[string]bool{
+	".go":        true,
+	".js":        true,
+	".ts":        true,
+	".jsx":       true,
+	".tsx":       true,
+	".java":      true,
+	".c":         true,
+	".cpp":       true,
+	".cc":        true,
+	".h":         true,
+	".hpp":       true,
+	".cs":        true,
+	".php":       true,
+	".rb":        true,
+	".py":        true,
+	".r":         true,
+	".rs":        true,
+	".swift":     true,
+	".kt":        true,
+	".scala":     true,
+	".sql":       true,
+	".html":      true,
+	".css":       true,
+	".scss":      true,
+	".json":      true,
+	".yaml":      true,
+	".yml":       true,
+	".toml":      true,
+	".xml":       true,
+	".sh":        true,
+	".bash":      true,
+	".rego":      true,
+	".jsonnet":   true,
+	".libsonnet": true,
+	".tf":        true,
+	".tfvars":    true,
+	".hcl":       true,
+	".feature":   true,
+	".bpmn":      true,
+	".cmmn":      true,
+	".mmd":       true,
+	".mermaid":   true,
+	".puml":      true,
+	".plantuml":  true,
+	".mo":        true,
+	".pkl":       true,
+	".cue":       true,
+	".kcl":       true,
+	".nix":       true,
+	".ipynb":     true,
+	".m":         true,
+	".pl":        true,
+	".pm":        true,
+	".mk":        true,
+	".cmake":     true,
+	".proto":     true,
+	".graphql":   true,
+	".gql":       true,
+	".md":        true,
+	".markdown":  true,
+	".rmd":       true,
+	".qmd":       true,
+	".vue":       true,
+	".svelte":    true,
+}
+
+// MarkdownExtensions are the code extensions only counted with
+// Options.Markdown: their prose counts as comments and their fenced code
+// blocks under the languages they name. R Markdown and Quarto documents,
+// like notebooks, are mostly code and always counted.
+var MarkdownExtensions = map[string]bool{
+	".md":       true,
+	".markdown": true,
+}
+
+// Profiles defines named extension sets selectable with --profile
+var Profiles = map[string][]string{
+	"config": {".yaml", ".yml", ".toml", ".json", ".hcl", ".tf", ".tfvars", ".pkl", ".cue", ".kcl", ".nix"},
+}
+
+// LanguageNames maps code extensions and the keys of SpecialFilenames to the
+// name of their language
+var LanguageNames = map[string]string{
+	".go":        "Go",
+	".js":        "JavaScript",
+	".ts":        "TypeScript",
+	".jsx":       "JavaScript JSX",
+	".tsx":       "TypeScript JSX",
+	".java":      "Java",
+	".c":         "C",
+	".cpp":       "C++",
+	".cc":        "C++",
+	".h":         "C Header",
+	".hpp":       "C++ Header",
+	".cs":        "C#",
+	".php":       "PHP",
+	".rb":        "Ruby",
+	".py":        "Python",
+	".r":         "R",
+	".rs":        "Rust",
+	".swift":     "Swift",
+	".kt":        "Kotlin",
+	".scala":     "Scala",
+	".sql":       "SQL",
+	".html":      "HTML",
+	".css":       "CSS",
+	".scss":      "SCSS",
+	".json":      "JSON",
+	".yaml":      "YAML",
+	".yml":       "YAML",
+	".toml":      "TOML",
+	".xml":       "XML",
+	".sh":        "Shell",
+	".bash":      "Bash",
+	".rego":      "Rego",
+	".jsonnet":   "Jsonnet",
+	".libsonnet": "Jsonnet",
+	".tf":        "Terraform",
+	".tfvars":    "Terraform Variables",
+	".hcl":       "HCL",
+	".feature":   "Gherkin",
+	".bpmn":      "BPMN",
+	".cmmn":      "CMMN",
+	".mmd":       "Mermaid",
+	".mermaid":   "Mermaid",
+	".puml":      "PlantUML",
+	".plantuml":  "PlantUML",
+	".mo":        "Modelica",
+	".pkl":       "Pkl",
+	".cue":       "CUE",
+	".kcl":       "KCL",
+	".nix":       "Nix",
+	".ipynb":     "Jupyter Notebook",
+	".m":         "Objective-C",
+	".pl":        "Perl",
+	".pm":        "Perl",
+	".mk":        "Makefile",
+	".cmake":     "CMake",
+	".proto":     "Protocol Buffers",
+	".graphql":   "GraphQL",
+	".gql":       "GraphQL",
+	".md":        "Markdown",
+	".markdown":  "Markdown",
+	".rmd":       "R Markdown",
+	".qmd":       "Quarto",
+	".vue":       "Vue",
+	".svelte":    "Svelte",
+	keyMATLAB:    "MATLAB",
+	KeyOther:     "Other",
+	keyProlog:    "Prolog",
+	"Makefile":   "Makefile",
+	"Dockerfile": "Dockerfile",
+}
+
+// LanguageName returns the language for ext, falling back to the extension
+func LanguageName(ext string) string {
+	if name, ok := LanguageNames[ext]; ok {
+		return name
+	}
+	return ext
+}
+
+// LanguageGroups maps the names of languages that are reported together
+// with a related language to the name of the group, e.g. TypeScript JSX
+// to TypeScript
+var LanguageGroups = map[string]string{
+	"JavaScript JSX":      "JavaScript",
+	"TypeScript JSX":      "TypeScript",
+	"C Header":            "C",
+	"C++ Header":          "C++",
+	"Terraform Variables": "Terraform",
+	"R Markdown":          "Markdown",
+	"Quarto":              "Markdown",
+}
+
+// ExtensionGroups maps keys to the name of the group they are reported in,
+// taking precedence over LanguageGroups. Config files add to it.
+var ExtensionGroups = map[string]string{}
+
+// GroupName returns the group the files of ext are reported in: its entry
+// in ExtensionGroups or the name of its language, followed through
+// LanguageGroups so that groups can be grouped in turn
+func GroupName(ext string) string {
+	name, ok := ExtensionGroups[ext]
+	if !ok {
+		name = LanguageName(ext)
+	}
+	// Bounded, so a cycle of groups cannot loop forever
+	for i := 0; i < len(LanguageGroups); i++ {
+		group, ok := LanguageGroups[name]
+		if !ok || group == name {
+			break
+		}
+		name = group
+	}
+	return name
+}
+
+// goGenericDecl matches Go func and type declarations with a type
+// parameter list, e.g. "func Map[T any](" or "type Pair[K comparable, V any]"
+var goGenericDecl = regexp.MustCompile(`^(func|type)\s+(\([^)]*\)\s*)?[A-Za-z_]\w*\s*\[[A-Za-z_]\w*(\s*,\s*[A-Za-z_]\w*)*\s+[^\]]+\]`)
+
+// IgnoreDirs defines directories to skip
+var IgnoreDirs = map[string]bool{
+	".git":         true,
+	".svn":         true,
+	"node_modules": true,
+	"vendor":       true,
+	"build":        true,
+	"dist":         true,
+	"target":       true,
+	"bin":          true,
+	"obj":          true,
+	".idea":        true,
+	".vscode":      true,
+	"coverage":     true,
+	".next":        true,
+	"__pycache__":  true,
+}
+
+// TestDirs defines directories skipped with --skip-test-dirs. Files below
+// them are test files for --tests.
+var TestDirs = map[string]bool{
+	"__tests__": true,
+	"test":      true,
+	"tests":     true,
+	"spec":      true,
+	"specs":     true,
+	"testdata":  true,
+	"fixtures":  true,
+}
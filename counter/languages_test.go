@@ -0,0 +1,134 @@
+package counter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// languageSamples are the files of testdata/languages, one or more per
+// supported language, with the key they are counted under and their
+// counts. Mixed lines, code with a trailing comment, count as code.
+var languageSamples = []struct {
+	file string
+	ext  string
+	want FileStats
+}{
+	{"c.c", ".c", FileStats{TotalLines: 8, CodeLines: 4, CommentLines: 3, BlankLines: 1, MixedLines: 1}},
+	{"cpp.cpp", ".cpp", FileStats{TotalLines: 8, CodeLines: 4, CommentLines: 3, BlankLines: 1, MixedLines: 1}},
+	{"cc.cc", ".cc", FileStats{TotalLines: 8, CodeLines: 4, CommentLines: 3, BlankLines: 1, MixedLines: 1}},
+	{"header.h", ".h", FileStats{TotalLines: 7, CodeLines: 4, CommentLines: 2, BlankLines: 1, MixedLines: 0}},
+	{"header.hpp", ".hpp", FileStats{TotalLines: 6, CodeLines: 4, CommentLines: 1, BlankLines: 1, MixedLines: 1}},
+	{"go.go", ".go", FileStats{TotalLines: 12, CodeLines: 6, CommentLines: 4, BlankLines: 2, MixedLines: 1}},
+	{"javascript.js", ".js", FileStats{TotalLines: 9, CodeLines: 4, CommentLines: 4, BlankLines: 1, MixedLines: 0}},
+	{"typescript.ts", ".ts", FileStats{TotalLines: 6, CodeLines: 3, CommentLines: 2, BlankLines: 1, MixedLines: 1}},
+	{"jsx.jsx", ".jsx", FileStats{TotalLines: 5, CodeLines: 3, CommentLines: 1, BlankLines: 1, MixedLines: 0}},
+	{"tsx.tsx", ".tsx", FileStats{TotalLines: 6, CodeLines: 3, CommentLines: 2, BlankLines: 1, MixedLines: 0}},
+	{"java.java", ".java", FileStats{TotalLines: 9, CodeLines: 5, CommentLines: 4, BlankLines: 0, MixedLines: 0}},
+	{"csharp.cs", ".cs", FileStats{TotalLines: 7, CodeLines: 4, CommentLines: 2, BlankLines: 1, MixedLines: 0}},
+	{"objc.m", ".m", FileStats{TotalLines: 6, CodeLines: 3, CommentLines: 2, BlankLines: 1, MixedLines: 0}},
+	{"php.php", ".php", FileStats{TotalLines: 7, CodeLines: 3, CommentLines: 3, BlankLines: 1, MixedLines: 0}},
+	{"ruby.rb", ".rb", FileStats{TotalLines: 6, CodeLines: 4, CommentLines: 1, BlankLines: 1, MixedLines: 1}},
+	{"python.py", ".py", FileStats{TotalLines: 10, CodeLines: 4, CommentLines: 3, BlankLines: 3, MixedLines: 1}},
+	{"r.r", ".r", FileStats{TotalLines: 4, CodeLines: 2, CommentLines: 1, BlankLines: 1, MixedLines: 0}},
+	{"rust.rs", ".rs", FileStats{TotalLines: 6, CodeLines: 3, CommentLines: 2, BlankLines: 1, MixedLines: 0}},
+	{"swift.swift", ".swift", FileStats{TotalLines: 6, CodeLines: 4, CommentLines: 1, BlankLines: 1, MixedLines: 0}},
+	{"kotlin.kt", ".kt", FileStats{TotalLines: 5, CodeLines: 3, CommentLines: 2, BlankLines: 0, MixedLines: 0}},
+	{"scala.scala", ".scala", FileStats{TotalLines: 5, CodeLines: 3, CommentLines: 1, BlankLines: 1, MixedLines: 0}},
+	{"sql.sql", ".sql", FileStats{TotalLines: 8, CodeLines: 5, CommentLines: 2, BlankLines: 1, MixedLines: 1}},
+	{"html.html", ".html", FileStats{TotalLines: 8, CodeLines: 6, CommentLines: 1, BlankLines: 1, MixedLines: 0}},
+	{"css.css", ".css", FileStats{TotalLines: 5, CodeLines: 3, CommentLines: 1, BlankLines: 1, MixedLines: 0}},
+	{"scss.scss", ".scss", FileStats{TotalLines: 5, CodeLines: 2, CommentLines: 2, BlankLines: 1, MixedLines: 0}},
+	{"json.json", ".json", FileStats{TotalLines: 4, CodeLines: 4, CommentLines: 0, BlankLines: 0, MixedLines: 0}},
+	{"yaml.yaml", ".yaml", FileStats{TotalLines: 6, CodeLines: 4, CommentLines: 1, BlankLines: 1, MixedLines: 1}},
+	{"yml.yml", ".yml", FileStats{TotalLines: 3, CodeLines: 2, CommentLines: 1, BlankLines: 0, MixedLines: 0}},
+	{"toml.toml", ".toml", FileStats{TotalLines: 4, CodeLines: 2, CommentLines: 1, BlankLines: 1, MixedLines: 0}},
+	{"xml.xml", ".xml", FileStats{TotalLines: 7, CodeLines: 4, CommentLines: 3, BlankLines: 0, MixedLines: 0}},
+	{"shell.sh", ".sh", FileStats{TotalLines: 5, CodeLines: 2, CommentLines: 2, BlankLines: 1, MixedLines: 0}},
+	{"bash.bash", ".bash", FileStats{TotalLines: 3, CodeLines: 2, CommentLines: 1, BlankLines: 0, MixedLines: 0}},
+	{"rego.rego", ".rego", FileStats{TotalLines: 4, CodeLines: 2, CommentLines: 1, BlankLines: 1, MixedLines: 0}},
+	{"jsonnet.jsonnet", ".jsonnet", FileStats{TotalLines: 5, CodeLines: 3, CommentLines: 2, BlankLines: 0, MixedLines: 0}},
+	{"libsonnet.libsonnet", ".libsonnet", FileStats{TotalLines: 2, CodeLines: 1, CommentLines: 1, BlankLines: 0, MixedLines: 0}},
+	{"terraform.tf", ".tf", FileStats{TotalLines: 6, CodeLines: 2, CommentLines: 3, BlankLines: 1, MixedLines: 0}},
+	{"tfvars.tfvars", ".tfvars", FileStats{TotalLines: 2, CodeLines: 1, CommentLines: 1, BlankLines: 0, MixedLines: 0}},
+	{"hcl.hcl", ".hcl", FileStats{TotalLines: 3, CodeLines: 1, CommentLines: 1, BlankLines: 1, MixedLines: 0}},
+	{"gherkin.feature", ".feature", FileStats{TotalLines: 6, CodeLines: 4, CommentLines: 1, BlankLines: 1, MixedLines: 0}},
+	{"bpmn.bpmn", ".bpmn", FileStats{TotalLines: 5, CodeLines: 4, CommentLines: 1, BlankLines: 0, MixedLines: 0}},
+	{"cmmn.cmmn", ".cmmn", FileStats{TotalLines: 4, CodeLines: 3, CommentLines: 1, BlankLines: 0, MixedLines: 0}},
+	{"mermaid.mmd", ".mmd", FileStats{TotalLines: 4, CodeLines: 2, CommentLines: 1, BlankLines: 1, MixedLines: 0}},
+	{"mermaid2.mermaid", ".mermaid", FileStats{TotalLines: 3, CodeLines: 2, CommentLines: 1, BlankLines: 0, MixedLines: 0}},
+	{"plantuml.puml", ".puml", FileStats{TotalLines: 6, CodeLines: 3, CommentLines: 3, BlankLines: 0, MixedLines: 0}},
+	{"plantuml2.plantuml", ".plantuml", FileStats{TotalLines: 3, CodeLines: 3, CommentLines: 0, BlankLines: 0, MixedLines: 0}},
+	{"modelica.mo", ".mo", FileStats{TotalLines: 7, CodeLines: 5, CommentLines: 2, BlankLines: 0, MixedLines: 0}},
+	{"pkl.pkl", ".pkl", FileStats{TotalLines: 3, CodeLines: 1, CommentLines: 2, BlankLines: 0, MixedLines: 0}},
+	{"cue.cue", ".cue", FileStats{TotalLines: 4, CodeLines: 2, CommentLines: 1, BlankLines: 1, MixedLines: 0}},
+	{"kcl.kcl", ".kcl", FileStats{TotalLines: 2, CodeLines: 1, CommentLines: 1, BlankLines: 0, MixedLines: 0}},
+	{"nix.nix", ".nix", FileStats{TotalLines: 4, CodeLines: 2, CommentLines: 2, BlankLines: 0, MixedLines: 0}},
+	{"perl.pl", ".pl", FileStats{TotalLines: 4, CodeLines: 1, CommentLines: 2, BlankLines: 1, MixedLines: 0}},
+	{"perl.pm", ".pm", FileStats{TotalLines: 3, CodeLines: 2, CommentLines: 1, BlankLines: 0, MixedLines: 0}},
+	{"makefile.mk", ".mk", FileStats{TotalLines: 4, CodeLines: 2, CommentLines: 1, BlankLines: 1, MixedLines: 0}},
+	{"Makefile", "Makefile", FileStats{TotalLines: 3, CodeLines: 2, CommentLines: 1, BlankLines: 0, MixedLines: 0}},
+	{"Dockerfile", "Dockerfile", FileStats{TotalLines: 4, CodeLines: 2, CommentLines: 1, BlankLines: 1, MixedLines: 0}},
+	{"cmake.cmake", ".cmake", FileStats{TotalLines: 5, CodeLines: 2, CommentLines: 3, BlankLines: 0, MixedLines: 0}},
+	{"proto.proto", ".proto", FileStats{TotalLines: 5, CodeLines: 4, CommentLines: 1, BlankLines: 0, MixedLines: 0}},
+	{"graphql.graphql", ".graphql", FileStats{TotalLines: 4, CodeLines: 3, CommentLines: 1, BlankLines: 0, MixedLines: 0}},
+	{"graphql.gql", ".gql", FileStats{TotalLines: 4, CodeLines: 3, CommentLines: 1, BlankLines: 0, MixedLines: 0}},
+	{"matlab.m", keyMATLAB, FileStats{TotalLines: 6, CodeLines: 2, CommentLines: 4, BlankLines: 0, MixedLines: 0}},
+	{"prolog.pl", keyProlog, FileStats{TotalLines: 4, CodeLines: 2, CommentLines: 2, BlankLines: 0, MixedLines: 0}},
+	{"markdown.md", ".md", FileStats{TotalLines: 9, CodeLines: 1, CommentLines: 6, BlankLines: 2, MixedLines: 0}},
+	{"markdown2.markdown", ".markdown", FileStats{TotalLines: 4, CodeLines: 0, CommentLines: 3, BlankLines: 1, MixedLines: 0}},
+	{"rmarkdown.rmd", ".rmd", FileStats{TotalLines: 10, CodeLines: 1, CommentLines: 7, BlankLines: 2, MixedLines: 0}},
+	{"quarto.qmd", ".qmd", FileStats{TotalLines: 6, CodeLines: 1, CommentLines: 4, BlankLines: 1, MixedLines: 0}},
+	{"vue.vue", ".vue", FileStats{TotalLines: 14, CodeLines: 9, CommentLines: 3, BlankLines: 2, MixedLines: 0}},
+	{"svelte.svelte", ".svelte", FileStats{TotalLines: 10, CodeLines: 7, CommentLines: 1, BlankLines: 2, MixedLines: 0}},
+	{"notebook.ipynb", ".ipynb", FileStats{TotalLines: 7, CodeLines: 2, CommentLines: 3, BlankLines: 2, MixedLines: 0}},
+}
+
+func TestCountReaderLanguages(t *testing.T) {
+	c, err := New(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range languageSamples {
+		t.Run(tc.file, func(t *testing.T) {
+			file, err := os.Open(filepath.Join("testdata", "languages", tc.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+			stats, err := c.CountReader(file, tc.ext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := FileStats{
+				TotalLines:   stats.TotalLines,
+				CodeLines:    stats.CodeLines,
+				CommentLines: stats.CommentLines,
+				BlankLines:   stats.BlankLines,
+				MixedLines:   stats.MixedLines,
+			}
+			if got != tc.want {
+				t.Errorf("CountReader(%s, %q) = %+v, want %+v", tc.file, tc.ext, got, tc.want)
+			}
+		})
+	}
+}
+
+// TestLanguageSamplesCoverExtensions checks that every code extension has
+// a sample, so that a new language comes with one
+func TestLanguageSamplesCoverExtensions(t *testing.T) {
+	covered := make(map[string]bool)
+	for _, tc := range languageSamples {
+		covered[tc.ext] = true
+	}
+	for ext := range CodeExtensions {
+		if !covered[ext] {
+			t.Errorf("no sample in testdata/languages for %s", ext)
+		}
+	}
+	for key := range languageSyntax {
+		if !covered[key] {
+			t.Errorf("no sample in testdata/languages for %s", key)
+		}
+	}
+}
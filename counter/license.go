@@ -0,0 +1,58 @@
+package counter
+
+import (
+	"bytes"
+	"regexp"
+)
+
+// licenseMarker matches the words that make a comment block a license or
+// copyright header
+var licenseMarker = regexp.MustCompile(`(?i)copyright|licen[cs]e|spdx-license-identifier|all rights reserved|©`)
+
+// headerTracker finds the license and copyright headers among the comment
+// blocks at the top of a file. Blank lines separate blocks, so a package
+// doc comment following a license header is not part of it, and the first
+// line of code ends the top of the file.
+type headerTracker struct {
+	done bool
+	// block counts the comment lines of the current block and license is
+	// set once one of them matched licenseMarker
+	block   int
+	license bool
+	// lines counts the comment lines of the headers found so far
+	lines int
+}
+
+// track follows the next line, already trimmed, of the given kind. A
+// shebang line is not part of a header.
+func (h *headerTracker) track(line []byte, kind LineKind) {
+	if h.done || bytes.HasPrefix(line, []byte("#!")) {
+		return
+	}
+	switch kind {
+	case CommentLine:
+		h.block++
+		h.license = h.license || licenseMarker.Match(line)
+	case BlankLine:
+		h.endBlock()
+	default:
+		h.endBlock()
+		h.done = true
+	}
+}
+
+func (h *headerTracker) endBlock() {
+	if h.license {
+		h.lines += h.block
+	}
+	h.block, h.license = 0, false
+}
+
+// add records the headers found in stats once the whole file was read
+func (h *headerTracker) add(stats *FileStats) {
+	h.endBlock()
+	if h.lines > 0 {
+		stats.LicenseHeaderFiles++
+		stats.LicenseHeaderLines += h.lines
+	}
+}
@@ -0,0 +1,156 @@
+package counter
+
+import (
+	"bytes"
+	"io"
+)
+
+// Line ending styles reported by lineEndingReader
+const (
+	EndingLF    = "lf"
+	EndingCRLF  = "crlf"
+	EndingCR    = "cr"
+	EndingMixed = "mixed"
+	// EndingNone is reported for files without any line break
+	EndingNone = "none"
+)
+
+// dominantSampleLines is how many lines the dominant line ending of a file
+// is taken from
+const dominantSampleLines = 100
+
+// LineEndingStats counts files by their dominant line ending
+type LineEndingStats struct {
+	LF   int
+	CRLF int
+	CR   int
+	// Mixed counts the files using several line endings
+	Mixed int
+}
+
+// Inconsistent reports whether the files counted by s do not all use the
+// same line ending
+func (s LineEndingStats) Inconsistent() bool {
+	kinds := 0
+	for _, n := range []int{s.LF, s.CRLF, s.CR} {
+		if n > 0 {
+			kinds++
+		}
+	}
+	return kinds > 1 || s.Mixed > 0
+}
+
+// add counts one file whose dominant ending is dominant and whose line
+// ending overall is ending
+func (s *LineEndingStats) add(dominant, ending string) {
+	if ending == EndingMixed {
+		s.Mixed++
+	}
+	switch dominant {
+	case EndingLF:
+		s.LF++
+	case EndingCRLF:
+		s.CRLF++
+	case EndingCR:
+		s.CR++
+	}
+}
+
+// lineEndingReader passes the text read from r through and counts its
+// line breaks, both over the whole text and in its first
+// dominantSampleLines lines
+type lineEndingReader struct {
+	r io.Reader
+	// all counts the LF, CRLF and CR breaks of the text, sample those of
+	// its first lines
+	all, sample [3]int
+	lines       int
+	// pendingCR is set when the last byte read was a CR, which is a CRLF
+	// if the next one is an LF
+	pendingCR bool
+}
+
+// Indexes of the counts of lineEndingReader
+const (
+	breakLF = iota
+	breakCRLF
+	breakCR
+)
+
+func (l *lineEndingReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	for rest := p[:n]; ; {
+		i := bytes.IndexAny(rest, "\r\n")
+		if i < 0 {
+			if len(rest) > 0 && l.pendingCR {
+				l.add(breakCR)
+				l.pendingCR = false
+			}
+			break
+		}
+		switch {
+		case l.pendingCR && i == 0 && rest[0] == '\n':
+			l.add(breakCRLF)
+		case l.pendingCR:
+			l.add(breakCR)
+			if rest[i] == '\n' {
+				l.add(breakLF)
+			}
+		case rest[i] == '\n':
+			l.add(breakLF)
+		}
+		l.pendingCR = rest[i] == '\r'
+		rest = rest[i+1:]
+	}
+	if err == io.EOF && l.pendingCR {
+		l.add(breakCR)
+		l.pendingCR = false
+	}
+	return n, err
+}
+
+// add counts a line break of kind
+func (l *lineEndingReader) add(kind int) {
+	l.all[kind]++
+	if l.lines < dominantSampleLines {
+		l.sample[kind]++
+		l.lines++
+	}
+}
+
+// ending returns the line ending of the whole text: "lf", "crlf", "cr",
+// "mixed" if it uses several, or "none" if it has no line breaks
+func (l *lineEndingReader) ending() string {
+	return endingOf(l.all, EndingMixed)
+}
+
+// dominant returns the most common line ending among the first lines of
+// the text, or "none" if they have no line breaks
+func (l *lineEndingReader) dominant() string {
+	return endingOf(l.sample, "")
+}
+
+// endingOf returns the line ending of the line breaks counted in counts,
+// or mixed if it is not "" and several are counted; otherwise the most
+// common one wins, CRLF over LF over CR on ties
+func endingOf(counts [3]int, mixed string) string {
+	lf, crlf, cr := counts[breakLF], counts[breakCRLF], counts[breakCR]
+	kinds := 0
+	for _, n := range counts {
+		if n > 0 {
+			kinds++
+		}
+	}
+	switch {
+	case kinds == 0:
+		return EndingNone
+	case kinds > 1 && mixed != "":
+		return mixed
+	case crlf >= lf && crlf >= cr:
+		return EndingCRLF
+	case cr > lf:
+		return EndingCR
+	default:
+		return EndingLF
+	}
+}
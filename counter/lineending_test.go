@@ -0,0 +1,73 @@
+package counter
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestLineEndingReader(t *testing.T) {
+	cases := []struct {
+		content  string
+		ending   string
+		dominant string
+	}{
+		{"", EndingNone, EndingNone},
+		{"no break", EndingNone, EndingNone},
+		{"a\nb\n", EndingLF, EndingLF},
+		{"a\r\nb\r\n", EndingCRLF, EndingCRLF},
+		{"a\rb\r", EndingCR, EndingCR},
+		{"a\r", EndingCR, EndingCR},
+		{"a\r\r\n", EndingMixed, EndingCRLF},
+		{"a\r\nb\nc\n", EndingMixed, EndingLF},
+		{"a\r\nb\r\nc\n", EndingMixed, EndingCRLF},
+		{"a\rb\n\n", EndingMixed, EndingLF},
+		{strings.Repeat("a\r\n", dominantSampleLines) + strings.Repeat("a\n", 2*dominantSampleLines), EndingMixed, EndingCRLF},
+	}
+	for _, tc := range cases {
+		// One byte at a time, a CRLF is split across reads
+		for _, r := range []io.Reader{strings.NewReader(tc.content), iotest.OneByteReader(strings.NewReader(tc.content))} {
+			endings := &lineEndingReader{r: r}
+			data, err := io.ReadAll(endings)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tc.content {
+				t.Errorf("%q was read as %q", tc.content, data)
+			}
+			if got := endings.ending(); got != tc.ending {
+				t.Errorf("ending of %q = %q, want %q", tc.content, got, tc.ending)
+			}
+			if got := endings.dominant(); got != tc.dominant {
+				t.Errorf("dominant ending of %q = %q, want %q", tc.content, got, tc.dominant)
+			}
+		}
+	}
+}
+
+func TestLineEndingReaderError(t *testing.T) {
+	failure := errors.New("read failed")
+	r := io.MultiReader(strings.NewReader("a\nb\n"), iotest.ErrReader(failure))
+	if _, err := io.ReadAll(&lineEndingReader{r: r}); !errors.Is(err, failure) {
+		t.Errorf("read error = %v, want %v", err, failure)
+	}
+}
+
+func TestLineEndingStatsInconsistent(t *testing.T) {
+	cases := []struct {
+		stats LineEndingStats
+		want  bool
+	}{
+		{LineEndingStats{}, false},
+		{LineEndingStats{LF: 3}, false},
+		{LineEndingStats{LF: 3, CRLF: 1}, true},
+		{LineEndingStats{CRLF: 2, Mixed: 1}, true},
+	}
+	for _, tc := range cases {
+		if got := tc.stats.Inconsistent(); got != tc.want {
+			t.Errorf("%+v.Inconsistent() = %v, want %v", tc.stats, got, tc.want)
+		}
+	}
+}
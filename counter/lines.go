@@ -0,0 +1,172 @@
+package counter
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// DefaultMaxLineBytes is the line length up to which lines are classified
+// when Options.MaxLineBytes is not set
+const DefaultMaxLineBytes = 1 << 20
+
+// readChunk is how much of a file countLines reads at once; larger chunks
+// cut the reads of huge files such as SQL dumps
+const readChunk = 64 << 10
+
+// countLines classifies each line read from r using the comment syntax of
+// ext. An unknown or empty ext counts every non-blank line as code. Lines
+// longer than opts.MaxLineBytes are classified by their start.
+func countLines(r io.Reader, ext string, opts Options) (FileStats, error) {
+	if ext == ".ipynb" {
+		return countNotebook(r, opts)
+	}
+	if regionSplitters[ext] != nil {
+		stats, _, err := countRegions(r, ext, opts)
+		return stats, err
+	}
+
+	var stats FileStats
+	maxLine := opts.MaxLineBytes
+	if maxLine <= 0 {
+		maxLine = DefaultMaxLineBytes
+	}
+	lines := &lineReader{r: bufio.NewReaderSize(r, readChunk), max: maxLine}
+	classifier := newLineClassifier(ext)
+	var branches []string
+	if opts.Complexity {
+		branches = complexityBranches[ext]
+	}
+
+	var header *headerTracker
+	if opts.LicenseHeaders {
+		header = &headerTracker{}
+	}
+
+	longLine := opts.LongLine
+	if longLine == 0 {
+		longLine = DefaultLongLine
+	}
+
+	var annotations *annotationTracker
+	if opts.ModelicaDetail && ext == ".mo" {
+		annotations = &annotationTracker{}
+	}
+
+	for {
+		raw, length, err := lines.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return stats, err
+		}
+		line := bytes.TrimSpace(raw)
+		stats.TotalLines++
+		if length > maxLine {
+			stats.TruncatedLines++
+		}
+		if opts.StyleMetrics {
+			stats.addStyle(raw, length, longLine)
+		}
+
+		kind := classifier.classify(line)
+		if opts.lineKinds != nil {
+			*opts.lineKinds = append(*opts.lineKinds, kind)
+		}
+		if header != nil {
+			header.track(line, kind)
+		}
+		if kind == BlankLine {
+			stats.BlankLines++
+			continue
+		}
+
+		if stats.MaxLineLength == 0 || length < stats.MinLineLength {
+			stats.MinLineLength = length
+		}
+		if length > stats.MaxLineLength {
+			stats.MaxLineLength = length
+		}
+		stats.TotalLineLength += length
+
+		if stats.TotalLines == 1 && isShebang(line) && stats.addMeta(opts.MetaAs) {
+			continue
+		}
+		if annotations != nil && kind != CommentLine && annotations.track(line) {
+			stats.AnnotationLines++
+			continue
+		}
+
+		stats.addLine(kind, opts.MixedAs)
+		if kind != CommentLine && branches != nil {
+			stats.Complexity += countBranches(line, branches)
+		}
+		if kind != CommentLine && opts.GoGenerics && ext == ".go" && goGenericDecl.Match(line) {
+			stats.GenericLines++
+		}
+	}
+
+	if header != nil {
+		header.add(&stats)
+	}
+	stats.MaxComplexity = stats.Complexity
+	return stats, nil
+}
+
+// lineReader reads lines of any length, keeping at most max bytes of each
+type lineReader struct {
+	r   *bufio.Reader
+	max int
+	buf []byte
+}
+
+// next returns the next line without its line break, cut to max bytes, and
+// the full length of the line. The line is only valid until the next call.
+// It returns io.EOF after the last line.
+func (l *lineReader) next() (line []byte, length int, err error) {
+	l.buf = l.buf[:0]
+	var last, beforeLast byte
+	read := false
+	for {
+		chunk, err := l.r.ReadSlice('\n')
+		if len(chunk) > 0 {
+			read = true
+			length += len(chunk)
+			if room := l.max - len(l.buf); room > 0 {
+				if room > len(chunk) {
+					room = len(chunk)
+				}
+				l.buf = append(l.buf, chunk[:room]...)
+			}
+			if len(chunk) >= 2 {
+				beforeLast, last = chunk[len(chunk)-2], chunk[len(chunk)-1]
+			} else {
+				beforeLast, last = last, chunk[0]
+			}
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return nil, 0, err
+		}
+		if !read {
+			return nil, 0, io.EOF
+		}
+		break
+	}
+
+	// Drop the line break, including the CR of a CRLF, as bufio.ScanLines does
+	if last == '\n' {
+		length--
+		last = beforeLast
+	}
+	if last == '\r' {
+		length--
+	}
+	if len(l.buf) > length {
+		l.buf = l.buf[:length]
+	}
+	return l.buf, length, nil
+}
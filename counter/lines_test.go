@@ -0,0 +1,170 @@
+package counter
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestLineLengths checks the line lengths measured for the files of
+// testdata/lengths: bytes before trimming, without the line break, and
+// only of non-blank lines
+func TestLineLengths(t *testing.T) {
+	for _, tc := range []struct {
+		file          string
+		min, max, sum int
+		avg           float64
+	}{
+		// Tab indentation counts, the closing brace is the shortest
+		{"indented.go", 1, 14, 40, 10},
+		// CRLF line breaks do not count
+		{"crlf.py", 5, 18, 23, 11.5},
+		// Surrounding spaces count, as do both bytes of é
+		{"spaces.js", 1, 9, 16, 16.0 / 3},
+		{"empty.py", 0, 0, 0, 0},
+	} {
+		t.Run(tc.file, func(t *testing.T) {
+			file, err := os.Open(filepath.Join("testdata", "lengths", tc.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+			stats, err := countLines(file, filepath.Ext(tc.file), Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stats.MinLineLength != tc.min || stats.MaxLineLength != tc.max || stats.TotalLineLength != tc.sum {
+				t.Errorf("min, max, total = %d, %d, %d, want %d, %d, %d",
+					stats.MinLineLength, stats.MaxLineLength, stats.TotalLineLength, tc.min, tc.max, tc.sum)
+			}
+			if avg := stats.AverageLineLength(); avg != tc.avg {
+				t.Errorf("AverageLineLength() = %v, want %v", avg, tc.avg)
+			}
+		})
+	}
+}
+
+// TestLineLengthsAdd checks that adding stats keeps the shortest and
+// longest line of both
+func TestLineLengthsAdd(t *testing.T) {
+	var total FileStats
+	total.Add(FileStats{TotalLines: 2, MinLineLength: 4, MaxLineLength: 9, TotalLineLength: 13})
+	total.Add(FileStats{TotalLines: 1, BlankLines: 1})
+	total.Add(FileStats{TotalLines: 2, MinLineLength: 2, MaxLineLength: 6, TotalLineLength: 8})
+	if total.MinLineLength != 2 || total.MaxLineLength != 9 || total.TotalLineLength != 21 {
+		t.Errorf("min, max, total = %d, %d, %d, want 2, 9, 21", total.MinLineLength, total.MaxLineLength, total.TotalLineLength)
+	}
+	if avg := total.AverageLineLength(); avg != 5.25 {
+		t.Errorf("AverageLineLength() = %v, want 5.25", avg)
+	}
+}
+
+// benchmarkInput repeats the lines made by line until the input has at
+// least size bytes
+func benchmarkInput(size int, line func(i int) string) []byte {
+	var buf bytes.Buffer
+	for i := 0; buf.Len() < size; i++ {
+		buf.WriteString(line(i))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// benchmarkCountLines counts input with countLines and, as a baseline,
+// with scanLines
+func benchmarkCountLines(b *testing.B, ext string, input []byte) {
+	b.Run("chunks", func(b *testing.B) {
+		b.SetBytes(int64(len(input)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := countLines(bytes.NewReader(input), ext, Options{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("scanner", func(b *testing.B) {
+		b.SetBytes(int64(len(input)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := scanLines(bytes.NewReader(input), ext); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// scanLines classifies the lines read from r the way countLines used to
+// read them: one at a time with bufio.Scanner, each trimmed into a string
+// of its own
+func scanLines(r io.Reader, ext string) (FileStats, error) {
+	var stats FileStats
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, DefaultMaxLineBytes)
+	classifier := newLineClassifier(ext)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		stats.TotalLines++
+		if kind := classifier.classify([]byte(line)); kind == BlankLine {
+			stats.BlankLines++
+		} else {
+			stats.addLine(kind, "")
+		}
+	}
+	return stats, scanner.Err()
+}
+
+// BenchmarkCountLinesCode counts a SQL dump, long lines of plain code
+// with a few strings
+func BenchmarkCountLinesCode(b *testing.B) {
+	input := benchmarkInput(4<<20, func(i int) string {
+		return fmt.Sprintf("INSERT INTO events (id, kind, payload, created_at) VALUES (%d, 'click', '{\"x\": %d, \"y\": %d}', '2024-01-01 00:00:%02d');", i, i%640, i%480, i%60)
+	})
+	benchmarkCountLines(b, ".sql", input)
+}
+
+// BenchmarkCountLinesComments counts Go source that is mostly line and
+// block comments
+func BenchmarkCountLinesComments(b *testing.B) {
+	input := benchmarkInput(4<<20, func(i int) string {
+		switch i % 8 {
+		case 0:
+			return "/*"
+		case 1, 2:
+			return " * " + strings.Repeat("block comment text ", 3)
+		case 3:
+			return " */"
+		case 7:
+			return fmt.Sprintf("var x%d = %d", i, i)
+		default:
+			return "// " + strings.Repeat("line comment text ", 3)
+		}
+	})
+	benchmarkCountLines(b, ".go", input)
+}
+
+// BenchmarkCountLinesMixed counts Go source mixing code, strings,
+// trailing comments and blank lines
+func BenchmarkCountLinesMixed(b *testing.B) {
+	input := benchmarkInput(4<<20, func(i int) string {
+		switch i % 6 {
+		case 0:
+			return fmt.Sprintf("func f%d(a, b int) int {", i)
+		case 1:
+			return "\ts := \"a // string\" + `raw /* string */`"
+		case 2:
+			return "\treturn a + b // the sum"
+		case 3:
+			return "}"
+		case 4:
+			return ""
+		default:
+			return "// f returns the sum of a and b"
+		}
+	})
+	benchmarkCountLines(b, ".go", input)
+}
@@ -0,0 +1,122 @@
+package counter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// defaultLogger is used when Options.Logger is nil: it prints warnings to
+// stderr
+var defaultLogger = slog.New(NewLogHandler(os.Stderr, slog.LevelWarn))
+
+// logger returns the logger of o, or defaultLogger if none is set
+func (o Options) logger() *slog.Logger {
+	if o.Logger != nil {
+		return o.Logger
+	}
+	return defaultLogger
+}
+
+// NewLogHandler returns a slog.Handler writing one line per record of at
+// least level to w, in the form the line-counter command prints warnings:
+//
+//	Warning: could not read file path=main.go err="permission denied"
+func NewLogHandler(w io.Writer, level slog.Leveler) slog.Handler {
+	return &logHandler{w: w, mu: new(sync.Mutex), level: level}
+}
+
+type logHandler struct {
+	w     io.Writer
+	mu    *sync.Mutex
+	level slog.Leveler
+	// attrs holds the attributes added by WithAttrs, already formatted
+	attrs string
+	// group is the prefix of the keys of the group opened by WithGroup
+	group string
+}
+
+func (h *logHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *logHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	b.WriteString(levelLabel(r.Level))
+	b.WriteString(": ")
+	b.WriteString(r.Message)
+	b.WriteString(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		appendAttr(&b, h.group, a)
+		return true
+	})
+	b.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	b.WriteString(h.attrs)
+	for _, a := range attrs {
+		appendAttr(&b, h.group, a)
+	}
+	h2 := *h
+	h2.attrs = b.String()
+	return &h2
+}
+
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.group += name + "."
+	return &h2
+}
+
+// levelLabel returns the word a record of level starts with
+func levelLabel(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return "Error"
+	case level >= slog.LevelWarn:
+		return "Warning"
+	case level >= slog.LevelInfo:
+		return "Info"
+	}
+	return "Debug"
+}
+
+// appendAttr writes a to b as " key=value", with the keys of groups
+// prefixed by the group name. Values holding spaces, quotes or "=" are
+// quoted.
+func appendAttr(b *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, member := range a.Value.Group() {
+			appendAttr(b, prefix, member)
+		}
+		return
+	}
+
+	value := a.Value.String()
+	if value == "" || strings.ContainsAny(value, " \t\r\n\"=") {
+		value = strconv.Quote(value)
+	}
+	fmt.Fprintf(b, " %s%s=%s", prefix, a.Key, value)
+}
@@ -0,0 +1,80 @@
+package counter
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Values of Options.MetaAs
+const (
+	MetaAsSyntax  = "syntax"
+	MetaAsCode    = "code"
+	MetaAsComment = "comment"
+	MetaAsMeta    = "meta"
+)
+
+// frontMatterDelimiters maps the lines opening the front matter of a
+// Markdown file, YAML or TOML, to the lines that may close it
+var frontMatterDelimiters = map[string][]string{
+	"---": {"---", "..."},
+	"+++": {"+++"},
+}
+
+// addMeta counts a non-blank meta line, such as a shebang or Markdown front
+// matter, in MetaLines and as code or a comment as metaAs selects. It
+// reports false for MetaAsSyntax, leaving the line to be classified as
+// usual.
+func (s *FileStats) addMeta(metaAs string) bool {
+	switch metaAs {
+	case MetaAsCode:
+		s.CodeLines++
+	case MetaAsComment:
+		s.CommentLines++
+	case MetaAsMeta:
+	default:
+		return false
+	}
+	s.MetaLines++
+	return true
+}
+
+// isShebang reports whether line, the first of a file, is a "#!" line
+func isShebang(line []byte) bool {
+	return bytes.HasPrefix(line, []byte("#!"))
+}
+
+// frontMatterLines returns the number of lines at the start of lines that
+// form YAML or TOML front matter, including both delimiters, or 0 if there
+// is none
+func frontMatterLines(lines []string) int {
+	if len(lines) == 0 {
+		return 0
+	}
+	closers, ok := frontMatterDelimiters[strings.TrimSpace(lines[0])]
+	if !ok {
+		return 0
+	}
+	for i, line := range lines[1:] {
+		for _, closer := range closers {
+			if strings.TrimSpace(line) == closer {
+				return i + 2
+			}
+		}
+	}
+	return 0
+}
+
+// countMeta counts text, the front matter of a file, with opts.MetaAs. As
+// MetaAsSyntax it counts as prose like the rest of a Markdown file.
+func countMeta(text string, opts Options) FileStats {
+	stats := countProse(text, opts)
+	if opts.MetaAs == "" || opts.MetaAs == MetaAsSyntax {
+		return stats
+	}
+	lines := stats.CommentLines
+	stats.CommentLines = 0
+	for i := 0; i < lines; i++ {
+		stats.addMeta(opts.MetaAs)
+	}
+	return stats
+}
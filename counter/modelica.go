@@ -0,0 +1,48 @@
+package counter
+
+import "bytes"
+
+// annotationTracker follows Modelica annotation(...) blocks across lines
+type annotationTracker struct {
+	depth int
+}
+
+// track reports whether line, already trimmed, belongs to an annotation
+// block: either it starts one or an earlier line left one open. A block
+// opened after code on the same line leaves that line counted as code.
+func (a *annotationTracker) track(line []byte) bool {
+	inside := a.depth > 0
+	starts := bytes.HasPrefix(line, []byte("annotation"))
+	if !inside {
+		at := bytes.Index(line, []byte("annotation"))
+		if at < 0 {
+			return false
+		}
+		line = line[at:]
+	}
+
+	a.depth += parenBalance(line)
+	if a.depth < 0 {
+		a.depth = 0
+	}
+	return inside || starts
+}
+
+// parenBalance returns the number of "(" minus ")" outside string literals
+func parenBalance(line []byte) int {
+	balance := 0
+	inString := false
+	for i := 0; i < len(line); i++ {
+		switch ch := line[i]; {
+		case inString && ch == '\\':
+			i++
+		case ch == '"':
+			inString = !inString
+		case !inString && ch == '(':
+			balance++
+		case !inString && ch == ')':
+			balance--
+		}
+	}
+	return balance
+}
@@ -0,0 +1,161 @@
+package counter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// notebook is the part of a Jupyter notebook that is counted
+type notebook struct {
+	Cells []struct {
+		CellType string         `json:"cell_type"`
+		Source   notebookSource `json:"source"`
+	} `json:"cells"`
+	Metadata struct {
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+}
+
+// notebookSource is the source of a cell, saved either as one string or as
+// a list of lines
+type notebookSource string
+
+func (s *notebookSource) UnmarshalJSON(data []byte) error {
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err == nil {
+		*s = notebookSource(strings.Join(lines, ""))
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	*s = notebookSource(text)
+	return nil
+}
+
+// notebookLanguages maps the kernel languages of notebooks to the code
+// extension whose syntax their code cells use
+var notebookLanguages = map[string]string{
+	"python": ".py",
+	"r":      ".r",
+	"ruby":   ".rb",
+	"scala":  ".scala",
+	"kotlin": ".kt",
+	"bash":   ".sh",
+	"sql":    ".sql",
+}
+
+// countNotebook counts the Jupyter notebook read from r: code cells as code
+// of the notebook's kernel language (Python unless its metadata says
+// otherwise) and markdown and raw cells as comments. Cell outputs are not
+// counted.
+func countNotebook(r io.Reader, opts Options) (FileStats, error) {
+	var nb notebook
+	if err := json.NewDecoder(r).Decode(&nb); err != nil {
+		return FileStats{}, fmt.Errorf("invalid notebook: %v", err)
+	}
+
+	codeExt := ".py"
+	if ext, ok := notebookLanguages[strings.ToLower(nb.Metadata.LanguageInfo.Name)]; ok {
+		codeExt = ext
+	}
+
+	var stats FileStats
+	for _, cell := range nb.Cells {
+		source := string(cell.Source)
+		if cell.CellType == "code" {
+			cellStats, err := countLines(strings.NewReader(source), codeExt, opts)
+			if err != nil {
+				return FileStats{}, err
+			}
+			stats.Add(cellStats)
+			continue
+		}
+
+		stats.Add(countProse(source, opts))
+	}
+	stats.MaxComplexity = stats.Complexity
+	return stats, nil
+}
+
+// countProse counts text that documents code, such as markdown cells, as
+// comment lines, apart from blank lines
+func countProse(text string, opts Options) FileStats {
+	var stats FileStats
+	if text == "" {
+		return stats
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		blank := strings.TrimSpace(line) == ""
+		if opts.lineKinds != nil {
+			kind := CommentLine
+			if blank {
+				kind = BlankLine
+			}
+			*opts.lineKinds = append(*opts.lineKinds, kind)
+		}
+		if blank {
+			stats.Add(FileStats{TotalLines: 1, BlankLines: 1})
+			continue
+		}
+		stats.Add(FileStats{
+			TotalLines:      1,
+			CommentLines:    1,
+			MinLineLength:   len(line),
+			MaxLineLength:   len(line),
+			TotalLineLength: len(line),
+		})
+	}
+	return stats
+}
+
+// notebookLineCounts returns the number of cell source lines on each line
+// of the notebook text. Jupyter saves a source as a list with one string
+// per line, one per line of the file, so the lines of a "source" list hold
+// the lines of their strings and all other lines none.
+func notebookLineCounts(text string) []int {
+	// sourceLines counts the lines of the JSON strings of value, as
+	// countLines would see them
+	sourceLines := func(value string) int {
+		value = strings.TrimSuffix(strings.TrimSpace(value), ",")
+		var source notebookSource
+		if err := json.Unmarshal([]byte(value), &source); err != nil || source == "" {
+			return 0
+		}
+		n := strings.Count(string(source), "\n")
+		if !strings.HasSuffix(string(source), "\n") {
+			n++
+		}
+		return n
+	}
+
+	lines := splitLines(text)
+	counts := make([]int, len(lines))
+	inSource := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if inSource {
+			if strings.HasPrefix(trimmed, "]") {
+				inSource = false
+				continue
+			}
+			counts[i] = sourceLines(trimmed)
+			continue
+		}
+		value, ok := strings.CutPrefix(trimmed, `"source":`)
+		if !ok {
+			continue
+		}
+		if strings.TrimSpace(value) == "[" {
+			inSource = true
+			continue
+		}
+		counts[i] = sourceLines(value)
+	}
+	return counts
+}
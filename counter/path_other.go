@@ -0,0 +1,16 @@
+//go:build !windows
+
+package counter
+
+import "os"
+
+// longPath returns path unchanged; only Windows limits the path length
+func longPath(path string) string {
+	return path
+}
+
+// isHidden reports false: outside Windows, files are hidden by a leading
+// dot in their name alone
+func isHidden(info os.FileInfo) bool {
+	return false
+}
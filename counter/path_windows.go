@@ -0,0 +1,38 @@
+//go:build windows
+
+package counter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// maxShortPath is the length from which paths get the \\?\ prefix. Windows
+// limits paths to 260 characters and directory names to 12 fewer.
+const maxShortPath = 248
+
+// longPath returns path in the \\?\ form that lifts the length limit of
+// the Windows API if it is too long to be used as is
+func longPath(path string) string {
+	if len(path) < maxShortPath || strings.HasPrefix(path, `\\?\`) {
+		return path
+	}
+	// The \\?\ form is not normalised, so it must be absolute and clean
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if strings.HasPrefix(abs, `\\`) {
+		return `\\?\UNC\` + abs[2:]
+	}
+	return `\\?\` + abs
+}
+
+// isHidden reports whether the file described by info has the hidden
+// attribute set
+func isHidden(info os.FileInfo) bool {
+	attrs, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	return ok && attrs.FileAttributes&syscall.FILE_ATTRIBUTE_HIDDEN != 0
+}
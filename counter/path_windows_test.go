@@ -0,0 +1,111 @@
+//go:build windows
+
+package counter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestLongPath(t *testing.T) {
+	long := strings.Repeat(`a\`, maxShortPath/2)
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"short path", `C:\src\main.go`, `C:\src\main.go`},
+		{"long path", `C:\` + long + "main.go", `\\?\C:\` + long + "main.go"},
+		{"long path is cleaned", `C:\` + long + `b\..\main.go`, `\\?\C:\` + long + "main.go"},
+		{"long UNC path", `\\server\share\` + long + "main.go", `\\?\UNC\server\share\` + long + "main.go"},
+		{"long relative path", long + "main.go", `\\?\` + filepath.Join(cwd, long+"main.go")},
+		{"prefixed path", `\\?\C:\` + long + "main.go", `\\?\C:\` + long + "main.go"},
+		{"prefixed UNC path", `\\?\UNC\server\share\` + long, `\\?\UNC\server\share\` + long},
+	}
+	for _, tc := range cases {
+		if got := longPath(tc.path); got != tc.want {
+			t.Errorf("%s: longPath(%q) = %q, want %q", tc.name, tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestCountLongPath(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.FromSlash(strings.Repeat("directory/", 30) + "main.go")
+	writeTree(t, dir, map[string]string{filepath.ToSlash(name): "package main\n\nfunc main() {}\n"})
+	if path := filepath.Join(dir, name); len(path) < 260 {
+		t.Fatalf("%s is not longer than MAX_PATH", path)
+	}
+
+	stats, err := Count(context.Background(), dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalFiles != 1 || stats.TotalStats.CodeLines != 2 {
+		t.Errorf("counted %d files with %d code lines, want 1 with 2", stats.TotalFiles, stats.TotalStats.CodeLines)
+	}
+}
+
+func TestIsHidden(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"main.go":         "package main\n",
+		"visible/a.go":    "package a\n",
+		"hidden/a.go":     "package a\n",
+		"hidden.go":       "package main\n",
+		"visible/keep.go": "package a\n",
+	})
+	hide(t, filepath.Join(dir, "hidden"))
+	hide(t, filepath.Join(dir, "hidden.go"))
+
+	for name, want := range map[string]bool{"hidden": true, "hidden.go": true, "visible": false, "main.go": false} {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := isHidden(info); got != want {
+			t.Errorf("isHidden(%s) = %v, want %v", name, got, want)
+		}
+	}
+
+	// Hidden directories are skipped, but not hidden files or a hidden root
+	stats, err := Count(context.Background(), dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalFiles != 4 {
+		t.Errorf("counted %d files, want 4 outside the hidden directory", stats.TotalFiles)
+	}
+	hide(t, dir)
+	stats, err = Count(context.Background(), dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalFiles != 4 {
+		t.Errorf("counted %d files below a hidden root, want 4", stats.TotalFiles)
+	}
+}
+
+// hide sets the hidden attribute of the file at path
+func hide(t *testing.T, path string) {
+	t.Helper()
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrs, err := syscall.GetFileAttributes(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.SetFileAttributes(p, attrs|syscall.FILE_ATTRIBUTE_HIDDEN); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,404 @@
+package counter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"hash"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+)
+
+// fileAnalysis is everything measured about one file by a worker
+type fileAnalysis struct {
+	path string
+	ext  string
+	// language is the key of the language the file is counted as: ext,
+	// or the key its content tells, such as .hpp for a C++ header
+	language string
+	stats    FileStats
+
+	// skipped is set when the file is unreadable or filtered out
+	skipped bool
+	// kind is KindBinary, KindMinified or KindGenerated for files left out
+	// of the totals, or ""
+	kind string
+	// category is the Category of Options.Classifiers the file is in, or ""
+	category Category
+
+	// parts holds the stats of each language whose lines a file embedding
+	// other languages holds, including its own under ext, or is nil
+	parts map[string]FileStats
+
+	ending   string
+	dominant string
+	// lines are the kinds of the counted lines in order, with ByAuthor
+	lines []LineKind
+	blame []BlameEntry
+
+	// hash is the content hash of the file with Options.Unique
+	hash [sha256.Size]byte
+	// duplicateOf is set instead of the measurements for a link to a file
+	// emitted earlier, to the path of that file
+	duplicateOf string
+}
+
+// countPaths counts the files below root produced by walk with opts.Jobs
+// workers. A single merger aggregates the results in the order walk emitted
+// the paths, so the output does not depend on scheduling. It also returns
+// the emitted paths. Once ctx is done the remaining files are skipped.
+func countPaths(ctx context.Context, root string, walk func(emit func(path string)) error, opts Options) (*ProjectStats, []string, error) {
+	stats := NewProjectStats()
+	paths, err := analyzePaths(ctx, walk, opts, func(a fileAnalysis) {
+		stats.record(a, root, opts)
+	})
+	return stats, paths, err
+}
+
+// analyzePaths analyzes the files produced by walk with opts.Jobs workers
+// and passes the results to sink in the order walk emitted the paths. It
+// returns the emitted paths.
+func analyzePaths(ctx context.Context, walk func(emit func(path string)) error, opts Options, sink func(fileAnalysis)) ([]string, error) {
+	var links linkTracker
+	return analyzeJobs(ctx, func(emit func(string, func() fileAnalysis)) error {
+		return walk(func(path string) {
+			if opts.Unique {
+				if first := links.firstPath(path); first != "" {
+					emit(path, func() fileAnalysis { return fileAnalysis{path: path, duplicateOf: first} })
+					return
+				}
+			}
+			emit(path, func() fileAnalysis { return analyzeFile(path, opts) })
+		})
+	}, opts, sink)
+}
+
+// analyzeJobs is analyzePaths for walks that emit each path along with the
+// function analyzing it, such as the members of an archive
+func analyzeJobs(ctx context.Context, walk func(emit func(path string, analyze func() fileAnalysis)) error, opts Options, sink func(fileAnalysis)) ([]string, error) {
+	workers := opts.Jobs
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	type job struct {
+		index   int
+		path    string
+		analyze func() fileAnalysis
+	}
+	type result struct {
+		index    int
+		analysis fileAnalysis
+	}
+	jobs := make(chan job, workers*4)
+	results := make(chan result, workers*4)
+
+	// The walker owns paths and walkErr until it closes jobs
+	var paths []string
+	var walkErr error
+	go func() {
+		defer close(jobs)
+		walkErr = walk(func(path string, analyze func() fileAnalysis) {
+			jobs <- job{index: len(paths), path: path, analyze: analyze}
+			paths = append(paths, path)
+		})
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if ctx.Err() != nil {
+					results <- result{index: j.index, analysis: fileAnalysis{path: j.path, skipped: true}}
+					continue
+				}
+				results <- result{index: j.index, analysis: j.analyze()}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	pending := make(map[int]fileAnalysis)
+	next := 0
+	var progress Progress
+	for r := range results {
+		pending[r.index] = r.analysis
+		for {
+			analysis, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			sink(analysis)
+			next++
+			if opts.Progress != nil && !analysis.skipped {
+				progress.Files++
+				progress.Lines += analysis.stats.TotalLines
+				progress.Path = analysis.path
+				opts.Progress(progress)
+			}
+		}
+	}
+	return paths, walkErr
+}
+
+// emitPaths returns a walk function for countPaths that emits files in order
+func emitPaths(files []string) func(emit func(string)) error {
+	return func(emit func(string)) error {
+		for _, path := range files {
+			emit(path)
+		}
+		return nil
+	}
+}
+
+// source opens the content of a file for one pass over it
+type source func() (io.ReadCloser, error)
+
+// fileSource returns the source reading the file at path
+func fileSource(path string) source {
+	return func() (io.ReadCloser, error) {
+		return os.Open(longPath(path))
+	}
+}
+
+// dataSource returns the source reading data, e.g. an archive member
+func dataSource(data []byte) source {
+	return func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+}
+
+// read calls fn with the content of s decoded to UTF-8 by decodeText
+func (s source) read(fn func(r io.Reader) error) error {
+	r, err := s()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return fn(decodeText(r))
+}
+
+// analyzeFile measures the file at path. Unreadable files are reported as
+// a warning and marked as skipped.
+func analyzeFile(path string, opts Options) fileAnalysis {
+	var info os.FileInfo
+	if opts.cache != nil {
+		var err error
+		if info, err = os.Stat(longPath(path)); err == nil {
+			if a, ok := opts.cache.lookup(path, info); ok {
+				return a
+			}
+		}
+	}
+
+	ext := fileType(path, opts)
+	a := analyze(path, ext, fileSource(path), opts)
+	if info != nil && !a.skipped {
+		opts.cache.store(a, info)
+	}
+
+	if opts.ByAuthor && !a.skipped && a.kind != KindBinary {
+		entries, err := blameFile(path, a)
+		if err != nil {
+			opts.logger().Warn("could not blame file", "path", path, "err", err)
+		}
+		a.blame = entries
+	}
+	return a
+}
+
+// analyze measures the content of the file named path, counted under ext,
+// that src opens. Unreadable files are reported as a warning and marked as
+// skipped.
+//
+// The file is read once: with Options.Unique its bytes are hashed as they
+// are read, the heuristics, the sniffing and the classifiers look at a
+// buffered prefix of its decoded text, and the same text is then counted
+// while its line breaks are tallied.
+func analyze(path, ext string, src source, opts Options) fileAnalysis {
+	a := fileAnalysis{path: path, ext: ext, language: ext}
+	unreadable := func(err error) fileAnalysis {
+		opts.logger().Warn("could not read file", "path", path, "err", err)
+		opts.errors.add(path, err)
+		a.skipped = true
+		return a
+	}
+
+	raw, err := src()
+	if err != nil {
+		return unreadable(err)
+	}
+	defer raw.Close()
+	var in io.Reader = raw
+	var hasher hash.Hash
+	if opts.Unique {
+		hasher = sha256.New()
+		in = io.TeeReader(raw, hasher)
+	}
+	text := bufio.NewReaderSize(decodeText(in), heuristicsSize)
+	prefix, err := text.Peek(heuristicsSize)
+	if err != nil && err != io.EOF {
+		return unreadable(err)
+	}
+	// finish reads what is left of the file into the hash, so the hash is
+	// of the whole content however much of it was needed
+	finish := func() error {
+		if hasher == nil {
+			return nil
+		}
+		if _, err := io.Copy(io.Discard, text); err != nil {
+			return err
+		}
+		copy(a.hash[:], hasher.Sum(nil))
+		return nil
+	}
+
+	if heuristics[ext] != nil && !opts.NoHeuristics {
+		a.language = refineHead(ext, prefix)
+		if a.language != ext {
+			opts.logger().Debug("detected language by content", "path", path, "key", a.language, "language", LanguageName(a.language), "instead_of", ext)
+		}
+	}
+
+	// The prefix is only valid until the text is read further
+	head := append([]byte(nil), prefix[:min(len(prefix), sniffSize)]...)
+	kind := sniffContent(path, head, opts)
+	if kind == KindBinary {
+		opts.logger().Info("leaving out file", "path", path, "kind", kind)
+		a.kind = kind
+		if err := finish(); err != nil {
+			return unreadable(err)
+		}
+		return a
+	}
+
+	if opts.ByAuthor {
+		opts.lineKinds = &a.lines
+	}
+	endings := &lineEndingReader{r: text}
+	if regionSplitters[a.language] != nil {
+		a.stats, a.parts, err = countRegions(endings, a.language, opts)
+	} else {
+		a.stats, err = countLines(endings, a.language, opts)
+	}
+	if err == nil {
+		// A count may stop before the end, as a notebook does after its
+		// JSON document
+		_, err = io.Copy(io.Discard, endings)
+	}
+	if err == nil {
+		err = finish()
+	}
+	if err != nil {
+		return unreadable(err)
+	}
+	a.ending, a.dominant = endings.ending(), endings.dominant()
+	if opts.filterLineEnding() && a.ending != opts.LineEnding {
+		opts.logger().Info("skipping file", "path", path, "reason", "--line-ending", "line_ending", a.ending)
+		a.skipped = true
+		return a
+	}
+
+	if kind == "" && looksMinified(a.stats) {
+		kind = KindMinified
+	}
+	a.category = classify(path, head, opts.Classifiers)
+	if !opts.IncludeGenerated {
+		a.kind = kind
+		if kind != "" {
+			opts.logger().Info("leaving out file", "path", path, "kind", kind)
+		}
+	}
+	return a
+}
+
+// record adds the analysis of one file below root to p, or to p.Tests if
+// it is a test file and opts.Tests is TestsSeparate. With opts.Unique,
+// copies of a file recorded earlier only count as duplicates.
+func (p *ProjectStats) record(a fileAnalysis, root string, opts Options) {
+	if a.skipped {
+		return
+	}
+	if a.kind != KindBinary && a.duplicateOf == "" {
+		if reason := opts.sizeSkipReason(a.stats); reason != "" {
+			opts.logger().Info("skipping file", "path", a.path, "reason", reason, "lines", a.stats.TotalLines)
+			p.FilteredFiles++
+			return
+		}
+	}
+	if opts.Unique {
+		if p.hashes == nil {
+			p.hashes = make(map[[sha256.Size]byte]string)
+		}
+		if first := duplicateOf(a, p.hashes); first != "" {
+			opts.logger().Info("skipping duplicate", "path", a.path, "copy_of", first)
+			p.Duplicates.Files++
+			p.Duplicates.add(first, a.path)
+			return
+		}
+	}
+	if opts.Tests == TestsSeparate && IsTestFile(relativePath(root, a.path)) {
+		if p.Tests == nil {
+			p.Tests = NewProjectStats()
+		}
+		p.Tests.addAnalysis(a, opts)
+		return
+	}
+	p.addAnalysis(a, opts)
+}
+
+// duplicateOf returns the path of the file a is a copy of, or "" if it is
+// the first file with its content, which is then added to hashes
+func duplicateOf(a fileAnalysis, hashes map[[sha256.Size]byte]string) string {
+	if a.duplicateOf != "" {
+		return a.duplicateOf
+	}
+	if first, ok := hashes[a.hash]; ok {
+		return first
+	}
+	hashes[a.hash] = a.path
+	return ""
+}
+
+func (p *ProjectStats) addAnalysis(a fileAnalysis, opts Options) {
+	if a.kind != "" {
+		p.Generated.add(a.kind, a.ext, a.stats)
+		return
+	}
+	if a.parts != nil {
+		p.addParts(a.ext, a.language, a.stats, a.parts)
+	} else {
+		p.addFile(a.ext, a.language, a.stats)
+	}
+	if a.category != "" {
+		p.addCategory(a.category, a.stats)
+	}
+
+	if opts.CollectFiles || opts.OnFile != nil {
+		result := a.result()
+		if opts.CollectFiles {
+			p.Files = append(p.Files, result)
+		}
+		if opts.OnFile != nil {
+			opts.OnFile(result)
+		}
+	}
+	p.LineEndings.add(a.dominant, a.ending)
+	if opts.ByAuthor {
+		p.addBlame(a.blame, opts.MixedAs)
+	}
+}
+
+// result returns the FileResult of a
+func (a fileAnalysis) result() FileResult {
+	return FileResult{Path: a.path, Ext: a.ext, Language: a.language, Stats: a.stats, LineEnding: a.ending, Category: a.category}
+}
@@ -0,0 +1,54 @@
+package counter
+
+import (
+	"crypto/sha256"
+	"io"
+	"strings"
+	"testing"
+)
+
+// TestAnalyzeReadsOnce checks that a file is opened and read once with
+// every option that needs its content
+func TestAnalyzeReadsOnce(t *testing.T) {
+	content := "#include <vector>\r\nclass A {};\n// done\n" + strings.Repeat("int x;\n", 5000)
+	opens := 0
+	var read int64
+	src := func() (io.ReadCloser, error) {
+		opens++
+		return countingReader{strings.NewReader(content), &read}, nil
+	}
+	opts := Options{Unique: true, CheckLineEndings: true, LineEnding: EndingMixed}
+	a := analyze("a.h", ".h", src, opts)
+	if opens != 1 || read != int64(len(content)) {
+		t.Errorf("file was opened %d times and %d bytes were read, want 1 and %d", opens, read, len(content))
+	}
+	if a.skipped {
+		t.Fatal("file was skipped")
+	}
+	if a.ext != ".h" || a.language != ".hpp" {
+		t.Errorf("counted under %s as %s, want .h as .hpp", a.ext, a.language)
+	}
+	if a.hash != sha256.Sum256([]byte(content)) {
+		t.Errorf("hash is not that of the content")
+	}
+	if a.ending != EndingMixed || a.dominant != EndingLF {
+		t.Errorf("line endings %s and dominant %s, want mixed and lf", a.ending, a.dominant)
+	}
+	if want := (FileStats{TotalLines: 5003, CodeLines: 5002, CommentLines: 1}); a.stats.TotalLines != want.TotalLines || a.stats.CodeLines != want.CodeLines || a.stats.CommentLines != want.CommentLines {
+		t.Errorf("stats = %+v, want %+v", a.stats, want)
+	}
+}
+
+// countingReader adds the number of bytes read from it to n
+type countingReader struct {
+	r io.Reader
+	n *int64
+}
+
+func (c countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	*c.n += int64(n)
+	return n, err
+}
+
+func (countingReader) Close() error { return nil }
@@ -0,0 +1,148 @@
+package counter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// CountRev counts the code files of the git revision rev, such as a commit,
+// tag or branch, of the repository at repoPath. The files are read from the
+// object database with git, so the working tree is not touched and bare
+// repositories work too. In a subdirectory of a work tree only the files
+// below it are counted. Files are filtered like those of an archive and
+// reported as paths below repoPath; symlinks and submodules are skipped.
+// With Options.Cache, the measurements of files are cached by git object,
+// so revisions sharing most of their files are counted quickly.
+// Like Count, it returns partial stats along with ctx.Err() if ctx is done
+// first.
+func (c *Counter) CountRev(ctx context.Context, repoPath, rev string) (*ProjectStats, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("--rev requires git, but it was not found in PATH")
+	}
+	opts := c.archiveOptions()
+	if opts.Cache != "" && len(opts.Classifiers) == 0 {
+		opts.cache = openObjectCache(opts.Cache, repoPath, opts)
+	}
+	walk, err := memberJobs(ctx, repoPath, func(fn memberFunc) error {
+		return readRev(ctx, repoPath, rev, fn)
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := countMembers(ctx, repoPath, walk, opts)
+	if err == nil {
+		saveCache(opts)
+	}
+	return stats, err
+}
+
+// revFile is a regular file of a git tree
+type revFile struct {
+	name   string
+	object string
+	size   int64
+}
+
+// readRev calls fn for each regular file of rev in the repository at
+// repoPath. read asks a git cat-file process for the file's contents, so
+// it must be called before fn returns.
+func readRev(ctx context.Context, repoPath, rev string, fn memberFunc) error {
+	files, err := revFiles(ctx, repoPath, rev)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "-C", repoPath, "cat-file", "--batch")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	defer func() {
+		stdin.Close()
+		cmd.Wait()
+	}()
+
+	r := bufio.NewReader(stdout)
+	for _, file := range files {
+		file := file
+		err := fn(file.name, file.size, file.object, func() ([]byte, error) {
+			return catBlob(stdin, r, file.object)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revFiles lists the regular files of rev with git ls-tree
+func revFiles(ctx context.Context, repoPath, rev string) ([]revFile, error) {
+	cmd := exec.CommandContext(ctx, "git", "-C", repoPath, "ls-tree", "-r", "-z", "-l", rev)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("could not read revision %s of %s: %s", rev, repoPath, msg)
+	}
+
+	var files []revFile
+	for _, entry := range strings.Split(string(out), "\x00") {
+		// <mode> SP <type> SP <object> SP <size> TAB <path>
+		meta, name, ok := strings.Cut(entry, "\t")
+		fields := strings.Fields(meta)
+		if !ok || len(fields) != 4 || fields[1] != "blob" || fields[0] == "120000" {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			continue
+		}
+		if name, ok = memberName(name); ok {
+			files = append(files, revFile{name: name, object: fields[2], size: size})
+		}
+	}
+	return files, nil
+}
+
+// catBlob asks git cat-file --batch, writing to w and read from r, for the
+// contents of object
+func catBlob(w io.Writer, r *bufio.Reader, object string) ([]byte, error) {
+	if _, err := fmt.Fprintln(w, object); err != nil {
+		return nil, err
+	}
+	// <object> SP <type> SP <size> LF <contents> LF
+	header, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(header)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("could not read object %s: %s", object, strings.TrimSpace(header))
+	}
+	size, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not read object %s: %s", object, strings.TrimSpace(header))
+	}
+	data := make([]byte, size+1)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data[:size], nil
+}
@@ -0,0 +1,182 @@
+package counter
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+)
+
+// estimateFromSample counts n files chosen uniformly at random from files
+// and extrapolates the counts to the full set. The files of each extension
+// are estimated from the share of its sampled files that were counted, so
+// binary, minified and generated files and those filtered by size are left
+// out as in a full count, and their lines from the per-file averages of
+// the sampled ones. The totals are the sums of the extensions, including
+// those none of whose files were sampled: they are listed with all their
+// files and no lines. Only the sampled files are read: the files of an
+// extension whose language is told by content are split between languages
+// in the proportions found in the sample.
+func estimateFromSample(ctx context.Context, files []string, n int, opts Options) *ProjectStats {
+	shuffled := append([]string(nil), files...)
+	rand.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+
+	// File counts are taken from all files, so tests cannot be told apart
+	sampleOpts := opts
+	sampleOpts.Tests = TestsInclude
+	sample := NewProjectStats()
+	// strata holds the counted files of the sample by extension, to be
+	// extrapolated to the files of that extension. They are only added to,
+	// so the callbacks and per-file lists of opts run once, for sample.
+	strata := make(map[string]*ProjectStats)
+	stratumOpts := sampleOpts
+	stratumOpts.CollectFiles, stratumOpts.OnFile, stratumOpts.ByAuthor = false, nil, false
+	analyzePaths(ctx, emitPaths(shuffled[:n]), sampleOpts, func(a fileAnalysis) {
+		counted := sample.TotalFiles
+		sample.record(a, "", sampleOpts)
+		if sample.TotalFiles > counted {
+			if strata[a.ext] == nil {
+				strata[a.ext] = NewProjectStats()
+			}
+			strata[a.ext].addAnalysis(a, stratumOpts)
+		}
+	})
+
+	// found and sampled count all files and the sampled ones by extension,
+	// whether they were counted or not
+	found := make(map[string]int)
+	for _, path := range files {
+		found[fileType(path, opts)]++
+	}
+	sampled := make(map[string]int)
+	for _, path := range shuffled[:n] {
+		sampled[fileType(path, opts)]++
+	}
+
+	stats := NewProjectStats()
+	stats.SampledFiles = n
+	stats.PerExtFileLengths = sample.PerExtFileLengths
+	stats.PerLanguageFileLengths = sample.PerLanguageFileLengths
+	stats.Generated = scaleGenerated(sample.Generated, len(files), n)
+	stats.FilteredFiles = scaleCount(sample.FilteredFiles, len(files), n)
+	for ext, fileCount := range found {
+		if sampled[ext] == 0 {
+			stats.FilesByExt[ext] += fileCount
+			stats.FilesByLanguage[ext] += fileCount
+			stats.TotalFiles += fileCount
+			continue
+		}
+		if stratum := strata[ext]; stratum != nil {
+			stats.addStratum(stratum, fileCount, sampled[ext])
+		}
+	}
+	return stats
+}
+
+// addStratum adds to p the estimate for total files of an extension, of
+// which sampled files were read and those counted added to stratum. Every
+// count is extrapolated by key, and the totals of p are the sums of the
+// extrapolated keys.
+func (p *ProjectStats) addStratum(stratum *ProjectStats, total, sampled int) {
+	for key, fileCount := range stratum.FilesByExt {
+		files := scaleCount(fileCount, total, sampled)
+		p.FilesByExt[key] += files
+		p.TotalFiles += files
+	}
+	for key, keyStats := range stratum.StatsByExt {
+		scaled := scaleStats(keyStats, total, sampled)
+		stats := p.StatsByExt[key]
+		stats.Add(scaled)
+		p.StatsByExt[key] = stats
+		p.TotalStats.Add(scaled)
+	}
+
+	// The counted files of the extension are split between its languages
+	// as in the sample, so they add up to its files
+	files := scaleCount(stratum.TotalFiles, total, sampled)
+	for language, languageCount := range splitByShare(files, stratum.FilesByLanguage) {
+		p.FilesByLanguage[language] += languageCount
+	}
+	for language, languageStats := range stratum.StatsByLanguage {
+		stats := p.StatsByLanguage[language]
+		stats.Add(scaleStats(languageStats, total, sampled))
+		p.StatsByLanguage[language] = stats
+	}
+}
+
+// splitByShare splits total between the keys of shares in proportion to
+// their values, giving the files left over by rounding down to the keys
+// with the largest remainders
+func splitByShare(total int, shares map[string]int) map[string]int {
+	keys := make([]string, 0, len(shares))
+	sum := 0
+	for key, share := range shares {
+		keys = append(keys, key)
+		sum += share
+	}
+	sort.Strings(keys)
+
+	split := make(map[string]int, len(shares))
+	left := total
+	for _, key := range keys {
+		split[key] = total * shares[key] / sum
+		left -= split[key]
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return total*shares[keys[i]]%sum > total*shares[keys[j]]%sum
+	})
+	for _, key := range keys[:left] {
+		split[key]++
+	}
+	return split
+}
+
+// scaleStats extrapolates stats measured over sampled files to total files.
+// Line length and complexity extremes are kept as observed in the sample.
+func scaleStats(stats FileStats, total, sampled int) FileStats {
+	scale := func(v int) int {
+		return scaleCount(v, total, sampled)
+	}
+	stats.TotalLines = scale(stats.TotalLines)
+	stats.CodeLines = scale(stats.CodeLines)
+	stats.BlankLines = scale(stats.BlankLines)
+	stats.CommentLines = scale(stats.CommentLines)
+	stats.TotalLineLength = scale(stats.TotalLineLength)
+	stats.GenericLines = scale(stats.GenericLines)
+	stats.MixedLines = scale(stats.MixedLines)
+	stats.AnnotationLines = scale(stats.AnnotationLines)
+	stats.LicenseHeaderLines = scale(stats.LicenseHeaderLines)
+	stats.LicenseHeaderFiles = scale(stats.LicenseHeaderFiles)
+	stats.MetaLines = scale(stats.MetaLines)
+	stats.Complexity = scale(stats.Complexity)
+	stats.TruncatedLines = scale(stats.TruncatedLines)
+	stats.LongLines = scale(stats.LongLines)
+	stats.TrailingWhitespaceLines = scale(stats.TrailingWhitespaceLines)
+	stats.MixedIndentLines = scale(stats.MixedIndentLines)
+	return stats
+}
+
+// scaleGenerated extrapolates the files left out of a sample of sampled
+// files to total files
+func scaleGenerated(g GeneratedStats, total, sampled int) GeneratedStats {
+	scaled := GeneratedStats{
+		Binary:    scaleCount(g.Binary, total, sampled),
+		Minified:  scaleCount(g.Minified, total, sampled),
+		Generated: scaleCount(g.Generated, total, sampled),
+		Stats:     scaleStats(g.Stats, total, sampled),
+	}
+	for ext, stats := range g.StatsByExt {
+		scaled.addExt(ext, scaleStats(stats, total, sampled))
+	}
+	return scaled
+}
+
+// scaleCount extrapolates v, counted over sampled files, to total files,
+// rounded to the nearest integer. Nothing is extrapolated from no files.
+func scaleCount(v, total, sampled int) int {
+	if sampled == 0 {
+		return 0
+	}
+	return int(float64(v)*float64(total)/float64(sampled) + 0.5)
+}
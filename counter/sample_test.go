@@ -0,0 +1,260 @@
+package counter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestRandomSampleRefinedTypes checks that files whose language is told
+// apart by their content are bucketed under the same keys when sampled as
+// when counted in full
+func TestRandomSampleRefinedTypes(t *testing.T) {
+	dir := t.TempDir()
+	tree := make(map[string]string)
+	for i := 0; i < 20; i++ {
+		tree[fmt.Sprintf("c%02d.h", i)] = "#include <stdio.h>\nint f(void);\n"
+		tree[fmt.Sprintf("cpp%02d.h", i)] = "#include <vector>\nnamespace app {\nint f();\n}\n"
+	}
+	writeTree(t, dir, tree)
+
+	for _, sample := range []int{0, 10} {
+		c, err := New(Options{RandomSample: sample})
+		if err != nil {
+			t.Fatal(err)
+		}
+		stats, err := c.Count(context.Background(), dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := map[string]int{".h": 40}; !reflect.DeepEqual(stats.FilesByExt, want) {
+			t.Errorf("sample %d: FilesByExt = %v, want %v", sample, stats.FilesByExt, want)
+		}
+		// A sample splits the files in its own proportions
+		if sample == 0 {
+			if want := map[string]int{".h": 20, ".hpp": 20}; !reflect.DeepEqual(stats.FilesByLanguage, want) {
+				t.Errorf("FilesByLanguage = %v, want %v", stats.FilesByLanguage, want)
+			}
+		} else if h, hpp := stats.FilesByLanguage[".h"], stats.FilesByLanguage[".hpp"]; h+hpp != 40 || len(stats.FilesByLanguage) != 2 {
+			t.Errorf("sample %d: FilesByLanguage = %v, want 40 files split between .h and .hpp", sample, stats.FilesByLanguage)
+		}
+	}
+}
+
+func TestSplitByShare(t *testing.T) {
+	cases := []struct {
+		total  int
+		shares map[string]int
+		want   map[string]int
+	}{
+		{40, map[string]int{".h": 1, ".hpp": 1}, map[string]int{".h": 20, ".hpp": 20}},
+		{10, map[string]int{".h": 3}, map[string]int{".h": 10}},
+		{10, map[string]int{".h": 1, ".hpp": 1, ".m": 1}, map[string]int{".h": 4, ".hpp": 3, ".m": 3}},
+		{100, map[string]int{".pl": 2, ".pro": 7}, map[string]int{".pl": 22, ".pro": 78}},
+	}
+	for _, tc := range cases {
+		if got := splitByShare(tc.total, tc.shares); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("splitByShare(%d, %v) = %v, want %v", tc.total, tc.shares, got, tc.want)
+		}
+	}
+}
+
+// TestScaleStats checks that every count of FileStats is extrapolated and
+// the extremes are kept as measured
+func TestScaleStats(t *testing.T) {
+	var in FileStats
+	v := reflect.ValueOf(&in).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		v.Field(i).SetInt(int64(i + 1))
+	}
+	kept := map[string]bool{"MinLineLength": true, "MaxLineLength": true, "MaxComplexity": true}
+
+	out := reflect.ValueOf(scaleStats(in, 30, 10))
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+		want := int64(i+1) * 3
+		if kept[name] {
+			want = int64(i + 1)
+		}
+		if got := out.Field(i).Int(); got != want {
+			t.Errorf("%s = %d, want %d", name, got, want)
+		}
+	}
+}
+
+// TestRandomSampleMatchesCount checks that a sample of every file gives
+// the exact counts, whatever a full count leaves out or filters
+func TestRandomSampleMatchesCount(t *testing.T) {
+	leftOut := make(map[string]string)
+	for i := 0; i < 5; i++ {
+		leftOut[fmt.Sprintf("gen%02d.go", i)] = "// Code generated by stringer. DO NOT EDIT.\npackage app\n\nvar x = 1\n"
+		leftOut[fmt.Sprintf("src%02d.go", i)] = "package app\n\n// f returns one\nfunc f() int { return 1 }\n"
+		leftOut[fmt.Sprintf("blob%02d.go", i)] = "package app\x00\x01\x02\n"
+		leftOut[fmt.Sprintf("app%02d.min.js", i)] = "var a=1;" + strings.Repeat("function f(){return a}", 50) + "\n"
+	}
+	sized := make(map[string]string)
+	for i := 0; i < 5; i++ {
+		sized[fmt.Sprintf("short%02d.go", i)] = "package app\n"
+		sized[fmt.Sprintf("medium%02d.go", i)] = "package app\n\nvar x = 1\n"
+		sized[fmt.Sprintf("long%02d.go", i)] = "package app\n\n// f returns one\nfunc f() int { return 1 }\n"
+	}
+
+	cases := []struct {
+		name string
+		tree map[string]string
+		opts Options
+	}{
+		{"generated and binary", leftOut, Options{}},
+		{"file min lines", sized, Options{FileMinLines: 4}},
+		{"file max lines", sized, Options{FileMaxLines: 3}},
+		{"file min and max lines", sized, Options{FileMinLines: 2, FileMaxLines: 3}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTree(t, dir, tc.tree)
+			c, err := New(tc.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			exact, err := c.Count(context.Background(), dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files, err := c.Discover(context.Background(), dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sampled := estimateFromSample(context.Background(), files, len(files), c.countOptions())
+
+			if sampled.TotalFiles != exact.TotalFiles || sampled.TotalStats != exact.TotalStats {
+				t.Errorf("sampled %d files, %+v, want %d files, %+v", sampled.TotalFiles, sampled.TotalStats, exact.TotalFiles, exact.TotalStats)
+			}
+			if !reflect.DeepEqual(sampled.FilesByExt, exact.FilesByExt) {
+				t.Errorf("sampled FilesByExt = %v, want %v", sampled.FilesByExt, exact.FilesByExt)
+			}
+			if !reflect.DeepEqual(sampled.StatsByExt, exact.StatsByExt) {
+				t.Errorf("sampled StatsByExt = %v, want %v", sampled.StatsByExt, exact.StatsByExt)
+			}
+			if sampled.FilteredFiles != exact.FilteredFiles {
+				t.Errorf("sampled FilteredFiles = %d, want %d", sampled.FilteredFiles, exact.FilteredFiles)
+			}
+			if !reflect.DeepEqual(sampled.Generated, exact.Generated) {
+				t.Errorf("sampled Generated = %+v, want %+v", sampled.Generated, exact.Generated)
+			}
+		})
+	}
+}
+
+// TestRandomSampleFileLines checks that a sample extrapolates the files
+// left out by the size filters along with the ones it counts
+func TestRandomSampleFileLines(t *testing.T) {
+	dir := t.TempDir()
+	tree := make(map[string]string)
+	for i := 0; i < 10; i++ {
+		tree[fmt.Sprintf("short%02d.go", i)] = "package app\n"
+		tree[fmt.Sprintf("long%02d.go", i)] = "package app\n\n// f returns one\nfunc f() int { return 1 }\n"
+	}
+	writeTree(t, dir, tree)
+
+	c, err := New(Options{RandomSample: 5, FileMinLines: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := c.Count(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stats.TotalFiles + stats.FilteredFiles; got != 20 {
+		t.Errorf("%d files counted and %d filtered, want 20 in all", stats.TotalFiles, stats.FilteredFiles)
+	}
+	if got, want := stats.TotalStats.TotalLines, 4*stats.TotalFiles; got != want {
+		t.Errorf("TotalLines = %d, want %d for %d files", got, want, stats.TotalFiles)
+	}
+}
+
+// TestRandomSampleTotals checks that the totals of a sample are the sums
+// of its extensions, and that extensions left out of the sample are still
+// listed with their files
+func TestRandomSampleTotals(t *testing.T) {
+	dir := t.TempDir()
+	tree := make(map[string]string)
+	for i := 0; i < 200; i++ {
+		tree[fmt.Sprintf("f%03d.go", i)] = "package app\n\nvar x = 1\n"
+	}
+	for i := 0; i < 3; i++ {
+		tree[fmt.Sprintf("s%d.py", i)] = "# s\nx = 1\n"
+	}
+	writeTree(t, dir, tree)
+
+	c, err := New(Options{RandomSample: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for run := 0; run < 5; run++ {
+		stats, err := c.Count(context.Background(), dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := map[string]int{".go": 200, ".py": 3}; !reflect.DeepEqual(stats.FilesByExt, want) {
+			t.Errorf("FilesByExt = %v, want %v", stats.FilesByExt, want)
+		}
+		files, lines, code := 0, 0, 0
+		for ext, fileCount := range stats.FilesByExt {
+			files += fileCount
+			lines += stats.StatsByExt[ext].TotalLines
+			code += stats.StatsByExt[ext].CodeLines
+		}
+		if stats.TotalFiles != files || stats.TotalStats.TotalLines != lines || stats.TotalStats.CodeLines != code {
+			t.Errorf("totals %d files, %d lines, %d code lines, want the sums %d, %d, %d",
+				stats.TotalFiles, stats.TotalStats.TotalLines, stats.TotalStats.CodeLines, files, lines, code)
+		}
+		if got := stats.StatsByExt[".go"].TotalLines; got != 600 {
+			t.Errorf("Go lines = %d, want 600", got)
+		}
+	}
+}
+
+// TestRandomSampleErrors checks that a sampled count reports the nested
+// repositories it skipped and the files it could not read, as a full count
+// does
+func TestRandomSampleErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"a.go":          "package a\n",
+		"b.go":          "package b\n",
+		"sub/.git/HEAD": "ref: refs/heads/main\n",
+		"sub/c.go":      "package c\n",
+	})
+	broken := filepath.Join(dir, "broken.go")
+	if err := os.Symlink(filepath.Join(dir, "missing.go"), broken); err != nil {
+		t.Skipf("cannot create symlinks: %v", err)
+	}
+
+	for _, sample := range []int{2, 3, 10} {
+		c, err := New(Options{RandomSample: sample, SkipNestedGit: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		stats, err := c.Count(context.Background(), dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{filepath.Join(dir, "sub")}; !reflect.DeepEqual(stats.NestedRepos, want) {
+			t.Errorf("sample %d: NestedRepos = %v, want %v", sample, stats.NestedRepos, want)
+		}
+		// Only the files sampled are read
+		if len(stats.Errors) > 1 || sample >= 3 && len(stats.Errors) == 0 {
+			t.Errorf("sample %d: Errors = %v, want %s", sample, stats.Errors, broken)
+		}
+		for _, e := range stats.Errors {
+			if e.Path != broken {
+				t.Errorf("sample %d: error for %s, want %s", sample, e.Path, broken)
+			}
+		}
+	}
+}
@@ -0,0 +1,495 @@
+package counter
+
+import (
+	"crypto/sha256"
+	"sort"
+)
+
+// FileStats holds statistics for a single file
+type FileStats struct {
+	TotalLines   int
+	CodeLines    int
+	BlankLines   int
+	CommentLines int
+
+	// Line length metrics, measured on non-blank lines before trimming
+	MinLineLength   int
+	MaxLineLength   int
+	TotalLineLength int
+
+	// GenericLines counts the Go code lines declaring type parameters
+	GenericLines int
+
+	// MixedLines counts lines holding both code and a comment. Depending on
+	// --mixed-as they are also included in CodeLines or CommentLines.
+	MixedLines int
+
+	// AnnotationLines counts Modelica annotation(...) lines, which are
+	// excluded from CodeLines with --modelica-detail
+	AnnotationLines int
+
+	// Complexity estimates the cyclomatic complexity as the number of
+	// branch keywords and operators on code lines (--complexity), and
+	// MaxComplexity is the highest Complexity of a single file
+	Complexity    int
+	MaxComplexity int
+
+	// LicenseHeaderLines counts the comment lines of license and copyright
+	// headers at the top of files, which are also counted in CommentLines,
+	// and LicenseHeaderFiles the files starting with one
+	// (Options.LicenseHeaders)
+	LicenseHeaderLines int
+	LicenseHeaderFiles int
+
+	// MetaLines counts shebang lines and the front matter of Markdown
+	// files unless Options.MetaAs is MetaAsSyntax; they are also counted in
+	// CodeLines or CommentLines if MetaAs says so
+	MetaLines int
+
+	// TruncatedLines counts lines longer than Options.MaxLineBytes, which
+	// are classified by their first MaxLineBytes bytes
+	TruncatedLines int
+
+	// LongLines counts the lines longer than Options.LongLine characters,
+	// TrailingWhitespaceLines those ending in spaces or tabs and
+	// MixedIndentLines those indented with both (Options.StyleMetrics)
+	LongLines               int
+	TrailingWhitespaceLines int
+	MixedIndentLines        int
+}
+
+// Add accumulates other into s
+func (s *FileStats) Add(other FileStats) {
+	s.TotalLines += other.TotalLines
+	s.CodeLines += other.CodeLines
+	s.BlankLines += other.BlankLines
+	s.CommentLines += other.CommentLines
+
+	// A zero maximum means nothing was measured, so it must not lower the minimum
+	if other.MaxLineLength > 0 && (s.MaxLineLength == 0 || other.MinLineLength < s.MinLineLength) {
+		s.MinLineLength = other.MinLineLength
+	}
+	if other.MaxLineLength > s.MaxLineLength {
+		s.MaxLineLength = other.MaxLineLength
+	}
+	s.TotalLineLength += other.TotalLineLength
+	s.GenericLines += other.GenericLines
+	s.MixedLines += other.MixedLines
+	s.AnnotationLines += other.AnnotationLines
+	s.LicenseHeaderLines += other.LicenseHeaderLines
+	s.LicenseHeaderFiles += other.LicenseHeaderFiles
+	s.MetaLines += other.MetaLines
+	s.Complexity += other.Complexity
+	s.TruncatedLines += other.TruncatedLines
+	s.LongLines += other.LongLines
+	s.TrailingWhitespaceLines += other.TrailingWhitespaceLines
+	s.MixedIndentLines += other.MixedIndentLines
+	if other.MaxComplexity > s.MaxComplexity {
+		s.MaxComplexity = other.MaxComplexity
+	}
+}
+
+// addLine records a non-blank line of the given kind. mixedAs selects
+// whether mixed lines also count as "code", "comment" or only as "mixed".
+func (s *FileStats) addLine(kind LineKind, mixedAs string) {
+	switch kind {
+	case CommentLine:
+		s.CommentLines++
+	case MixedLine:
+		s.MixedLines++
+		switch mixedAs {
+		case "comment":
+			s.CommentLines++
+		case "mixed":
+		default:
+			s.CodeLines++
+		}
+	default:
+		s.CodeLines++
+	}
+}
+
+// AverageLineLength returns the mean length of the non-blank lines
+func (s FileStats) AverageLineLength() float64 {
+	measured := s.TotalLines - s.BlankLines
+	if measured == 0 {
+		return 0
+	}
+	return float64(s.TotalLineLength) / float64(measured)
+}
+
+// FileResult holds the statistics of one counted file
+type FileResult struct {
+	Path string
+	Ext  string
+	// Language is the key of the language the file was counted as: Ext,
+	// or the key its content told, such as .hpp for a C++ header named
+	// .h. LanguageName and GroupName take it.
+	Language string
+	Stats    FileStats
+
+	// LineEnding is "lf", "crlf", "cr", "mixed" or "none"
+	LineEnding string
+	// Category is the Category Options.Classifiers put the file in, or ""
+	Category Category
+}
+
+// ProjectStats holds statistics for the entire project
+type ProjectStats struct {
+	FilesByExt map[string]int
+	StatsByExt map[string]FileStats
+	TotalStats FileStats
+	TotalFiles int
+
+	// PerExtFileLengths records the total line count of every file, by extension
+	PerExtFileLengths map[string][]int
+
+	// FilesByLanguage, StatsByLanguage and PerLanguageFileLengths are the
+	// per-extension maps keyed by FileResult.Language instead, so a C++
+	// header named .h is listed under .hpp. The language breakdown uses
+	// them; they are empty in reports saved before they were added.
+	FilesByLanguage        map[string]int
+	StatsByLanguage        map[string]FileStats
+	PerLanguageFileLengths map[string][]int
+
+	Terraform TerraformStats
+
+	// LineEndings counts files by dominant line ending
+	LineEndings LineEndingStats
+
+	// Files lists the individual files, when a per-file report needs them
+	Files []FileResult
+
+	// AuthorStats attributes lines to their git blame author (--by-author)
+	AuthorStats map[string]FileStats
+
+	// Tests holds the test files when they are counted separately with
+	// Options.Tests set to TestsSeparate, or is nil
+	Tests *ProjectStats
+
+	// Generated counts the binary, minified and generated files left out
+	// of the totals
+	Generated GeneratedStats
+
+	// SampledFiles is non-zero when the counts are extrapolated from a
+	// random sample of that many files. Extensions none of whose files were
+	// sampled are listed with all their files and no lines.
+	SampledFiles int
+
+	// FilteredFiles counts the files left out by Options.FileMinLines and
+	// Options.FileMaxLines
+	FilteredFiles int
+
+	// NestedRepos lists the repositories skipped with Options.SkipNestedGit
+	// in walk order
+	NestedRepos []string
+
+	// Errors lists the files and directories skipped because they could
+	// not be read, by path
+	Errors []PathError
+
+	// Duplicates counts the files skipped as copies of others with
+	// Options.Unique
+	Duplicates DuplicateStats
+	// hashes maps the content hashes of the files recorded with
+	// Options.Unique to their path
+	hashes map[[sha256.Size]byte]string
+
+	// Categories totals the files of each Category of Options.Classifiers
+	Categories map[Category]CategoryStats
+
+	// Changes holds the lines added and removed per key in the git range
+	// of Options.DiffRange, or is nil
+	Changes map[string]LineChanges
+
+	// Partial is set when the count was cancelled before it completed, so
+	// only some of the files are included
+	Partial bool
+}
+
+// AverageCodeLines returns the mean number of code lines per file
+func (p *ProjectStats) AverageCodeLines() float64 {
+	return average(p.TotalStats.CodeLines, p.TotalFiles)
+}
+
+// AverageTotalLines returns the mean number of lines per file
+func (p *ProjectStats) AverageTotalLines() float64 {
+	return average(p.TotalStats.TotalLines, p.TotalFiles)
+}
+
+// AverageComplexity returns the mean complexity per file
+func (p *ProjectStats) AverageComplexity() float64 {
+	return average(p.TotalStats.Complexity, p.TotalFiles)
+}
+
+// MedianTotalLines returns the median number of lines per file
+func (p *ProjectStats) MedianTotalLines() float64 {
+	var lengths []int
+	for _, extLengths := range p.PerExtFileLengths {
+		lengths = append(lengths, extLengths...)
+	}
+	return median(lengths)
+}
+
+// ExtAverageCodeLines returns the mean number of code lines per file for ext
+func (p *ProjectStats) ExtAverageCodeLines(ext string) float64 {
+	return average(p.StatsByExt[ext].CodeLines, p.FilesByExt[ext])
+}
+
+// ExtAverageTotalLines returns the mean number of lines per file for ext
+func (p *ProjectStats) ExtAverageTotalLines(ext string) float64 {
+	return average(p.StatsByExt[ext].TotalLines, p.FilesByExt[ext])
+}
+
+// ExtAverageComplexity returns the mean complexity per file for ext
+func (p *ProjectStats) ExtAverageComplexity(ext string) float64 {
+	return average(p.StatsByExt[ext].Complexity, p.FilesByExt[ext])
+}
+
+// ExtMedianTotalLines returns the median number of lines per file for ext
+func (p *ProjectStats) ExtMedianTotalLines(ext string) float64 {
+	return median(p.PerExtFileLengths[ext])
+}
+
+// MaxTotalLines returns the number of lines of the longest file
+func (p *ProjectStats) MaxTotalLines() int {
+	longest := 0
+	for _, extLengths := range p.PerExtFileLengths {
+		longest = max(longest, maxOf(extLengths))
+	}
+	return longest
+}
+
+// ExtMaxTotalLines returns the number of lines of the longest file for ext
+func (p *ProjectStats) ExtMaxTotalLines(ext string) int {
+	return maxOf(p.PerExtFileLengths[ext])
+}
+
+// LargeFiles returns the number of files with more than threshold lines
+func (p *ProjectStats) LargeFiles(threshold int) int {
+	count := 0
+	for _, extLengths := range p.PerExtFileLengths {
+		count += countAbove(extLengths, threshold)
+	}
+	return count
+}
+
+// ExtLargeFiles returns the number of files for ext with more than
+// threshold lines
+func (p *ProjectStats) ExtLargeFiles(ext string, threshold int) int {
+	return countAbove(p.PerExtFileLengths[ext], threshold)
+}
+
+func maxOf(values []int) int {
+	largest := 0
+	for _, v := range values {
+		largest = max(largest, v)
+	}
+	return largest
+}
+
+func countAbove(values []int, threshold int) int {
+	count := 0
+	for _, v := range values {
+		if v > threshold {
+			count++
+		}
+	}
+	return count
+}
+
+func average(sum, count int) float64 {
+	if count == 0 {
+		return 0
+	}
+	return float64(sum) / float64(count)
+}
+
+func median(values []int) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return float64(sorted[mid-1]+sorted[mid]) / 2
+	}
+	return float64(sorted[mid])
+}
+
+// NewProjectStats returns empty ProjectStats ready to be added to
+func NewProjectStats() *ProjectStats {
+	return &ProjectStats{
+		FilesByExt:             make(map[string]int),
+		StatsByExt:             make(map[string]FileStats),
+		PerExtFileLengths:      make(map[string][]int),
+		FilesByLanguage:        make(map[string]int),
+		StatsByLanguage:        make(map[string]FileStats),
+		PerLanguageFileLengths: make(map[string][]int),
+		AuthorStats:            make(map[string]FileStats),
+	}
+}
+
+// Merge adds the counts of other to p, as if the files of both had been
+// counted together, so subtrees can be counted separately, in parallel or
+// on different machines, and combined. A ProjectStats must not be used by
+// several goroutines at once, so each shard is built on its own and merged
+// once complete. The totals do not depend on the order shards are merged
+// in; Files and the file lengths are appended in that order. Copies of a
+// file in different shards are not detected with Options.Unique.
+func (p *ProjectStats) Merge(other *ProjectStats) {
+	if p.FilesByExt == nil {
+		p.FilesByExt = make(map[string]int)
+	}
+	if p.StatsByExt == nil {
+		p.StatsByExt = make(map[string]FileStats)
+	}
+	if p.PerExtFileLengths == nil {
+		p.PerExtFileLengths = make(map[string][]int)
+	}
+	if p.FilesByLanguage == nil {
+		p.FilesByLanguage = make(map[string]int)
+		p.StatsByLanguage = make(map[string]FileStats)
+		p.PerLanguageFileLengths = make(map[string][]int)
+	}
+	if p.AuthorStats == nil {
+		p.AuthorStats = make(map[string]FileStats)
+	}
+
+	mergeKeyed(p.FilesByExt, p.StatsByExt, p.PerExtFileLengths,
+		other.FilesByExt, other.StatsByExt, other.PerExtFileLengths)
+	mergeKeyed(p.FilesByLanguage, p.StatsByLanguage, p.PerLanguageFileLengths,
+		other.FilesByLanguage, other.StatsByLanguage, other.PerLanguageFileLengths)
+	for author, stats := range other.AuthorStats {
+		authorStats := p.AuthorStats[author]
+		authorStats.Add(stats)
+		p.AuthorStats[author] = authorStats
+	}
+	for ext, changes := range other.Changes {
+		if p.Changes == nil {
+			p.Changes = make(map[string]LineChanges)
+		}
+		extChanges := p.Changes[ext]
+		extChanges.Add(changes)
+		p.Changes[ext] = extChanges
+	}
+	for category, stats := range other.Categories {
+		if p.Categories == nil {
+			p.Categories = make(map[Category]CategoryStats)
+		}
+		c := p.Categories[category]
+		c.Files += stats.Files
+		c.Stats.Add(stats.Stats)
+		p.Categories[category] = c
+	}
+	p.Files = append(p.Files, other.Files...)
+	p.Errors = append(p.Errors, other.Errors...)
+	p.NestedRepos = append(p.NestedRepos, other.NestedRepos...)
+	p.LineEndings.LF += other.LineEndings.LF
+	p.LineEndings.CRLF += other.LineEndings.CRLF
+	p.LineEndings.CR += other.LineEndings.CR
+	p.LineEndings.Mixed += other.LineEndings.Mixed
+	p.TotalStats.Add(other.TotalStats)
+	p.TotalFiles += other.TotalFiles
+	p.SampledFiles += other.SampledFiles
+	p.FilteredFiles += other.FilteredFiles
+	p.Partial = p.Partial || other.Partial
+	p.Generated.Add(other.Generated)
+	p.Duplicates.Add(other.Duplicates)
+	if other.Tests != nil {
+		if p.Tests == nil {
+			p.Tests = NewProjectStats()
+		}
+		p.Tests.Merge(other.Tests)
+	}
+
+	p.Terraform.Detected = p.Terraform.Detected || other.Terraform.Detected
+	p.Terraform.ResourceBlocks += other.Terraform.ResourceBlocks
+	p.Terraform.DataBlocks += other.Terraform.DataBlocks
+}
+
+// AddFile records a file counted on its own, e.g. by Counter.CountFile.
+// Its embedded languages are not split out.
+func (p *ProjectStats) AddFile(result FileResult) {
+	if p.FilesByExt == nil {
+		p.FilesByExt = make(map[string]int)
+		p.StatsByExt = make(map[string]FileStats)
+		p.PerExtFileLengths = make(map[string][]int)
+	}
+	if p.FilesByLanguage == nil {
+		p.FilesByLanguage = make(map[string]int)
+		p.StatsByLanguage = make(map[string]FileStats)
+		p.PerLanguageFileLengths = make(map[string][]int)
+	}
+	language := result.Language
+	if language == "" {
+		language = result.Ext
+	}
+	p.addFile(result.Ext, language, result.Stats)
+	if result.Category != "" {
+		p.addCategory(result.Category, result.Stats)
+	}
+	p.Files = append(p.Files, result)
+}
+
+// addParts records a file of ext, counted as language, with fileStats in
+// total, whose lines parts attributes to several keys. The file only counts
+// under ext, but every key of parts is listed in FilesByExt.
+func (p *ProjectStats) addParts(ext, language string, fileStats FileStats, parts map[string]FileStats) {
+	addKeyedParts(p.FilesByExt, p.StatsByExt, p.PerExtFileLengths, ext, fileStats, parts)
+	addKeyedParts(p.FilesByLanguage, p.StatsByLanguage, p.PerLanguageFileLengths, language, fileStats, parts)
+	p.TotalFiles++
+	p.TotalStats.Add(fileStats)
+}
+
+// addFile records the statistics of a single file with extension ext,
+// counted as language
+func (p *ProjectStats) addFile(ext, language string, fileStats FileStats) {
+	addKeyed(p.FilesByExt, p.StatsByExt, p.PerExtFileLengths, ext, fileStats)
+	addKeyed(p.FilesByLanguage, p.StatsByLanguage, p.PerLanguageFileLengths, language, fileStats)
+	p.TotalFiles++
+	p.TotalStats.Add(fileStats)
+}
+
+// addKeyed records a file under key in the per-key maps files, stats and
+// lengths
+func addKeyed(files map[string]int, stats map[string]FileStats, lengths map[string][]int, key string, fileStats FileStats) {
+	files[key]++
+	keyStats := stats[key]
+	keyStats.Add(fileStats)
+	stats[key] = keyStats
+	lengths[key] = append(lengths[key], fileStats.TotalLines)
+}
+
+// addKeyedParts records a file under key in the per-key maps files, stats
+// and lengths, with its lines under the keys of parts
+func addKeyedParts(files map[string]int, stats map[string]FileStats, lengths map[string][]int, key string, fileStats FileStats, parts map[string]FileStats) {
+	files[key]++
+	for part, partStats := range parts {
+		if _, ok := files[part]; !ok {
+			files[part] = 0
+		}
+		keyStats := stats[part]
+		keyStats.Add(partStats)
+		stats[part] = keyStats
+	}
+	lengths[key] = append(lengths[key], fileStats.TotalLines)
+}
+
+// mergeKeyed adds the per-key maps of another ProjectStats to files, stats
+// and lengths
+func mergeKeyed(files map[string]int, stats map[string]FileStats, lengths map[string][]int,
+	otherFiles map[string]int, otherStats map[string]FileStats, otherLengths map[string][]int) {
+	for key, count := range otherFiles {
+		files[key] += count
+	}
+	for key, s := range otherStats {
+		keyStats := stats[key]
+		keyStats.Add(s)
+		stats[key] = keyStats
+	}
+	for key, l := range otherLengths {
+		lengths[key] = append(lengths[key], l...)
+	}
+}
@@ -0,0 +1,32 @@
+package counter
+
+import "unicode/utf8"
+
+// DefaultLongLine is the length, in characters, above which
+// Options.StyleMetrics counts a line as long
+const DefaultLongLine = 120
+
+// addStyle records the style metrics of raw, a line of length bytes whose
+// first bytes raw holds: whether it is longer than longLine characters,
+// ends in whitespace or indents with both tabs and spaces
+func (s *FileStats) addStyle(raw []byte, length, longLine int) {
+	if utf8.RuneCount(raw)+length-len(raw) > longLine {
+		s.LongLines++
+	}
+	if n := len(raw); n > 0 && n == length && (raw[n-1] == ' ' || raw[n-1] == '\t') {
+		s.TrailingWhitespaceLines++
+	}
+	tabs, spaces := false, false
+	for _, c := range raw {
+		if c == '\t' {
+			tabs = true
+		} else if c == ' ' {
+			spaces = true
+		} else {
+			break
+		}
+	}
+	if tabs && spaces {
+		s.MixedIndentLines++
+	}
+}
@@ -0,0 +1,63 @@
+package counter
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TerraformStats describes the Terraform configuration found in a project
+type TerraformStats struct {
+	// Detected is set when the root holds terraform.tfstate or .terraform
+	Detected bool
+
+	// Block counts, only collected with --hcl-detail
+	ResourceBlocks int
+	DataBlocks     int
+}
+
+// detectTerraform checks rootPath for Terraform state and, with HCLDetail
+// set, counts the resource and data blocks declared in the .tf files
+func detectTerraform(rootPath string, files []string, opts Options) TerraformStats {
+	var stats TerraformStats
+	for _, name := range []string{"terraform.tfstate", ".terraform"} {
+		if _, err := os.Stat(filepath.Join(rootPath, name)); err == nil {
+			stats.Detected = true
+		}
+	}
+
+	if !opts.HCLDetail {
+		return stats
+	}
+	for _, path := range files {
+		if strings.ToLower(filepath.Ext(path)) != ".tf" {
+			continue
+		}
+		if err := countTerraformBlocks(path, &stats); err != nil {
+			opts.logger().Warn("could not read file", "path", path, "err", err)
+		}
+	}
+	return stats
+}
+
+// countTerraformBlocks adds the blocks declared in the file at path to stats
+func countTerraformBlocks(path string, stats *TerraformStats) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "resource "):
+			stats.ResourceBlocks++
+		case strings.HasPrefix(line, "data "):
+			stats.DataBlocks++
+		}
+	}
+	return scanner.Err()
+}
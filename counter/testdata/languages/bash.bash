@@ -0,0 +1,3 @@
+# demo.bash
+set -euo pipefail
+echo hi
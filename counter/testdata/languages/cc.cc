@@ -0,0 +1,8 @@
+// Package demo shows the counts.
+#include <vector>
+
+/* A block comment
+   over two lines */
+int main(void) {
+    return 0; // done
+}
@@ -0,0 +1,5 @@
+# demo.cmake
+project(demo)
+#[[ block
+comment ]]
+add_executable(demo main.c)
@@ -0,0 +1,7 @@
+// Demo.cs
+using System;
+
+class Demo {
+    /// <summary>Entry.</summary>
+    static void Main() => Console.WriteLine("hi");
+}
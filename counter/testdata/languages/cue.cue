@@ -0,0 +1,4 @@
+// demo.cue
+package demo
+
+name: "demo"
@@ -0,0 +1,6 @@
+# greeting.feature
+Feature: Greeting
+
+  Scenario: Say hi
+    Given a user
+    Then they see "hi"
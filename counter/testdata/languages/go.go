@@ -0,0 +1,12 @@
+// Package demo counts.
+package demo
+
+import "fmt"
+
+/*
+Hello prints a greeting.
+*/
+func Hello() {
+	fmt.Println("// not a comment")
+	_ = 1 // trailing
+}
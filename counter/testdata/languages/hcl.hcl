@@ -0,0 +1,3 @@
+// demo.hcl
+name = "demo"
+
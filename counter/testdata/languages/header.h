@@ -0,0 +1,7 @@
+/* demo.h */
+#ifndef DEMO_H
+#define DEMO_H
+
+// add returns a + b
+int add(int a, int b);
+#endif
@@ -0,0 +1,6 @@
+// demo.hpp
+#pragma once
+
+namespace demo {
+int add(int a, int b); /* sum */
+}
@@ -0,0 +1,9 @@
+/*
+ * Demo.java
+ */
+public class Demo {
+    // entry point
+    public static void main(String[] args) {
+        System.out.println("hi");
+    }
+}
@@ -0,0 +1,9 @@
+// greet.js
+const msg = "/* not a comment */";
+
+/**
+ * greet logs msg.
+ */
+function greet() {
+  console.log(msg);
+}
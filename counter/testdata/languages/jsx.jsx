@@ -0,0 +1,5 @@
+// App.jsx
+export function App() {
+  return <div>hi</div>;
+}
+
@@ -0,0 +1,5 @@
+// Main.kt
+fun main() {
+    /* say hi */
+    println("hi")
+}
@@ -0,0 +1,2 @@
+/* lib */
+{ add(a, b):: a + b }
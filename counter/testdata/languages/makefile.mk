@@ -0,0 +1,4 @@
+# rules.mk
+all:
+	echo hi
+
@@ -0,0 +1,6 @@
+% demo.m
+x = [1 2 3];
+%{
+block
+%}
+disp(x)
@@ -0,0 +1,4 @@
+# default.nix
+{ pkgs }:
+pkgs.hello
+/* end */
@@ -0,0 +1,6 @@
+// Demo.m
+#import "Demo.h"
+
+@implementation Demo
+/* nothing yet */
+@end
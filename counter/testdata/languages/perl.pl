@@ -0,0 +1,4 @@
+#!/usr/bin/perl
+# demo.pl
+print "hi\n";
+
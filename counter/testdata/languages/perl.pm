@@ -0,0 +1,3 @@
+package Demo;
+# module
+1;
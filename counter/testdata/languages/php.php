@@ -0,0 +1,7 @@
+<?php
+// demo.php
+# hash comment
+$x = 1;
+
+/* block */
+echo $x;
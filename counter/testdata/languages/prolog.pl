@@ -0,0 +1,4 @@
+% facts
+parent(tom, bob).
+/* rules */
+grandparent(X, Z) :- parent(X, Y), parent(Y, Z).
@@ -0,0 +1,10 @@
+# demo.py
+import os
+
+
+def greet():
+    """Greet prints a
+    greeting."""
+    print("# not a comment")
+
+greet()  # call
@@ -0,0 +1,4 @@
+# demo.R
+x <- c(1, 2, 3)
+
+print(mean(x))
@@ -0,0 +1,4 @@
+# policy
+package demo
+
+default allow := false
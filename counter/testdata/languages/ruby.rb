@@ -0,0 +1,6 @@
+# demo.rb
+def greet
+  puts "# not a comment"
+end
+
+greet # call
@@ -0,0 +1,6 @@
+//! Crate docs.
+fn main() {
+    /* outer /* nested */ still comment */
+    println!("hi");
+}
+
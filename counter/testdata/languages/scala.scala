@@ -0,0 +1,5 @@
+// Main.scala
+object Main extends App {
+  println("hi")
+}
+
@@ -0,0 +1,5 @@
+#!/bin/sh
+# demo.sh
+echo "# not a comment"
+
+exit 0
@@ -0,0 +1,8 @@
+-- schema.sql
+CREATE TABLE users (
+  id INTEGER PRIMARY KEY, -- key
+  name TEXT
+);
+
+/* seed */
+INSERT INTO users VALUES (1, 'a -- b');
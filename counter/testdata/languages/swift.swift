@@ -0,0 +1,6 @@
+// main.swift
+let text = """
+    // inside a string
+    """
+
+print(text)
@@ -0,0 +1,6 @@
+# main.tf
+resource "null_resource" "demo" {
+  // nothing
+}
+
+/* end */
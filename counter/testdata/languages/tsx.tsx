@@ -0,0 +1,6 @@
+// App.tsx
+export const App = (): JSX.Element => (
+  <div>hi</div>
+);
+
+/* done */
@@ -0,0 +1,6 @@
+// greet.ts
+export function greet(name: string): string {
+  return `hi ${name}`; // template
+}
+
+/* end */
@@ -0,0 +1,3 @@
+x = 1
+
+# a longer comment
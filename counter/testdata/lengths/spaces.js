@@ -0,0 +1,5 @@
+a
+
+
+  bb  
+// héllo
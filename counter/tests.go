@@ -0,0 +1,65 @@
+package counter
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Values of Options.Tests
+const (
+	TestsInclude  = "include"
+	TestsSeparate = "separate"
+	TestsExclude  = "exclude"
+)
+
+var (
+	// testPrefixes and testSuffixes are matched against file names without
+	// their extension, e.g. test_app.py, app_test.go or AppTest.java
+	testPrefixes = []string{"test_"}
+	testSuffixes = []string{"_test", "_tests", "_spec", "_unittest", "Test", "Tests", "Spec"}
+	// testInfixes are matched against whole file names, e.g. app.spec.ts
+	testInfixes = []string{".test.", ".spec."}
+)
+
+// IsTestFile reports whether relPath, a slash-separated path relative to
+// the project root, is a test file: it lies below one of TestDirs or its
+// name follows a common test naming convention such as *_test.go,
+// *.spec.ts, test_*.py or *Test.java
+func IsTestFile(relPath string) bool {
+	dir, name := path.Split(relPath)
+	for _, part := range strings.Split(strings.Trim(dir, "/"), "/") {
+		if TestDirs[part] {
+			return true
+		}
+	}
+
+	for _, infix := range testInfixes {
+		if strings.Contains(name, infix) {
+			return true
+		}
+	}
+	base := strings.TrimSuffix(name, path.Ext(name))
+	for _, prefix := range testPrefixes {
+		if strings.HasPrefix(base, prefix) {
+			return true
+		}
+	}
+	for _, suffix := range testSuffixes {
+		if strings.HasSuffix(base, suffix) && base != suffix {
+			return true
+		}
+	}
+	return false
+}
+
+// relativePath returns path relative to root with slashes, or path itself
+// if it is not below root
+func relativePath(root, path string) string {
+	if root != "" {
+		if rel, err := filepath.Rel(root, path); err == nil {
+			path = rel
+		}
+	}
+	return filepath.ToSlash(path)
+}
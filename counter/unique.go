@@ -0,0 +1,59 @@
+package counter
+
+import "os"
+
+// DuplicateStats counts the files skipped by Options.Unique because their
+// content is identical to a file counted earlier
+type DuplicateStats struct {
+	Files int
+	// Groups maps the path of each counted file that has duplicates to the
+	// paths of its skipped copies, in walk order
+	Groups map[string][]string
+}
+
+// Add accumulates other into d
+func (d *DuplicateStats) Add(other DuplicateStats) {
+	d.Files += other.Files
+	for first, copies := range other.Groups {
+		d.add(first, copies...)
+	}
+}
+
+func (d *DuplicateStats) add(first string, copies ...string) {
+	if d.Groups == nil {
+		d.Groups = make(map[string][]string)
+	}
+	d.Groups[first] = append(d.Groups[first], copies...)
+}
+
+// fileID identifies a file on disk by device and inode
+type fileID struct {
+	dev, ino uint64
+}
+
+// linkTracker recognises hard links to, and symlinks resolving to, a file
+// that was already emitted, so their content is not read again
+type linkTracker struct {
+	seen map[fileID]string
+}
+
+// firstPath returns the path under which the file at path was first seen,
+// or records path and returns "" if it is new or cannot be identified
+func (t *linkTracker) firstPath(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	id, ok := fileIDOf(info)
+	if !ok {
+		return ""
+	}
+	if first, ok := t.seen[id]; ok {
+		return first
+	}
+	if t.seen == nil {
+		t.seen = make(map[fileID]string)
+	}
+	t.seen[id] = path
+	return ""
+}
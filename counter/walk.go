@@ -0,0 +1,500 @@
+package counter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// pathFilter holds the filters shared by the filesystem walk and --git
+// listing
+type pathFilter struct {
+	opts             Options
+	ignore           *IgnoreMatcher
+	exclude, include *GlobSet
+	tracked          map[string]bool
+	changed          map[string]bool
+	profile          map[string]bool
+}
+
+func newPathFilter(rootPath string, opts Options) (*pathFilter, error) {
+	f := &pathFilter{opts: opts}
+	if opts.IgnorePathsFrom != "" {
+		var err error
+		f.ignore, err = LoadIgnoreFile(opts.IgnorePathsFrom)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	if f.exclude, err = CompileGlobs(opts.Exclude); err != nil {
+		return nil, err
+	}
+	if f.include, err = CompileGlobs(opts.Include); err != nil {
+		return nil, err
+	}
+
+	if opts.GitTracked {
+		f.tracked, err = gitTrackedFiles(rootPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.DiffRange != "" {
+		f.changed, err = gitChangedFiles(rootPath, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Profile != "" {
+		extensions, ok := Profiles[opts.Profile]
+		if !ok {
+			return nil, fmt.Errorf("unknown profile %q", opts.Profile)
+		}
+		f.profile = make(map[string]bool)
+		for _, ext := range extensions {
+			f.profile[ext] = true
+		}
+	}
+	return f, nil
+}
+
+// skipDir reports whether the directory relPath, named name, is skipped
+// along with everything below it, logging the reason if so
+func (f *pathFilter) skipDir(relPath, name string, isRoot bool) bool {
+	reason := f.dirSkipReason(relPath, name, isRoot)
+	if reason != "" {
+		f.opts.logger().Info("skipping directory", "path", relPath, "reason", reason)
+	}
+	return reason != ""
+}
+
+// dirSkipReason returns why the directory relPath, named name, is skipped,
+// or "" if it is walked
+func (f *pathFilter) dirSkipReason(relPath, name string, isRoot bool) string {
+	if shouldIgnoreDir(name) {
+		return "ignored directory name"
+	}
+	if f.ignore.Match(relPath, true) {
+		return "--ignore-paths-from"
+	}
+	if isRoot {
+		return ""
+	}
+	// Files in relPath are one level deeper than relPath itself
+	if f.opts.MaxDepth > 0 && strings.Count(relPath, "/")+1 >= f.opts.MaxDepth {
+		return "--max-depth"
+	}
+	if (f.opts.SkipTestDirs || f.opts.Tests == TestsExclude) && TestDirs[name] {
+		return "test directory"
+	}
+	if f.exclude.Match(relPath) {
+		return "--exclude"
+	}
+	return ""
+}
+
+// countFile reports whether the file at path, relPath below the root, is
+// a code file that passes the filters, logging why it is skipped if not
+func (f *pathFilter) countFile(path, relPath string) bool {
+	log := f.opts.logger()
+	if reason := f.pathSkipReason(relPath); reason != "" {
+		log.Info("skipping file", "path", path, "reason", reason)
+		return false
+	}
+
+	// Check if it's a code file
+	key, ok, script := typeByName(filepath.Base(path))
+	by := "name"
+	if script {
+		key, ok = shebangType(path)
+		by = "shebang"
+	}
+	if !ok && f.opts.AllText {
+		key, ok, by = KeyOther, true, "--all-text"
+	}
+	if !ok {
+		log.Info("skipping file", "path", path, "reason", "not a code file")
+		return false
+	}
+	if reason := f.typeSkipReason(key); reason != "" {
+		log.Info("skipping file", "path", path, "reason", reason)
+		return false
+	}
+	log.Debug("detected file type", "path", path, "key", key, "language", LanguageName(key), "by", by)
+	return true
+}
+
+// matchPath reports whether the file relPath passes the path filters
+func (f *pathFilter) matchPath(relPath string) bool {
+	return f.pathSkipReason(relPath) == ""
+}
+
+// pathSkipReason returns the path filter the file relPath fails, or "" if
+// it passes them all
+func (f *pathFilter) pathSkipReason(relPath string) string {
+	switch {
+	case f.ignore.Match(relPath, false):
+		return "--ignore-paths-from"
+	case f.exclude.Match(relPath):
+		return "--exclude"
+	case f.include != nil && !f.include.Match(relPath):
+		return "--include"
+	case f.opts.Tests == TestsExclude && IsTestFile(relPath):
+		return "test file"
+	case f.changed != nil && !f.changed[relPath]:
+		return "not changed in the git range"
+	case f.tracked != nil && !f.tracked[relPath]:
+		return "not tracked by git"
+	}
+	return ""
+}
+
+// matchType reports whether files counted under key pass --profile and
+// --markdown
+func (f *pathFilter) matchType(key string) bool {
+	return f.typeSkipReason(key) == ""
+}
+
+// typeSkipReason returns why files counted under key are skipped, or ""
+// if they are not
+func (f *pathFilter) typeSkipReason(key string) string {
+	if MarkdownExtensions[key] && !f.opts.Markdown {
+		return "Markdown without --markdown"
+	}
+	if f.profile != nil && !f.profile[key] {
+		return "--profile"
+	}
+	return ""
+}
+
+// walkProject calls emit for each code file below rootPath that passes the
+// configured filters, listing them with git if opts.Git is set
+func walkProject(ctx context.Context, rootPath string, opts Options, emit func(path string)) error {
+	if opts.Git {
+		files, err := gitListFiles(rootPath)
+		if err == nil {
+			return emitGitFiles(ctx, rootPath, files, opts, emit)
+		}
+		opts.logger().Warn("could not list files with git, walking the filesystem instead", "err", err)
+	}
+	return walkFiles(ctx, rootPath, opts, emit)
+}
+
+// walkFiles walks rootPath and calls emit for each code file that passes
+// the configured filters, in lexical order. It stops once ctx is done.
+func walkFiles(ctx context.Context, rootPath string, opts Options, emit func(path string)) error {
+	w, err := newFileWalker(ctx, rootPath, opts, emit)
+	if err != nil {
+		return err
+	}
+
+	// The root is followed even if it is a symlink
+	info, err := os.Stat(longPath(rootPath))
+	if err != nil {
+		return err
+	}
+	return w.walk(rootPath, info)
+}
+
+// walkPaths walks the files and directories at paths below rootPath as a
+// walk of rootPath would, calling emit for each code file that passes the
+// configured filters. The ignore files of the directories above each path
+// apply; paths that no longer exist or are not below rootPath are skipped.
+func walkPaths(ctx context.Context, rootPath string, paths []string, opts Options, emit func(path string)) error {
+	w, err := newFileWalker(ctx, rootPath, opts, emit)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		relPath, err := filepath.Rel(rootPath, path)
+		if err != nil || relPath == "." || strings.HasPrefix(relPath, "..") {
+			continue
+		}
+		info, err := os.Lstat(longPath(path))
+		if err != nil {
+			continue
+		}
+		dir := ""
+		w.gitignore.Visit(dir)
+		w.lcignore.Visit(dir)
+		parts := strings.Split(filepath.ToSlash(relPath), "/")
+		for _, part := range parts[:len(parts)-1] {
+			dir = strings.TrimPrefix(dir+"/"+part, "/")
+			w.gitignore.Visit(dir)
+			w.lcignore.Visit(dir)
+		}
+		if err := w.walk(path, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newFileWalker returns a walker of the tree at rootPath
+func newFileWalker(ctx context.Context, rootPath string, opts Options, emit func(path string)) (*fileWalker, error) {
+	filter, err := newPathFilter(rootPath, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &fileWalker{ctx: ctx, root: rootPath, opts: opts, filter: filter, emit: emit}
+	if !opts.NoGitignore {
+		w.gitignore = NewIgnoreTree(rootPath, ".gitignore")
+		w.gitignore.LoadRepoParents()
+	}
+	w.lcignore = newLcignoreTree(rootPath)
+	if opts.FollowSymlinks {
+		w.visited = make(map[string]string)
+		w.ancestors = make(map[string]bool)
+	}
+	return w, nil
+}
+
+// fileWalker walks a tree for walkFiles
+type fileWalker struct {
+	ctx       context.Context
+	root      string
+	opts      Options
+	filter    *pathFilter
+	gitignore *IgnoreTree
+	lcignore  *IgnoreTree
+	emit      func(path string)
+
+	// With FollowSymlinks, visited maps the real path of every directory
+	// walked to the path it was walked as, and ancestors holds the real
+	// paths of the directories being walked
+	visited   map[string]string
+	ancestors map[string]bool
+}
+
+// walk visits path, whose Lstat result is info, and everything below it
+func (w *fileWalker) walk(path string, info os.FileInfo) error {
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+
+	relPath, _ := filepath.Rel(w.root, path)
+	relPath = filepath.ToSlash(relPath)
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		if w.opts.NoSymlinks {
+			return nil
+		}
+		if w.opts.FollowSymlinks {
+			target, err := os.Stat(longPath(path))
+			if err != nil {
+				w.opts.logger().Warn("skipping broken symlink", "path", path)
+				return nil
+			}
+			info = target
+		}
+	}
+
+	if !info.IsDir() {
+		if reason := w.ignoredBy(relPath, false); reason != "" {
+			w.opts.logger().Info("skipping file", "path", path, "reason", reason)
+			return nil
+		}
+		if !w.filter.countFile(path, relPath) {
+			return nil
+		}
+		w.emit(path)
+		return nil
+	}
+
+	// Skip directories we want to ignore. A drive root such as C:\ is
+	// hidden on Windows, so the root is never skipped for that.
+	isRoot := path == w.root
+	if w.filter.skipDir(relPath, info.Name(), isRoot) {
+		return nil
+	}
+	reason := w.ignoredBy(relPath, true)
+	if reason == "" && !isRoot && isHidden(info) {
+		reason = "hidden"
+	}
+	if reason != "" {
+		w.opts.logger().Info("skipping directory", "path", path, "reason", reason)
+		return nil
+	}
+
+	if w.visited != nil {
+		realPath, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return w.unreadable(path, err)
+		}
+		if w.ancestors[realPath] {
+			w.opts.logger().Warn("skipping symlink cycle", "path", path, "target", w.visited[realPath])
+			return nil
+		}
+		if first, ok := w.visited[realPath]; ok {
+			w.opts.logger().Warn("skipping directory already counted", "path", path, "counted_as", first)
+			return nil
+		}
+		w.visited[realPath] = path
+		w.ancestors[realPath] = true
+		defer delete(w.ancestors, realPath)
+	}
+
+	w.gitignore.Visit(relPath)
+	w.lcignore.Visit(relPath)
+	if w.opts.walkedDirs != nil {
+		*w.opts.walkedDirs = append(*w.opts.walkedDirs, path)
+	}
+	entries, err := os.ReadDir(longPath(path))
+	if err != nil {
+		return w.unreadable(path, err)
+	}
+	if !isRoot && w.opts.SkipNestedGit && hasGitEntry(entries) {
+		w.opts.logger().Info("skipping directory", "path", path, "reason", "nested git repository")
+		if w.opts.nestedRepos != nil {
+			*w.opts.nestedRepos = append(*w.opts.nestedRepos, path)
+		}
+		return nil
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			if err := w.unreadable(filepath.Join(path, entry.Name()), err); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := w.walk(filepath.Join(path, entry.Name()), info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasGitEntry reports whether entries, those of a directory, include the
+// .git directory of a repository or the .git file of a submodule or
+// worktree
+func hasGitEntry(entries []os.DirEntry) bool {
+	for _, entry := range entries {
+		if entry.Name() == ".git" {
+			return true
+		}
+	}
+	return false
+}
+
+// unreadable handles a path the walk could not read: with Strict it stops
+// the walk with err, otherwise the path is skipped and recorded
+func (w *fileWalker) unreadable(path string, err error) error {
+	if w.opts.Strict {
+		return err
+	}
+	w.opts.logger().Warn("could not read directory", "path", path, "err", err)
+	w.opts.errors.add(path, err)
+	return nil
+}
+
+// ignoredBy returns the ignore file that excludes relPath, a directory if
+// isDir is set, or "" if none does
+func (w *fileWalker) ignoredBy(relPath string, isDir bool) string {
+	if w.gitignore.Match(relPath, isDir) {
+		return ".gitignore"
+	}
+	if w.lcignore.Match(relPath, isDir) {
+		return LcignoreFileName
+	}
+	return ""
+}
+
+// emitGitFiles calls emit for each of files, paths relative to rootPath as
+// listed by git, that passes the configured filters. Files inside skipped
+// directories are left out, as in the filesystem walk.
+func emitGitFiles(ctx context.Context, rootPath string, files []string, opts Options, emit func(path string)) error {
+	filter, err := newPathFilter(rootPath, opts)
+	if err != nil {
+		return err
+	}
+
+	lcignore := newLcignoreTree(rootPath)
+	skipped := make(map[string]bool)
+	for _, relPath := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if inSkippedDir(filter, relPath, skipped) {
+			continue
+		}
+		visitParents(lcignore, relPath)
+		if lcignore.Match(relPath, false) {
+			opts.logger().Info("skipping file", "path", relPath, "reason", LcignoreFileName)
+			continue
+		}
+
+		path := filepath.Join(rootPath, filepath.FromSlash(relPath))
+		stat := os.Lstat
+		if opts.FollowSymlinks {
+			stat = os.Stat
+		}
+		if info, err := stat(path); err != nil || !info.Mode().IsRegular() {
+			// Deleted from the work tree but still in the index, or a symlink
+			// or submodule
+			continue
+		}
+		if filter.countFile(path, relPath) {
+			emit(path)
+		}
+	}
+	return nil
+}
+
+// LcignoreFileName is the ignore file honored by every walk, with or
+// without git, for paths to leave out of the count only
+const LcignoreFileName = ".lcignore"
+
+// newLcignoreTree returns the tree of .lcignore files for rootPath,
+// including those in the directories above it
+func newLcignoreTree(rootPath string) *IgnoreTree {
+	tree := NewIgnoreTree(rootPath, LcignoreFileName)
+	tree.LoadParents()
+	return tree
+}
+
+// visitParents visits the root and every directory containing relPath in
+// tree, so their ignore files are loaded before relPath is matched
+func visitParents(tree *IgnoreTree, relPath string) {
+	tree.Visit("")
+	for i := 0; i < len(relPath); i++ {
+		if relPath[i] == '/' {
+			tree.Visit(relPath[:i])
+		}
+	}
+}
+
+// inSkippedDir reports whether any directory containing relPath is skipped,
+// caching the verdict for each directory in skipped
+func inSkippedDir(filter *pathFilter, relPath string, skipped map[string]bool) bool {
+	for i := 0; i < len(relPath); i++ {
+		if relPath[i] != '/' {
+			continue
+		}
+		dir := relPath[:i]
+		skip, ok := skipped[dir]
+		if !ok {
+			skip = filter.skipDir(dir, dir[strings.LastIndexByte(dir, '/')+1:], false)
+			skipped[dir] = skip
+		}
+		if skip {
+			return true
+		}
+	}
+	return false
+}
+
+func shouldIgnoreDir(dirName string) bool {
+	if IgnoreDirs[dirName] {
+		return true
+	}
+	// Only ignore hidden directories if not "." or ".."
+	return dirName != "." && dirName != ".." && strings.HasPrefix(dirName, ".")
+}
@@ -0,0 +1,73 @@
+package counter
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestCountMarkdownOptIn(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"main.go":        "package main\n",
+		"README.md":      "# Demo\n\nProse.\n\n```go\nx := 1\n```\n",
+		"docs/guide.rmd": "Prose.\n\n```{r}\nx <- 1\n```\n",
+	})
+	for _, tc := range []struct {
+		markdown bool
+		files    int
+	}{
+		{false, 2},
+		{true, 3},
+	} {
+		stats, err := Count(context.Background(), dir, Options{Markdown: tc.markdown})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.TotalFiles != tc.files {
+			t.Errorf("Markdown %v: counted %d files, want %d", tc.markdown, stats.TotalFiles, tc.files)
+		}
+		if _, ok := stats.FilesByExt[".md"]; ok != tc.markdown {
+			t.Errorf("Markdown %v: FilesByExt has .md = %v", tc.markdown, ok)
+		}
+	}
+}
+
+func TestCountGitTracked(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found in PATH")
+	}
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"tracked.go":       "package main\n",
+		"sub/tracked.py":   "x = 1\n",
+		"untracked.go":     "package main\n\nvar x = 1\n",
+		"sub/untracked.js": "let x;\n",
+	})
+	git(t, dir, "init", "-q")
+	git(t, dir, "add", "tracked.go", "sub/tracked.py")
+
+	stats, err := Count(context.Background(), dir, Options{GitTracked: true, CollectFiles: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, file := range stats.Files {
+		rel, _ := filepath.Rel(dir, file.Path)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	sort.Strings(got)
+	if want := []string{"sub/tracked.py", "tracked.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("counted %v, want %v", got, want)
+	}
+
+	// Outside a repository the flag fails instead of counting everything
+	_, err = Count(context.Background(), t.TempDir(), Options{GitTracked: true})
+	if err == nil || !strings.Contains(err.Error(), "--git-tracked") {
+		t.Errorf("count outside a repository: err = %v, want a --git-tracked error", err)
+	}
+}
@@ -0,0 +1,301 @@
+package counter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// yamlLine is a non-blank line of a YAML document with comments removed
+type yamlLine struct {
+	number int
+	indent int
+	text   string
+}
+
+// yamlParser decodes the YAML subset used by config files: block maps and
+// lists (including lists of maps), flow lists and maps on one line such as
+// [a, "b"] and {add: [.x]}, and plain, single- or double-quoted scalars.
+// Every scalar decodes to a string. Block scalars (| and >) are rejected.
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// parseYAML decodes data into nested map[string]interface{},
+// []interface{} and string values
+func parseYAML(data []byte) (interface{}, error) {
+	p := &yamlParser{}
+	for i, raw := range strings.Split(string(data), "\n") {
+		raw = strings.TrimRight(stripYAMLComment(raw), " \t\r")
+		text := strings.TrimLeft(raw, " ")
+		if text == "" || text == "---" {
+			continue
+		}
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+		p.lines = append(p.lines, yamlLine{number: i + 1, indent: len(raw) - len(text), text: text})
+	}
+	if len(p.lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+
+	value, err := p.parseBlock(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].number)
+	}
+	return value, nil
+}
+
+// parseBlock parses the map or list starting at the current line
+func (p *yamlParser) parseBlock(indent int) (interface{}, error) {
+	if isYAMLListItem(p.lines[p.pos].text) {
+		return p.parseList(indent)
+	}
+	return p.parseMap(indent)
+}
+
+func (p *yamlParser) parseList(indent int) ([]interface{}, error) {
+	list := []interface{}{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isYAMLListItem(p.lines[p.pos].text) {
+		line := p.lines[p.pos]
+		rest := strings.TrimLeft(line.text[1:], " ")
+
+		switch {
+		case rest == "":
+			p.pos++
+			item, err := p.parseNested(indent)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		case isYAMLMapEntry(rest):
+			// "- key: value" starts a map indented at the column of key
+			p.lines[p.pos] = yamlLine{number: line.number, indent: indent + len(line.text) - len(rest), text: rest}
+			item, err := p.parseMap(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		default:
+			item, err := parseYAMLScalar(rest, line.number)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+			p.pos++
+		}
+	}
+	return list, nil
+}
+
+func (p *yamlParser) parseMap(indent int) (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && !isYAMLListItem(p.lines[p.pos].text) {
+		line := p.lines[p.pos]
+		key, rest, ok := splitYAMLKey(line.text)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", line.number)
+		}
+		if _, dup := m[key]; dup {
+			return nil, fmt.Errorf("line %d: duplicate key %q", line.number, key)
+		}
+		p.pos++
+
+		if rest == "" {
+			value, err := p.parseNested(indent)
+			if err != nil {
+				return nil, err
+			}
+			// A list may sit at the same indentation as its key
+			if value == "" && p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isYAMLListItem(p.lines[p.pos].text) {
+				value, err = p.parseList(indent)
+				if err != nil {
+					return nil, err
+				}
+			}
+			m[key] = value
+			continue
+		}
+
+		value, err := parseYAMLScalar(rest, line.number)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = value
+	}
+	return m, nil
+}
+
+// parseNested parses the block indented deeper than indent that follows
+// a "key:" or "-" line, or returns "" if there is none
+func (p *yamlParser) parseNested(indent int) (interface{}, error) {
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		return p.parseBlock(p.lines[p.pos].indent)
+	}
+	return "", nil
+}
+
+func isYAMLListItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func isYAMLMapEntry(text string) bool {
+	if strings.HasPrefix(text, "[") || strings.HasPrefix(text, "{") {
+		return false
+	}
+	_, _, ok := splitYAMLKey(text)
+	return ok
+}
+
+// splitYAMLKey splits "key: value" into its key and value. The separator
+// is the first colon outside quotes followed by a space or the line end.
+func splitYAMLKey(text string) (key, value string, ok bool) {
+	for i := 0; i < len(text); i++ {
+		switch {
+		case i == 0 && opensYAMLQuote(text, i):
+			end := strings.IndexByte(text[i+1:], text[i])
+			if end < 0 {
+				return "", "", false
+			}
+			i += end + 1
+		case text[i] == ':':
+			if i+1 < len(text) && text[i+1] != ' ' {
+				continue
+			}
+			key, err := parseYAMLScalar(strings.TrimSpace(text[:i]), 0)
+			if err != nil || key == "" {
+				return "", "", false
+			}
+			return key.(string), strings.TrimSpace(text[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// parseYAMLScalar parses a plain or quoted scalar or a flow list or map
+func parseYAMLScalar(text string, lineNumber int) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(text, "["):
+		if !strings.HasSuffix(text, "]") {
+			return nil, fmt.Errorf("line %d: unterminated list", lineNumber)
+		}
+		list := []interface{}{}
+		inner := strings.TrimSpace(text[1 : len(text)-1])
+		if inner == "" {
+			return list, nil
+		}
+		for _, item := range splitYAMLFlow(inner) {
+			value, err := parseYAMLScalar(strings.TrimSpace(item), lineNumber)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	case strings.HasPrefix(text, "{"):
+		if !strings.HasSuffix(text, "}") {
+			return nil, fmt.Errorf("line %d: unterminated map", lineNumber)
+		}
+		m := map[string]interface{}{}
+		inner := strings.TrimSpace(text[1 : len(text)-1])
+		if inner == "" {
+			return m, nil
+		}
+		for _, item := range splitYAMLFlow(inner) {
+			key, rest, ok := splitYAMLKey(strings.TrimSpace(item))
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected \"key: value\" in %s", lineNumber, text)
+			}
+			if _, dup := m[key]; dup {
+				return nil, fmt.Errorf("line %d: duplicate key %q", lineNumber, key)
+			}
+			value, err := parseYAMLScalar(rest, lineNumber)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		}
+		return m, nil
+	case strings.HasPrefix(text, "|") || strings.HasPrefix(text, ">"):
+		if strings.Trim(text[1:], "+-0123456789") == "" {
+			return nil, fmt.Errorf("line %d: unsupported YAML feature: block scalar %s", lineNumber, text)
+		}
+		return text, nil
+	case strings.HasPrefix(text, `"`):
+		value, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid quoted string %s", lineNumber, text)
+		}
+		return value, nil
+	case strings.HasPrefix(text, "'"):
+		if len(text) < 2 || !strings.HasSuffix(text, "'") {
+			return nil, fmt.Errorf("line %d: invalid quoted string %s", lineNumber, text)
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	default:
+		return text, nil
+	}
+}
+
+// splitYAMLFlow splits the items of a flow list or map at the commas
+// outside quotes and nested collections
+func splitYAMLFlow(text string) []string {
+	var items []string
+	start, depth := 0, 0
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		switch {
+		case quote != 0:
+			if text[i] == '\\' && quote == '"' {
+				i++
+			} else if text[i] == quote {
+				quote = 0
+			}
+		case opensYAMLQuote(text, i):
+			quote = text[i]
+		case text[i] == '[' || text[i] == '{':
+			depth++
+		case text[i] == ']' || text[i] == '}':
+			depth--
+		case text[i] == ',' && depth == 0:
+			items = append(items, text[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, text[start:])
+}
+
+// stripYAMLComment removes a "#" comment that starts the line or follows
+// whitespace outside quotes
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch {
+		case quote != 0:
+			if line[i] == '\\' && quote == '"' {
+				i++
+			} else if line[i] == quote {
+				quote = 0
+			}
+		case opensYAMLQuote(line, i):
+			quote = line[i]
+		case line[i] == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// opensYAMLQuote reports whether s[i] starts a quoted scalar: a quote at
+// the start of a token, so apostrophes inside plain scalars are not quotes
+func opensYAMLQuote(s string, i int) bool {
+	if s[i] != '"' && s[i] != '\'' {
+		return false
+	}
+	return i == 0 || strings.IndexByte(" \t[{,:-", s[i-1]) >= 0
+}
@@ -0,0 +1,72 @@
+package counter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	cases := []struct {
+		name string
+		yaml string
+		want interface{}
+	}{
+		{"block map", "a: 1\nb: x y\n", map[string]interface{}{"a": "1", "b": "x y"}},
+		{"nested map", "a:\n  b: 1\n", map[string]interface{}{"a": map[string]interface{}{"b": "1"}}},
+		{"block list", "a:\n  - x\n  - 'y'\n", map[string]interface{}{"a": []interface{}{"x", "y"}}},
+		{"list at key indentation", "a:\n- x\n", map[string]interface{}{"a": []interface{}{"x"}}},
+		{"list of maps", "- name: a\n  ext: [.a]\n- name: b\n", []interface{}{
+			map[string]interface{}{"name": "a", "ext": []interface{}{".a"}},
+			map[string]interface{}{"name": "b"},
+		}},
+		{"flow list", `a: [x, "y, z", '#']`, map[string]interface{}{"a": []interface{}{"x", "y, z", "#"}}},
+		{"empty flow list", "a: []", map[string]interface{}{"a": []interface{}{}}},
+		{"flow map", "a: {add: [.x, .y], remove: .z}", map[string]interface{}{
+			"a": map[string]interface{}{"add": []interface{}{".x", ".y"}, "remove": ".z"},
+		}},
+		{"empty flow map", "a: {}", map[string]interface{}{"a": map[string]interface{}{}}},
+		{"flow map in a list", "- {name: a, quotes: [\"'\", '\"']}", []interface{}{
+			map[string]interface{}{"name": "a", "quotes": []interface{}{"'", `"`}},
+		}},
+		{"nested flow collections", "a: [{b: [1, 2]}, [c]]", map[string]interface{}{
+			"a": []interface{}{map[string]interface{}{"b": []interface{}{"1", "2"}}, []interface{}{"c"}},
+		}},
+		{"comments", "# top\na: x # trailing\nb: 'y # z'\n", map[string]interface{}{"a": "x", "b": "y # z"}},
+		{"plain scalar starting with >", "a: >=1\n", map[string]interface{}{"a": ">=1"}},
+		{"empty document", "# nothing\n", map[string]interface{}{}},
+	}
+	for _, tc := range cases {
+		got, err := parseYAML([]byte(tc.yaml))
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: parseYAML(%q) = %#v, want %#v", tc.name, tc.yaml, got, tc.want)
+		}
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	cases := []struct {
+		yaml string
+		want string
+	}{
+		{"a: |\n  text\n", "line 1: unsupported YAML feature: block scalar |"},
+		{"a: x\nb: >-\n  folded\n", "line 2: unsupported YAML feature: block scalar >-"},
+		{"a:\n  - |2\n    text\n", "line 2: unsupported YAML feature: block scalar |2"},
+		{"a: [x, y\n", "line 1: unterminated list"},
+		{"a: {b: x\n", "line 1: unterminated map"},
+		{"a: {b: x, b: y}\n", `line 1: duplicate key "b"`},
+		{"a: {b}\n", `line 1: expected "key: value" in {b}`},
+		{"a: x\na: y\n", `line 2: duplicate key "a"`},
+		{"a:\n\tb: x\n", "line 2: tabs are not allowed for indentation"},
+	}
+	for _, tc := range cases {
+		_, err := parseYAML([]byte(tc.yaml))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("parseYAML(%q) = %v, want an error with %q", tc.yaml, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/a2hop/line-counter/counter"
+)
+
+// runDiff implements "line-counter diff <old> <new>": it prints the
+// per-language change between two snapshots, each either a baseline saved
+// with --save-baseline or a directory that is counted now
+func runDiff(ctx context.Context, w io.Writer, args []string, opts Options) error {
+	if len(args) != 2 {
+		return errors.New("diff: expected two snapshots (a saved baseline or a directory)")
+	}
+
+	old, err := loadSnapshot(ctx, args[0], opts)
+	if err != nil {
+		return err
+	}
+	cur, err := loadSnapshot(ctx, args[1], opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Comparing %s to %s\n", args[0], args[1])
+	printDelta(w, "Change:", old, cur)
+	return checkGrowth(old, cur, opts.FailIfCodeGrowsBy)
+}
+
+// loadSnapshot counts the directory at path, or loads it as a baseline if
+// it is a file
+func loadSnapshot(ctx context.Context, path string, opts Options) (*counter.ProjectStats, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		stats, err := counter.Count(ctx, path, opts.Options)
+		if err != nil {
+			return nil, countError(err, opts)
+		}
+		return stats, nil
+	}
+
+	baseline, err := LoadBaseline(path, opts.Logger)
+	if err != nil {
+		return nil, err
+	}
+	return baseline.Stats, nil
+}
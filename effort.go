@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// defaultCostPerMonth is the cost of one person-month used by --report
+// effort: sloccount's default salary of $56,286 a year times its overhead
+// factor of 2.4, spread over 12 months
+const defaultCostPerMonth = 56286 * 2.4 / 12
+
+// Effort is a basic COCOMO estimate for an organic project, the model used
+// by sloccount and scc
+type Effort struct {
+	// PersonMonths is the development effort
+	PersonMonths float64
+	// ScheduleMonths is the elapsed development time
+	ScheduleMonths float64
+	// Developers is the average team size over the schedule
+	Developers float64
+	// Cost is PersonMonths at the cost of one person-month
+	Cost float64
+}
+
+// estimateEffort returns the COCOMO estimate for codeLines lines of code,
+// with each person-month costing costPerMonth
+func estimateEffort(codeLines int, costPerMonth float64) Effort {
+	var e Effort
+	if codeLines <= 0 {
+		return e
+	}
+	e.PersonMonths = 2.4 * math.Pow(float64(codeLines)/1000, 1.05)
+	e.ScheduleMonths = 2.5 * math.Pow(e.PersonMonths, 0.38)
+	e.Developers = e.PersonMonths / e.ScheduleMonths
+	e.Cost = e.PersonMonths * costPerMonth
+	return e
+}
+
+func printEffort(w io.Writer, codeLines int, costPerMonth float64) {
+	e := estimateEffort(codeLines, costPerMonth)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Estimated effort (basic COCOMO, organic):")
+	fmt.Fprintln(w, strings.Repeat("-", 58))
+	fmt.Fprintf(w, "%-28s %.2f\n", "Effort (person-months)", e.PersonMonths)
+	fmt.Fprintf(w, "%-28s %.2f\n", "Schedule (months)", e.ScheduleMonths)
+	fmt.Fprintf(w, "%-28s %.2f\n", "Developers", e.Developers)
+	fmt.Fprintf(w, "%-28s $%.0f\n", "Cost", e.Cost)
+	fmt.Fprintf(w, "(at $%.0f per person-month)\n", costPerMonth)
+}
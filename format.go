@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/a2hop/line-counter/counter"
+)
+
+// Formatter renders project statistics in one output format
+type Formatter interface {
+	Format(w io.Writer, stats *counter.ProjectStats) error
+}
+
+// newFormatter returns the formatter selected by opts for a scan of rootPath
+func newFormatter(rootPath string, opts Options) (Formatter, error) {
+	if opts.SummaryOnly {
+		return SummaryFormatter{}, nil
+	}
+	if opts.Embed {
+		return EmbedFormatter{Package: opts.EmbedPackage}, nil
+	}
+	if opts.Template != "" {
+		tmpl, err := LoadTemplate(opts.Template)
+		if err != nil {
+			return nil, err
+		}
+		return TemplateFormatter{Template: tmpl, ByLanguage: strings.HasPrefix(opts.Template, "builtin:")}, nil
+	}
+
+	switch opts.Format {
+	case "", "text":
+		return TextFormatter{Root: rootPath, Opts: opts}, nil
+	case "json":
+		return JSONFormatter{}, nil
+	case "csv":
+		return CSVFormatter{}, nil
+	case "markdown", "html":
+		tmpl, err := LoadTemplate("builtin:" + opts.Format)
+		if err != nil {
+			return nil, err
+		}
+		return TemplateFormatter{Template: tmpl, ByLanguage: true}, nil
+	case "sbom":
+		return SBOMFormatter{}, nil
+	case "influxdb":
+		return InfluxDBFormatter{Measurement: opts.Measurement, Timestamp: time.Now()}, nil
+	case "sarif":
+		return SARIFFormatter{}, nil
+	case "codeclimate":
+		return CodeClimateFormatter{}, nil
+	case "junit":
+		return JUnitFormatter{Opts: opts}, nil
+	case "ndjson":
+		return NDJSONFormatter{}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q (expected text, json, csv, markdown, html, sbom, influxdb, sarif, codeclimate, junit or ndjson)", opts.Format)
+	}
+}
+
+// TextFormatter prints the human-readable tables
+type TextFormatter struct {
+	Root string
+	Opts Options
+}
+
+func (f TextFormatter) Format(w io.Writer, stats *counter.ProjectStats) error {
+	printReport(w, f.Root, stats, f.Opts)
+	return nil
+}
+
+// SummaryFormatter prints the totals as a single line of key=value pairs
+// for scripts, e.g. "files=12 lines=340 code=300 comments=25 blank=15"
+type SummaryFormatter struct{}
+
+func (SummaryFormatter) Format(w io.Writer, stats *counter.ProjectStats) error {
+	t := stats.TotalStats
+	_, err := fmt.Fprintf(w, "files=%d lines=%d code=%d comments=%d blank=%d\n",
+		stats.TotalFiles, t.TotalLines, t.CodeLines, t.CommentLines, t.BlankLines)
+	return err
+}
+
+// EmbedFormatter writes a Go source file holding the JSON-encoded stats
+type EmbedFormatter struct {
+	Package string
+}
+
+func (f EmbedFormatter) Format(w io.Writer, stats *counter.ProjectStats) error {
+	return printEmbed(w, stats, f.Package)
+}
+
+// TemplateFormatter executes a text/template with the stats as data
+type TemplateFormatter struct {
+	Template *template.Template
+
+	// ByLanguage keys the per-extension maps by language name instead, as
+	// the built-in templates expect
+	ByLanguage bool
+}
+
+func (f TemplateFormatter) Format(w io.Writer, stats *counter.ProjectStats) error {
+	if f.ByLanguage {
+		stats = byLanguage(stats)
+	}
+	return RenderTemplate(f.Template, stats, w)
+}
+
+// JSONFormatter writes the complete ProjectStats, including per-file
+// results, as indented JSON
+type JSONFormatter struct{}
+
+func (JSONFormatter) Format(w io.Writer, stats *counter.ProjectStats) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(stats)
+}
+
+// CSVFormatter writes one RFC 4180 row per extension followed by a TOTAL row.
+// The columns are fixed so scripts can rely on their order.
+type CSVFormatter struct{}
+
+func (CSVFormatter) Format(w io.Writer, stats *counter.ProjectStats) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"extension", "language", "files", "total_lines", "code_lines", "comment_lines", "blank_lines", "pct_code", "avg_lines_per_file"})
+
+	record := func(label, language string, files int, s counter.FileStats, avg float64) []string {
+		return []string{
+			label,
+			language,
+			strconv.Itoa(files),
+			strconv.Itoa(s.TotalLines),
+			strconv.Itoa(s.CodeLines),
+			strconv.Itoa(s.CommentLines),
+			strconv.Itoa(s.BlankLines),
+			strconv.FormatFloat(percentOf(s.CodeLines, s.TotalLines), 'f', 1, 64),
+			strconv.FormatFloat(avg, 'f', 1, 64),
+		}
+	}
+	for _, ext := range sortedExtensions(stats) {
+		cw.Write(record(ext, counter.LanguageName(ext), stats.FilesByExt[ext], stats.StatsByExt[ext], stats.ExtAverageTotalLines(ext)))
+	}
+	cw.Write(record("TOTAL", "", stats.TotalFiles, stats.TotalStats, stats.AverageTotalLines()))
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// SBOMComponent summarises one extension for software bill of materials
+// tooling such as CycloneDX or SPDX generators
+type SBOMComponent struct {
+	Language     string `json:"language"`
+	Files        int    `json:"files"`
+	TotalLines   int    `json:"totalLines"`
+	CodeLines    int    `json:"codeLines"`
+	CommentLines int    `json:"commentLines"`
+	BlankLines   int    `json:"blankLines"`
+}
+
+// SBOMFormatter writes a JSON object mapping each extension to its component
+type SBOMFormatter struct{}
+
+func (SBOMFormatter) Format(w io.Writer, stats *counter.ProjectStats) error {
+	components := make(map[string]SBOMComponent, len(stats.FilesByExt))
+	for ext, files := range stats.FilesByExt {
+		s := stats.StatsByExt[ext]
+		components[ext] = SBOMComponent{
+			Language:     counter.LanguageName(ext),
+			Files:        files,
+			TotalLines:   s.TotalLines,
+			CodeLines:    s.CodeLines,
+			CommentLines: s.CommentLines,
+			BlankLines:   s.BlankLines,
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(components)
+}
+
+// InfluxDBFormatter emits InfluxDB line protocol, one point per extension
+type InfluxDBFormatter struct {
+	Measurement string
+	Timestamp   time.Time
+}
+
+func (f InfluxDBFormatter) Format(w io.Writer, stats *counter.ProjectStats) error {
+	measurement := measurementEscaper.Replace(f.Measurement)
+	for _, ext := range sortedExtensions(stats) {
+		s := stats.StatsByExt[ext]
+		_, err := fmt.Fprintf(w, "%s,ext=%s files=%di,total=%di,code=%di,comments=%di,blank=%di %d\n",
+			measurement, influxEscaper.Replace(ext), stats.FilesByExt[ext],
+			s.TotalLines, s.CodeLines, s.CommentLines, s.BlankLines, f.Timestamp.UnixNano())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortedExtensions returns every extension in stats in sorted order
+func sortedExtensions(stats *counter.ProjectStats) []string {
+	var extensions []string
+	for ext := range stats.FilesByExt {
+		extensions = append(extensions, ext)
+	}
+	sort.Strings(extensions)
+	return extensions
+}
+
+// measurementEscaper escapes the characters that are special in line
+// protocol measurement names. An equals sign is literal there.
+var measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+
+// influxEscaper escapes the characters that are special in line protocol
+// tag values
+var influxEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
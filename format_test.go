@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a2hop/line-counter/counter"
+)
+
+func TestCSVRoundTrip(t *testing.T) {
+	// An unknown key is its own language name, so both of its cells need
+	// quoting
+	const odd = `data,"raw"`
+	stats := counter.NewProjectStats()
+	want := map[string]struct {
+		files int
+		stats counter.FileStats
+	}{
+		".go": {3, counter.FileStats{TotalLines: 120, CodeLines: 90, CommentLines: 20, BlankLines: 10}},
+		odd:   {1, counter.FileStats{TotalLines: 7, CodeLines: 7}},
+	}
+	for key, c := range want {
+		stats.FilesByExt[key] = c.files
+		stats.StatsByExt[key] = c.stats
+		stats.PerExtFileLengths[key] = []int{c.stats.TotalLines}
+		stats.TotalFiles += c.files
+		stats.TotalStats.Add(c.stats)
+	}
+	want["TOTAL"] = struct {
+		files int
+		stats counter.FileStats
+	}{stats.TotalFiles, stats.TotalStats}
+
+	var buf bytes.Buffer
+	if err := (CSVFormatter{}).Format(&buf, stats); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"data,""raw"""`) {
+		t.Errorf("key %q is not quoted per RFC 4180:\n%s", odd, buf.String())
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1+len(want) {
+		t.Fatalf("got %d records, want a header and %d rows", len(records), len(want))
+	}
+	for _, record := range records[1:] {
+		c, ok := want[record[0]]
+		if !ok {
+			t.Errorf("unexpected row %q", record)
+			continue
+		}
+		if record[0] == odd && record[1] != odd {
+			t.Errorf("language of %q = %q", odd, record[1])
+		}
+		numbers := []int{c.files, c.stats.TotalLines, c.stats.CodeLines, c.stats.CommentLines, c.stats.BlankLines}
+		for i, n := range numbers {
+			if record[2+i] != strconv.Itoa(n) {
+				t.Errorf("%s: column %s = %q, want %d", record[0], records[0][2+i], record[2+i], n)
+			}
+		}
+		pct := strconv.FormatFloat(percentOf(c.stats.CodeLines, c.stats.TotalLines), 'f', 1, 64)
+		avg := strconv.FormatFloat(float64(c.stats.TotalLines)/float64(c.files), 'f', 1, 64)
+		if record[7] != pct || record[8] != avg {
+			t.Errorf("%s: pct_code, avg_lines_per_file = %s, %s, want %s, %s", record[0], record[7], record[8], pct, avg)
+		}
+	}
+}
+
+func TestInfluxDBEscaping(t *testing.T) {
+	stats := counter.NewProjectStats()
+	stats.FilesByExt["a b,c=d"] = 2
+	stats.StatsByExt["a b,c=d"] = counter.FileStats{TotalLines: 5, CodeLines: 3, CommentLines: 1, BlankLines: 1}
+
+	var buf bytes.Buffer
+	f := InfluxDBFormatter{Measurement: "lines of,code=x", Timestamp: time.Unix(0, 42)}
+	if err := f.Format(&buf, stats); err != nil {
+		t.Fatal(err)
+	}
+	// The equals sign is only escaped in the tag value
+	want := `lines\ of\,code=x,ext=a\ b\,c\=d files=2i,total=5i,code=3i,comments=1i,blank=1i 42` + "\n"
+	if buf.String() != want {
+		t.Errorf("got  %q\nwant %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/a2hop/line-counter/counter"
+)
+
+// writeTree creates the files of tree, slash-separated paths mapped to
+// their content, below dir
+func writeTree(t *testing.T, dir string, tree map[string]string) {
+	t.Helper()
+	for name, content := range tree {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// count counts root with opts, failing the test on errors
+func count(t *testing.T, root string, opts counter.Options) *counter.ProjectStats {
+	t.Helper()
+	c, err := counter.New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := c.Count(context.Background(), root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stats
+}
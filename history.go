@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/a2hop/line-counter/counter"
+)
+
+// defaultHistoryDB is the history file used by record and history
+// without --db
+const defaultHistoryDB = ".linecounter-history.jsonl"
+
+// HistoryEntry is one snapshot appended by "line-counter record", or one
+// commit counted by "line-counter trend". The history file holds one JSON
+// entry per line, oldest first.
+type HistoryEntry struct {
+	Time time.Time
+	Root string
+	// Commit is the commit counted by trend
+	Commit    string `json:",omitempty"`
+	Total     HistoryCounts
+	Languages map[string]HistoryCounts
+}
+
+// HistoryCounts are the counts of a language, or of the whole tree, in a
+// HistoryEntry
+type HistoryCounts struct {
+	Files    int
+	Total    int
+	Code     int
+	Comments int
+	Blank    int
+}
+
+func historyCounts(files int, stats counter.FileStats) HistoryCounts {
+	return HistoryCounts{
+		Files:    files,
+		Total:    stats.TotalLines,
+		Code:     stats.CodeLines,
+		Comments: stats.CommentLines,
+		Blank:    stats.BlankLines,
+	}
+}
+
+// historyEntry returns the entry of stats, counted in root at t
+func historyEntry(t time.Time, root string, stats *counter.ProjectStats) HistoryEntry {
+	languages := byLanguage(stats)
+	entry := HistoryEntry{
+		Time:      t,
+		Root:      root,
+		Total:     historyCounts(stats.TotalFiles, stats.TotalStats),
+		Languages: make(map[string]HistoryCounts),
+	}
+	for name, langStats := range languages.StatsByExt {
+		entry.Languages[name] = historyCounts(languages.FilesByExt[name], langStats)
+	}
+	return entry
+}
+
+// runRecord implements "line-counter record [path]": it counts path and
+// appends the counts per language to the history file
+func runRecord(ctx context.Context, w io.Writer, args []string, opts Options) error {
+	if len(args) > 1 {
+		return errors.New("record: expected at most one path")
+	}
+	root := "."
+	if len(args) == 1 {
+		root = args[0]
+	}
+
+	stats, err := countStats(ctx, root, opts)
+	opts.progress.clear()
+	if err != nil {
+		return countError(err, opts)
+	}
+
+	entry := historyEntry(time.Now().UTC(), root, stats)
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(opts.HistoryDB, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Recorded %d files, %d code lines to %s\n", stats.TotalFiles, stats.TotalStats.CodeLines, opts.HistoryDB)
+	return nil
+}
+
+// LoadHistory reads the entries of the history file at path
+func LoadHistory(path string) ([]HistoryEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("could not parse %s line %d: %v", path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// runHistory implements "line-counter history": it prints the recorded
+// totals over time, followed by a trend line per language
+func runHistory(w io.Writer, args []string, opts Options) error {
+	if len(args) != 0 {
+		return errors.New("history: unexpected arguments; use --db to choose the history file")
+	}
+	entries, err := LoadHistory(opts.HistoryDB)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("history: %s holds no snapshots; add one with line-counter record", opts.HistoryDB)
+	}
+
+	printHistory(w, fmt.Sprintf("History of %s (%d snapshots)", opts.HistoryDB, len(entries)), entries)
+	return nil
+}
+
+// printHistory prints the totals of entries, oldest first, under title,
+// followed by a trend line of the code lines of each language
+func printHistory(w io.Writer, title string, entries []HistoryEntry) {
+	fmt.Fprintln(w, title)
+	fmt.Fprintln(w, strings.Repeat("-", 58))
+	fmt.Fprintf(w, "%-18s %-8s %-10s %-10s %s\n", "Time", "Files", "Total", "Code", "Change")
+	fmt.Fprintln(w, strings.Repeat("-", 58))
+	for i, entry := range entries {
+		change := ""
+		if i > 0 {
+			change = signed(entry.Total.Code - entries[i-1].Total.Code)
+		}
+		fmt.Fprintf(w, "%-18s %-8d %-10d %-10d %s\n", entry.label(), entry.Total.Files,
+			entry.Total.Total, entry.Total.Code, change)
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, entry := range entries {
+		for name := range entry.Languages {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	first, last := entries[0], entries[len(entries)-1]
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Code lines by language:")
+	fmt.Fprintln(w, strings.Repeat("-", 58))
+	for _, name := range names {
+		values := make([]int, len(entries))
+		for i, entry := range entries {
+			values[i] = entry.Languages[name].Code
+		}
+		code := last.Languages[name].Code
+		fmt.Fprintf(w, "%-16s %-10d %-8s %s\n", name, code,
+			signed(code-first.Languages[name].Code), sparkline(values))
+	}
+}
+
+// label names the entry in printHistory: its local time, or the date and
+// short hash of its commit
+func (e HistoryEntry) label() string {
+	if len(e.Commit) >= 7 {
+		return e.Time.Format("2006-01-02") + " " + e.Commit[:7]
+	}
+	return e.Time.Local().Format("2006-01-02 15:04")
+}
+
+// sparkBars are the bar heights drawn by sparkline, lowest first
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws values as a row of bars scaled between their minimum
+// and maximum
+func sparkline(values []int) string {
+	low, high := values[0], values[0]
+	for _, v := range values {
+		if v < low {
+			low = v
+		}
+		if v > high {
+			high = v
+		}
+	}
+
+	var b strings.Builder
+	for _, v := range values {
+		bar := 0
+		if high > low {
+			bar = (v - low) * (len(sparkBars) - 1) / (high - low)
+		}
+		b.WriteRune(sparkBars[bar])
+	}
+	return b.String()
+}
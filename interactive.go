@@ -0,0 +1,411 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/a2hop/line-counter/counter"
+)
+
+// explorerSorts are the columns the explorer sorts by, in the order the s
+// key cycles through them
+var explorerSorts = []string{"code", "total", "comments", "files", "name"}
+
+// dirNode is a directory of the tree the explorer browses
+type dirNode struct {
+	name   string
+	parent *dirNode
+	dirs   map[string]*dirNode
+	files  []counter.FileResult
+}
+
+func newDirNode(name string, parent *dirNode) *dirNode {
+	return &dirNode{name: name, parent: parent, dirs: make(map[string]*dirNode)}
+}
+
+// path returns the slash-separated path of d below the root
+func (d *dirNode) path() string {
+	if d.parent == nil {
+		return ""
+	}
+	if parent := d.parent.path(); parent != "" {
+		return parent + "/" + d.name
+	}
+	return d.name
+}
+
+// buildTree arranges files, counted below root, into a directory tree. The
+// files in the tree have slash-separated paths relative to root.
+func buildTree(root string, files []counter.FileResult) *dirNode {
+	tree := newDirNode("", nil)
+	for _, file := range files {
+		if rel, err := filepath.Rel(root, file.Path); err == nil {
+			file.Path = rel
+		}
+		file.Path = filepath.ToSlash(file.Path)
+		parts := strings.Split(file.Path, "/")
+		dir := tree
+		for _, part := range parts[:len(parts)-1] {
+			child := dir.dirs[part]
+			if child == nil {
+				child = newDirNode(part, dir)
+				dir.dirs[part] = child
+			}
+			dir = child
+		}
+		dir.files = append(dir.files, file)
+	}
+	return tree
+}
+
+// explorerRow is one line of the explorer: a subdirectory or a file of the
+// current directory, or any file below it in the flat file list
+type explorerRow struct {
+	name  string
+	dir   *dirNode
+	files int
+	stats counter.FileStats
+}
+
+// explorer is the state of the --interactive terminal UI
+type explorer struct {
+	label    string
+	partial  bool
+	cwd      *dirNode
+	disabled map[string]bool
+	sortBy   int
+	reverse  bool
+	flat     bool
+
+	cursor, offset int
+
+	// With langMode the language list is shown, to turn languages on and
+	// off
+	langMode   bool
+	languages  []string
+	langCursor int
+}
+
+func newExplorer(label string, root string, stats *counter.ProjectStats) *explorer {
+	names := make(map[string]bool)
+	for _, file := range stats.Files {
+		names[counter.GroupName(file.Language)] = true
+	}
+	languages := make([]string, 0, len(names))
+	for name := range names {
+		languages = append(languages, name)
+	}
+	sort.Strings(languages)
+
+	return &explorer{
+		label:     label,
+		partial:   stats.Partial,
+		cwd:       buildTree(root, stats.Files),
+		disabled:  make(map[string]bool),
+		languages: languages,
+	}
+}
+
+// enabled reports whether file is in one of the languages turned on
+func (e *explorer) enabled(file counter.FileResult) bool {
+	return !e.disabled[counter.GroupName(file.Language)]
+}
+
+// total adds up the enabled files below d
+func (e *explorer) total(d *dirNode) (files int, stats counter.FileStats) {
+	for _, file := range e.flatFiles(d) {
+		files++
+		stats.Add(file.Stats)
+	}
+	return files, stats
+}
+
+// flatFiles returns the enabled files below d
+func (e *explorer) flatFiles(d *dirNode) []counter.FileResult {
+	var files []counter.FileResult
+	for _, file := range d.files {
+		if e.enabled(file) {
+			files = append(files, file)
+		}
+	}
+	for _, child := range d.dirs {
+		files = append(files, e.flatFiles(child)...)
+	}
+	return files
+}
+
+// rows returns the rows of the current directory in the selected order
+func (e *explorer) rows() []explorerRow {
+	var rows []explorerRow
+	if e.flat {
+		prefix := e.cwd.path() + "/"
+		for _, file := range e.flatFiles(e.cwd) {
+			rows = append(rows, explorerRow{name: strings.TrimPrefix(file.Path, prefix), files: 1, stats: file.Stats})
+		}
+	} else {
+		for name, child := range e.cwd.dirs {
+			if files, stats := e.total(child); files > 0 {
+				rows = append(rows, explorerRow{name: name + "/", dir: child, files: files, stats: stats})
+			}
+		}
+		for _, file := range e.cwd.files {
+			if e.enabled(file) {
+				rows = append(rows, explorerRow{name: path.Base(file.Path), files: 1, stats: file.Stats})
+			}
+		}
+	}
+
+	value := func(r explorerRow) int {
+		switch explorerSorts[e.sortBy] {
+		case "total":
+			return r.stats.TotalLines
+		case "comments":
+			return r.stats.CommentLines
+		case "files":
+			return r.files
+		}
+		return r.stats.CodeLines
+	}
+	// Counts sort largest first and names alphabetically, unless reversed
+	sort.Slice(rows, func(i, j int) bool {
+		if explorerSorts[e.sortBy] != "name" {
+			if a, b := value(rows[i]), value(rows[j]); a != b {
+				return (a > b) != e.reverse
+			}
+		}
+		return (rows[i].name < rows[j].name) != (e.reverse && explorerSorts[e.sortBy] == "name")
+	})
+	return rows
+}
+
+// Keys of the explorer, after escape sequences are decoded
+const (
+	keyUp    = "up"
+	keyDown  = "down"
+	keyLeft  = "left"
+	keyRight = "right"
+	keyEnter = "enter"
+	keyQuit  = "quit"
+)
+
+// decodeKey translates the bytes of one key press read from the terminal
+func decodeKey(b []byte) string {
+	switch s := string(b); s {
+	case "\x1b[A", "k":
+		return keyUp
+	case "\x1b[B", "j":
+		return keyDown
+	case "\x1b[D", "h", "\x7f", "\b":
+		return keyLeft
+	case "\x1b[C", "l":
+		return keyRight
+	case "\r", "\n":
+		return keyEnter
+	case "q", "\x03", "\x1b":
+		return keyQuit
+	default:
+		return s
+	}
+}
+
+// handle applies one key press and reports whether the explorer is done
+func (e *explorer) handle(key string, height int) bool {
+	if e.langMode {
+		switch key {
+		case keyUp:
+			e.langCursor = max(e.langCursor-1, 0)
+		case keyDown:
+			e.langCursor = max(min(e.langCursor+1, len(e.languages)-1), 0)
+		case " ", keyEnter:
+			if len(e.languages) > 0 {
+				name := e.languages[e.langCursor]
+				e.disabled[name] = !e.disabled[name]
+			}
+		case "L", keyLeft:
+			e.langMode = false
+			e.cursor, e.offset = 0, 0
+		case keyQuit:
+			return true
+		}
+		return false
+	}
+
+	rows := e.rows()
+	// Turning languages off may have removed rows
+	e.cursor = max(min(e.cursor, len(rows)-1), 0)
+	switch key {
+	case keyUp:
+		e.cursor = max(e.cursor-1, 0)
+	case keyDown:
+		e.cursor = max(min(e.cursor+1, len(rows)-1), 0)
+	case keyRight, keyEnter:
+		if e.cursor < len(rows) && rows[e.cursor].dir != nil {
+			e.cwd = rows[e.cursor].dir
+			e.cursor, e.offset = 0, 0
+		}
+	case keyLeft:
+		if e.cwd.parent != nil {
+			name := e.cwd.name + "/"
+			e.cwd = e.cwd.parent
+			e.cursor, e.offset = 0, 0
+			// Keep the directory just left selected
+			for i, row := range e.rows() {
+				if row.name == name {
+					e.cursor = i
+				}
+			}
+		}
+	case "s":
+		e.sortBy = (e.sortBy + 1) % len(explorerSorts)
+	case "r":
+		e.reverse = !e.reverse
+	case "f":
+		e.flat = !e.flat
+		e.cursor, e.offset = 0, 0
+	case "L":
+		e.langMode = true
+	case keyQuit:
+		return true
+	}
+
+	// Scroll the cursor into view
+	visible := max(height-explorerChrome, 1)
+	if e.cursor < e.offset {
+		e.offset = e.cursor
+	} else if e.cursor >= e.offset+visible {
+		e.offset = e.cursor - visible + 1
+	}
+	return false
+}
+
+// explorerChrome is the number of lines around the rows of the explorer
+const explorerChrome = 7
+
+// render draws the explorer on a terminal of width and height. Lines end
+// in "\r\n" as the terminal is in raw mode.
+func (e *explorer) render(w io.Writer, width, height int) {
+	var lines []string
+	add := func(format string, args ...any) {
+		line := fmt.Sprintf(format, args...)
+		if len(line) > width {
+			line = line[:width]
+		}
+		lines = append(lines, line)
+	}
+
+	where := e.label
+	if p := e.cwd.path(); p != "" {
+		where = filepath.Join(e.label, filepath.FromSlash(p))
+	}
+	header := "Lines of code in " + where
+	if e.partial {
+		header += " (partial)"
+	}
+	add("%s", header)
+
+	if e.langMode {
+		add("Languages (space: toggle, L: back, q: quit)")
+		add("%s", strings.Repeat("-", min(width, 70)))
+		for i, name := range e.languages {
+			mark := "x"
+			if e.disabled[name] {
+				mark = " "
+			}
+			cursor := " "
+			if i == e.langCursor {
+				cursor = ">"
+			}
+			add("%s [%s] %s", cursor, mark, name)
+		}
+		fmt.Fprint(w, "\033[H\033[2J"+strings.Join(lines, "\r\n"))
+		return
+	}
+
+	order := explorerSorts[e.sortBy]
+	if e.reverse {
+		order += ", reversed"
+	}
+	view := "directories"
+	if e.flat {
+		view = "all files"
+	}
+	add("Sort: %s, view: %s", order, view)
+	add("%s", strings.Repeat("-", min(width, 70)))
+	add("  %-8s %-10s %-10s %-10s %s", "Files", "Code", "Comments", "Total", "Name")
+	add("%s", strings.Repeat("-", min(width, 70)))
+
+	rows := e.rows()
+	visible := max(height-explorerChrome, 1)
+	for i := e.offset; i < len(rows) && i < e.offset+visible; i++ {
+		cursor := " "
+		if i == e.cursor {
+			cursor = ">"
+		}
+		row := rows[i]
+		add("%s %-8d %-10d %-10d %-10d %s", cursor, row.files, row.stats.CodeLines, row.stats.CommentLines, row.stats.TotalLines, row.name)
+	}
+
+	files, total := e.total(e.cwd)
+	add("%s", strings.Repeat("-", min(width, 70)))
+	add("  %-8d %-10d %-10d %-10d %s", files, total.CodeLines, total.CommentLines, total.TotalLines, "TOTAL")
+	add("arrows/hjkl: move, enter: open, s: sort, r: reverse, f: all files, L: languages, q: quit")
+	fmt.Fprint(w, "\033[H\033[2J"+strings.Join(lines, "\r\n"))
+}
+
+// runInteractive counts root and lets the user explore the results in a
+// terminal UI until they quit
+func runInteractive(ctx context.Context, root string, opts Options) error {
+	// Fail before counting, as makeRaw would
+	if runtime.GOOS == "windows" {
+		return errors.New("--interactive is not supported on Windows")
+	}
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		return errors.New("--interactive needs a terminal")
+	}
+	if opts.Output != "" {
+		return errors.New("--interactive cannot be combined with --output")
+	}
+
+	stats, err := countStats(ctx, root, opts)
+	opts.progress.clear()
+	if err != nil && (stats == nil || !stats.Partial) {
+		return countError(err, opts)
+	}
+
+	restore, err := makeRaw(os.Stdin)
+	if err != nil {
+		return err
+	}
+	// Switch to the alternate screen and hide the cursor while exploring
+	fmt.Fprint(os.Stdout, "\033[?1049h\033[?25l")
+	defer func() {
+		fmt.Fprint(os.Stdout, "\033[?25h\033[?1049l")
+		restore()
+	}()
+
+	label := root
+	if opts.FilesFrom != "" {
+		label = fileListLabel(opts.FilesFrom)
+	}
+	e := newExplorer(label, root, stats)
+	buf := make([]byte, 16)
+	for {
+		height, width := terminalSize(os.Stdin)
+		e.render(os.Stdout, width, height)
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return err
+		}
+		if e.handle(decodeKey(buf[:n]), height) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/a2hop/line-counter/counter"
+)
+
+// junitClassname is the classname of the testcases of --format junit
+const junitClassname = "line-counter"
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name       string          `xml:"name,attr"`
+	Classname  string          `xml:"classname,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Failure    *junitFailure   `xml:"failure"`
+	SystemOut  string          `xml:"system-out"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnitFormatter writes a JUnit XML report with a testcase per language
+// and one for the TOTAL, carrying the line counts, so CI servers show them
+// with the test results. The limits a language or the project exceeds fail
+// its testcase.
+type JUnitFormatter struct {
+	Opts Options
+}
+
+func (f JUnitFormatter) Format(w io.Writer, stats *counter.ProjectStats) error {
+	violations, err := limitViolations(stats, f.Opts)
+	if err != nil {
+		return err
+	}
+	byCase := make(map[string][]string)
+	for _, violation := range violations {
+		byCase[violation.language] = append(byCase[violation.language], violation.message)
+	}
+
+	languages := byLanguage(stats)
+	names := make([]string, 0, len(languages.StatsByExt))
+	for name := range languages.StatsByExt {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	suite := junitTestSuite{Name: "line counts"}
+	addCase := func(name string, files int, s counter.FileStats, messages []string) {
+		testCase := junitTestCase{
+			Name:      name,
+			Classname: junitClassname,
+			Properties: []junitProperty{
+				{"files", strconv.Itoa(files)},
+				{"total_lines", strconv.Itoa(s.TotalLines)},
+				{"code_lines", strconv.Itoa(s.CodeLines)},
+				{"comment_lines", strconv.Itoa(s.CommentLines)},
+				{"blank_lines", strconv.Itoa(s.BlankLines)},
+			},
+			SystemOut: fmt.Sprintf("%d files, %d lines: %d code, %d comments, %d blank",
+				files, s.TotalLines, s.CodeLines, s.CommentLines, s.BlankLines),
+		}
+		if len(messages) > 0 {
+			message := messages[0]
+			if len(messages) > 1 {
+				message = fmt.Sprintf("%d limits exceeded", len(messages))
+			}
+			testCase.Failure = &junitFailure{Message: message, Type: "limit", Text: strings.Join(messages, "\n")}
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, testCase)
+		suite.Tests++
+	}
+	for _, name := range names {
+		addCase(name, languages.FilesByExt[name], languages.StatsByExt[name], byCase[name])
+	}
+	addCase("TOTAL", stats.TotalFiles, stats.TotalStats, byCase[""])
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitTestSuites{Name: junitClassname, Tests: suite.Tests, Failures: suite.Failures, Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w)
+	return err
+}
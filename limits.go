@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/a2hop/line-counter/counter"
+)
+
+// limitViolation is a limit exceeded by the whole project, whose language
+// is "", or by a language or one of its files
+type limitViolation struct {
+	language string
+	message  string
+}
+
+// checkLimits lists the files and languages exceeding the --max-total-lines,
+// --max-file-lines and --fail-on-growth limits on w, and fails if there
+// are any
+func checkLimits(w io.Writer, stats *counter.ProjectStats, opts Options) error {
+	violations, err := limitViolations(stats, opts)
+	if err != nil {
+		return err
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	// The limits go to stderr, which may be colored unlike the report
+	colors, _ := newPalette(opts.Color, w)
+	fmt.Fprintln(w, colors.paint("error", "Limits exceeded:"))
+	for _, violation := range violations {
+		fmt.Fprintf(w, "  %s\n", colors.paint("error", violation.message))
+	}
+	return fmt.Errorf("%d limit(s) exceeded", len(violations))
+}
+
+// limitViolations returns the violations of the --max-total-lines,
+// --max-file-lines and --fail-on-growth limits, in that order
+func limitViolations(stats *counter.ProjectStats, opts Options) ([]limitViolation, error) {
+	var violations []limitViolation
+
+	if opts.MaxTotalLines > 0 && stats.TotalStats.TotalLines > opts.MaxTotalLines {
+		violations = append(violations, limitViolation{message: fmt.Sprintf("total: %d lines exceed --max-total-lines %d",
+			stats.TotalStats.TotalLines, opts.MaxTotalLines)})
+	}
+
+	if opts.MaxFileLines > 0 {
+		var large []counter.FileResult
+		for _, file := range stats.Files {
+			if file.Stats.TotalLines > opts.MaxFileLines {
+				large = append(large, file)
+			}
+		}
+		sort.SliceStable(large, func(i, j int) bool {
+			return large[i].Stats.TotalLines > large[j].Stats.TotalLines
+		})
+		for _, file := range large {
+			violations = append(violations, limitViolation{
+				language: counter.GroupName(file.Language),
+				message: fmt.Sprintf("%s: %d lines exceed --max-file-lines %d",
+					file.Path, file.Stats.TotalLines, opts.MaxFileLines),
+			})
+		}
+	}
+
+	if opts.FailOnGrowth != "" {
+		baseline, err := LoadBaseline(opts.FailOnGrowth, opts.Logger)
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, growthViolations(baseline.Stats, stats, opts.FailOnGrowth)...)
+	}
+	return violations, nil
+}
+
+// growthViolations describes every language whose code lines grew from old
+// to cur, the baseline saved at path
+func growthViolations(old, cur *counter.ProjectStats, path string) []limitViolation {
+	oldLanguages, curLanguages := byLanguage(old), byLanguage(cur)
+	var names []string
+	for name := range curLanguages.StatsByExt {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var violations []limitViolation
+	for _, name := range names {
+		growth := curLanguages.StatsByExt[name].CodeLines - oldLanguages.StatsByExt[name].CodeLines
+		if growth > 0 {
+			violations = append(violations, limitViolation{
+				language: name,
+				message:  fmt.Sprintf("%s: code lines grew by %d since %s", name, growth, path),
+			})
+		}
+	}
+	return violations
+}
@@ -2,273 +2,924 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
-	"path/filepath"
-	"sort"
+	"os/signal"
+	"runtime"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/a2hop/line-counter/counter"
 )
 
-// CodeExtensions defines file extensions to consider as code files
-var CodeExtensions = map[string]bool{
-	".go":    true,
-	".js":    true,
-	".ts":    true,
-	".jsx":   true,
-	".tsx":   true,
-	".java":  true,
-	".c":     true,
-	".cpp":   true,
-	".cc":    true,
-	".h":     true,
-	".hpp":   true,
-	".cs":    true,
-	".php":   true,
-	".rb":    true,
-	".py":    true,
-	".rs":    true,
-	".swift": true,
-	".kt":    true,
-	".scala": true,
-	".sql":   true,
-	".html":  true,
-	".css":   true,
-	".scss":  true,
-	".json":  true,
-	".yaml":  true,
-	".yml":   true,
-	".toml":  true,
-	".xml":   true,
-	".sh":    true,
-	".bash":  true,
+// Options holds the command-line settings for a run. The embedded
+// counter.Options control what is counted; the rest control the report.
+type Options struct {
+	counter.Options
+
+	LineLengthStats bool
+	Stats           bool
+	LargeFile       int
+	ByFile          bool
+	ByDir           int
+	Top             int
+	TopFiles        int
+	Pprof           string
+	Config          string
+	FilesFrom       string
+	Ext             string
+	Output          string
+	Append          bool
+	MinFiles        int
+	MinLines        int
+	ShowAll         bool
+	Embed           bool
+	EmbedPackage    string
+	Template        string
+	Format          string
+	Measurement     string
+	Watch           bool
+	Reports         []string
+	ByRoot          bool
+	ByOwner         bool
+	NestedGitRoots  bool
+	Roots           []string
+	Revs            []string
+	Quiet           bool
+	HistoryDB       string
+	Budgets         string
+	Step            string
+	Last            int
+	Sort            string
+	CostPerMonth    float64
+	ListDuplicates  bool
+	ListErrors      bool
+	SummaryOnly     bool
+	Percentages     bool
+	Since           string
+	NoRecursive     bool
+	Verbose         bool
+	VeryVerbose     bool
+	LogFormat       string
+	Color           string
+	Interactive     bool
+	FilesNDJSON     string
+	Tree            bool
+	TreeDepth       int
+	Columns         string
+	GroupBy         string
+	PromTextfile    string
+	Listen          string
+	ServePath       string
+	Interval        time.Duration
+	Timeout         time.Duration
+
+	// progress shows the progress of the count on stderr, if set
+	progress *progressLine
+	// remote is the repository URL that was cloned into the counted
+	// directory, if any
+	remote string
+	// colors colors the text output, if set
+	colors *palette
+	// codeowners assigns the counted files to owners with --by-owner
+	codeowners *counter.Codeowners
+
+	SaveBaseline      string
+	CompareBaseline   string
+	FailIfCodeGrowsBy int
+
+	MaxTotalLines int
+	MaxFileLines  int
+	FailOnGrowth  string
 }
 
-// IgnoreDirs defines directories to skip
-var IgnoreDirs = map[string]bool{
-	".git":         true,
-	".svn":         true,
-	"node_modules": true,
-	"vendor":       true,
-	"build":        true,
-	"dist":         true,
-	"target":       true,
-	"bin":          true,
-	"obj":          true,
-	".idea":        true,
-	".vscode":      true,
-	"coverage":     true,
-	".next":        true,
-	"__pycache__":  true,
-}
+// reportSections are the section names accepted by --report
+var reportSections = map[string]bool{"effort": true, "generated": true}
 
-// FileStats holds statistics for a single file
-type FileStats struct {
-	TotalLines   int
-	CodeLines    int
-	BlankLines   int
-	CommentLines int
+// hasReport reports whether --report asked for the named section
+func (o Options) hasReport(name string) bool {
+	for _, report := range o.Reports {
+		if report == name {
+			return true
+		}
+	}
+	return false
 }
 
-// ProjectStats holds statistics for the entire project
-type ProjectStats struct {
-	FilesByExt map[string]int
-	StatsByExt map[string]FileStats
-	TotalStats FileStats
-	TotalFiles int
+// needFiles reports whether the run reports on individual files, so
+// ProjectStats.Files must be filled in
+func (o Options) needFiles() bool {
+	return o.ByFile || o.ByDir > 0 || o.Tree || o.Interactive || o.TopFiles > 0 || o.ByOwner || o.MaxFileLines > 0 || o.CheckLineEndings || o.Format == "json" || o.Format == "sarif" || o.Format == "codeclimate"
 }
 
 func main() {
-	var projectPath string
-	if len(os.Args) > 1 {
-		projectPath = os.Args[1]
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() (err error) {
+	var opts Options
+	flag.BoolVar(&opts.LineLengthStats, "line-length-stats", false, "show min/max/average line length per extension")
+	flag.BoolVar(&opts.Complexity, "complexity", false, "estimate cyclomatic complexity per language by counting branch keywords")
+	flag.BoolVar(&opts.Stats, "stats", false, "show the average, median and maximum lines per file and the number of large files")
+	flag.IntVar(&opts.LargeFile, "large-file", defaultLargeFile, "with --stats, count the files with more than `N` lines as large")
+	flag.BoolVar(&opts.ByFile, "by-file", false, "list every counted file, largest code count first")
+	flag.BoolVar(&opts.Tree, "tree", false, "print the directory tree with the code lines of each directory and a bar of its share")
+	flag.IntVar(&opts.TreeDepth, "tree-depth", defaultTreeDepth, "with --tree, show `N` levels of directories (0 for all)")
+	flag.Var((*depthFlag)(&opts.ByDir), "by-dir", "break down the results per directory, `N` levels deep (--by-dir for 1, --by-dir=N; the = is required)")
+	flag.Var((*stringList)(&opts.Revs), "rev", "count the files of git `revision` from the object database instead of the working tree; repeat to print the change between revisions")
+	flag.BoolVar(&opts.ByRoot, "by-root", false, "when counting several paths, break down the results per path")
+	flag.IntVar(&opts.Top, "top", 0, "with --by-file, only list the `N` largest files")
+	flag.IntVar(&opts.TopFiles, "top-files", 0, "list the `N` files with the most code lines and the N with the longest lines, per language")
+	flag.StringVar(&opts.Pprof, "pprof", defaultPprofMode, "write a cpu or mem profile after the scan")
+	flag.StringVar(&opts.IgnorePathsFrom, "ignore-paths-from", "", "read gitignore-style patterns of paths to skip from `file`")
+	flag.StringVar(&opts.FilesFrom, "files-from", "", "count the newline-separated paths listed in `file` (- for stdin) instead of walking a tree")
+	flag.IntVar(&opts.MaxDepth, "max-depth", 0, "only count files at most `N` directory levels below the path; 1 is the path itself (0: no limit)")
+	flag.BoolVar(&opts.NoRecursive, "no-recursive", false, "only count the files directly in the path, like --max-depth 1")
+	flag.BoolVar(&opts.GitTracked, "git-tracked", false, "only count files tracked by git")
+	flag.StringVar(&opts.Since, "since", "", "only count files changed since the git `ref`, including uncommitted changes, and report the lines added and removed")
+	flag.StringVar(&opts.DiffRange, "diff-range", "", "only count files changed in the git `range` A..B and report the lines added and removed")
+	flag.BoolVar(&opts.Git, "git", false, "list files with git ls-files instead of walking the filesystem")
+	flag.BoolVar(&opts.NoGitignore, "no-gitignore", false, "count files even if .gitignore files exclude them")
+	flag.BoolVar(&opts.FollowSymlinks, "follow-symlinks", false, "walk into symlinked directories, skipping link cycles")
+	flag.BoolVar(&opts.NoSymlinks, "no-symlinks", false, "skip symlinked files and directories")
+	flag.BoolVar(&opts.AllText, "all-text", false, "also count the text files that are not code by name or shebang, under Other")
+	flag.BoolVar(&opts.Markdown, "markdown", false, "also count Markdown files, the prose as comments and code blocks under their language")
+	flag.BoolVar(&opts.IncludeGenerated, "include-generated", false, "count minified files and files marked @generated or DO NOT EDIT like any other")
+	flag.Var((*stringList)(&opts.GeneratedMarkers), "generated-marker", "also treat files with `text` in their first lines as generated (repeatable)")
+	flag.Var((*stringList)(&opts.GeneratedNames), "generated-name", "also treat files whose name matches the glob `pattern` as generated (repeatable)")
+	flag.Var((*stringList)(&opts.Exclude), "exclude", "skip paths matching the doublestar glob `pattern` (repeatable)")
+	flag.Var((*stringList)(&opts.Include), "include", "only count files matching the doublestar glob `pattern` (repeatable)")
+	flag.BoolVar(&opts.NoHeuristics, "no-heuristics", false, "classify files by extension alone, e.g. every .h file as a C header")
+	flag.BoolVar(&opts.Unique, "unique", false, "count files with identical content, such as vendored copies and hard links, once")
+	flag.BoolVar(&opts.ListDuplicates, "list-duplicates", false, "with --unique, list each counted file that has copies along with the copies")
+	flag.StringVar(&opts.Tests, "tests", "include", "count test files like any other (include), in a separate section (separate) or not at all (exclude)")
+	flag.BoolVar(&opts.SkipNestedGit, "skip-nested-git", false, "skip directories holding a nested git repository, such as submodules and vendored repositories")
+	flag.BoolVar(&opts.NestedGitRoots, "nested-git-roots", false, "count nested git repositories separately from the totals and list each with its totals")
+	flag.BoolVar(&opts.SkipTestDirs, "skip-test-dirs", false, "skip test directories such as test, tests, spec and testdata")
+	flag.StringVar(&opts.Profile, "profile", "", "only count the extensions of a preset profile: config")
+	flag.StringVar(&opts.Config, "config", "", "read extensions, languages and ignored directories from `file` (default: .linecounter.yaml in the project root)")
+	flag.StringVar(&opts.Ext, "ext", "", "extension used to classify comments when reading stdin, e.g. .go")
+	flag.StringVar(&opts.Output, "output", "", "write results to `path` instead of stdout")
+	flag.BoolVar(&opts.Append, "append", false, "append to the --output file instead of overwriting it")
+	flag.StringVar(&opts.Sort, "sort", "name", "order the breakdown by `field`: name, files, total, code or comments (comment ratio), with an optional :asc or :desc")
+	flag.StringVar(&opts.GroupBy, "group-by", groupByLanguage, "report the breakdown per language, rolling up related extensions such as .ts and .tsx, or per extension")
+	flag.StringVar(&opts.Columns, "columns", "", "comma-separated `list` of the language table's columns: language (or ext), files, total, code, comments, blank, mixed, share and density")
+	flag.BoolVar(&opts.Percentages, "percentages", false, "add each language's share of the code lines and its comment density to the breakdown")
+	flag.IntVar(&opts.MinFiles, "min-files", 0, "hide extensions with fewer than `N` files from the breakdown")
+	flag.IntVar(&opts.FileMinLines, "file-min-lines", 0, "only count files with at least `N` lines")
+	flag.IntVar(&opts.FileMaxLines, "file-max-lines", 0, "only count files with at most `N` lines")
+	flag.IntVar(&opts.MinLines, "min-lines", 0, "hide extensions with fewer than `N` total lines from the breakdown")
+	flag.BoolVar(&opts.ShowAll, "show-all", false, "show every extension, ignoring --min-files and --min-lines")
+	flag.BoolVar(&opts.Embed, "embed", false, "write the results as a Go source file for go generate")
+	flag.StringVar(&opts.EmbedPackage, "embed-package", "main", "package name used by --embed")
+	flag.StringVar(&opts.Template, "template", "", "render the results with a text/template `file`, or builtin:markdown / builtin:html")
+	flag.StringVar(&opts.Format, "format", "text", "output format: text, json, csv, markdown, html, sbom, influxdb, sarif, codeclimate, junit or ndjson")
+	flag.BoolVar(&opts.SummaryOnly, "summary-only", false, "print only the totals as one line, e.g. files=12 lines=340 code=300 comments=25 blank=15")
+	flag.StringVar(&opts.PromTextfile, "prom-textfile", "", "also write the counts in Prometheus text format to `file`, e.g. for node_exporter's textfile collector")
+	flag.StringVar(&opts.Listen, "listen", defaultListen, "`address` serve listens on")
+	flag.StringVar(&opts.ServePath, "path", ".", "`dir` counted by serve when no path argument is given")
+	flag.DurationVar(&opts.Interval, "interval", 10*time.Minute, "how often serve recounts")
+	flag.StringVar(&opts.Measurement, "measurement", "line_counter", "measurement name used by --format influxdb")
+	flag.Var((*stringList)(&opts.Reports), "report", "add an extra report `section` to the text output: effort or generated (repeatable)")
+	flag.Float64Var(&opts.CostPerMonth, "cost-per-month", defaultCostPerMonth, "cost of one person-month used by --report effort, in dollars")
+	flag.IntVar(&opts.RandomSample, "random-sample", 0, "estimate totals by counting `N` randomly chosen files")
+	flag.StringVar(&opts.Cache, "cache", "", "keep the counts of files in `dir` between runs and only re-read files whose size or modification time changed")
+	flag.IntVar(&opts.Jobs, "jobs", runtime.NumCPU(), "number of files to count in parallel")
+	flag.DurationVar(&opts.Timeout, "timeout", 0, "stop counting after `duration`, e.g. 30s, and print the partial results")
+	flag.BoolVar(&opts.Verbose, "v", false, "also log skipped files and directories and why they were skipped")
+	flag.BoolVar(&opts.VeryVerbose, "vv", false, "like -v, and also log how the language of each file was detected")
+	flag.BoolVar(&opts.Strict, "strict", false, "stop with an error at the first file or directory that cannot be read instead of skipping it")
+	flag.BoolVar(&opts.ListErrors, "list-errors", false, "list the paths that could not be read, and why, in the errors section")
+	flag.StringVar(&opts.LogFormat, "log-format", "text", "format of warnings and -v logs on stderr: text or json")
+	flag.StringVar(&opts.Color, "color", colorAuto, "color the text output: auto (when stdout is a terminal and NO_COLOR is unset), always or never")
+	flag.BoolVar(&opts.Quiet, "quiet", false, "do not show progress on stderr while counting")
+	flag.StringVar(&opts.Budgets, "budgets", "", "budget `file` read by check (default: "+counter.BudgetsFile+" in the path)")
+	flag.StringVar(&opts.HistoryDB, "db", defaultHistoryDB, "history `file` appended to by record and read by history")
+	flag.StringVar(&opts.Step, "step", stepMonthly, "interval between the commits counted by trend: daily, weekly or monthly")
+	flag.IntVar(&opts.Last, "last", 12, "number of intervals counted by trend")
+	flag.IntVar(&opts.MaxLineBytes, "max-line-bytes", counter.DefaultMaxLineBytes, "classify lines longer than `N` bytes by their first N bytes")
+	flag.StringVar(&opts.FilesNDJSON, "files-ndjson", "", "write the result of each file to `path` as a line of JSON while counting, without keeping them in memory")
+	flag.BoolVar(&opts.Interactive, "interactive", false, "explore the results in a terminal UI: drill into directories, sort, toggle languages and list files (needs stty, not available on Windows)")
+	flag.BoolVar(&opts.Watch, "watch", false, "rescan and print updated results whenever code files change")
+	flag.BoolVar(&opts.HCLDetail, "hcl-detail", false, "count Terraform resource and data blocks")
+	flag.BoolVar(&opts.GoGenerics, "go-generics", false, "count Go lines declaring type parameters")
+	flag.BoolVar(&opts.ByAuthor, "by-author", false, "attribute lines to authors with git blame")
+	flag.BoolVar(&opts.ByOwner, "by-owner", false, "print the code lines of each owner of the CODEOWNERS file of the path")
+	flag.StringVar(&opts.EmbeddedAs, "embedded-as", "language", "count the code blocks of Markdown files and the blocks of Vue and Svelte components under their own language or the container's")
+	flag.StringVar(&opts.MetaAs, "meta-as", "syntax", "count shebang lines and Markdown front matter by the file's syntax (syntax), as code, as comments or only as meta lines (meta)")
+	flag.StringVar(&opts.MixedAs, "mixed-as", "code", "count lines with code and a comment as code, comment or mixed")
+	flag.BoolVar(&opts.LicenseHeaders, "license-headers", false, "report the comment lines of license and copyright headers at the top of files")
+	flag.BoolVar(&opts.StyleMetrics, "style-metrics", false, "count long lines, lines with trailing whitespace and lines indented with both tabs and spaces")
+	flag.IntVar(&opts.LongLine, "long-line", counter.DefaultLongLine, "with --style-metrics, count lines longer than `N` characters as long")
+	flag.BoolVar(&opts.ModelicaDetail, "modelica-detail", false, "count Modelica annotation(...) lines separately from code")
+	flag.BoolVar(&opts.CheckLineEndings, "check-line-endings", false, "list files mixing LF and CRLF line endings")
+	flag.StringVar(&opts.LineEnding, "line-ending", "any", "only count files whose line endings are lf, crlf or any")
+	flag.StringVar(&opts.SaveBaseline, "save-baseline", "", "save the results as a JSON baseline to `path`")
+	flag.StringVar(&opts.CompareBaseline, "compare-baseline", "", "print the change since the baseline saved at `path`")
+	flag.IntVar(&opts.FailIfCodeGrowsBy, "fail-if-code-grows-by", -1, "with --compare-baseline, exit 1 if code lines grew by more than `N`")
+	flag.IntVar(&opts.MaxTotalLines, "max-total-lines", 0, "exit 1 if the project has more than `N` lines")
+	flag.IntVar(&opts.MaxFileLines, "max-file-lines", 0, "exit 1 if any file has more than `N` lines, listing those files")
+	flag.StringVar(&opts.FailOnGrowth, "fail-on-growth", "", "exit 1 if any language has more code lines than in the baseline at `path`")
+	flag.Usage = usage
+	args, err := parseInterspersed(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		return err
+	}
+	// count is the default command and watch a spelling of --watch; the
+	// other subcommands are run once the output is open. A directory named
+	// like a subcommand is counted as ./check or count check.
+	var command string
+	if len(args) > 0 {
+		switch args[0] {
+		case "count":
+			args = args[1:]
+		case "watch":
+			opts.Watch = true
+			args = args[1:]
+		case "completion":
+			return runCompletion(os.Stdout, args[1:])
+		case "merge", "diff", "record", "check", "history", "trend", "serve":
+			command = args[0]
+			args = args[1:]
+		}
+	}
+
+	if opts.NoRecursive {
+		if opts.MaxDepth > 1 {
+			return errors.New("--no-recursive and --max-depth cannot be combined")
+		}
+		opts.MaxDepth = 1
+	}
+	if opts.Since != "" {
+		if opts.DiffRange != "" {
+			return errors.New("--since and --diff-range cannot be combined")
+		}
+		opts.DiffRange = opts.Since
+	}
+	if err := opts.Options.Validate(); err != nil {
+		return err
+	}
+	if _, _, err := parseSort(opts.Sort); err != nil {
+		return err
+	}
+	if opts.GroupBy != groupByLanguage && opts.GroupBy != groupByExtension {
+		return fmt.Errorf("invalid --group-by %q (expected language or extension)", opts.GroupBy)
+	}
+	if _, err := tableColumns(opts); err != nil {
+		return err
+	}
+	if opts.TreeDepth < 0 {
+		return errors.New("--tree-depth must not be negative")
+	}
+	if opts.LargeFile < 0 {
+		return errors.New("--large-file must not be negative")
+	}
+	for _, report := range opts.Reports {
+		if !reportSections[report] {
+			return fmt.Errorf("unknown --report %q (expected effort or generated)", report)
+		}
+	}
+	if len(opts.Revs) > 0 && (opts.Watch || opts.Interactive || opts.FilesFrom != "" || len(args) > 1) {
+		return errors.New("--rev takes a single repository and cannot be combined with --watch, --interactive or --files-from")
+	}
+	if opts.ListDuplicates {
+		opts.Unique = true
+	}
+	if opts.NestedGitRoots {
+		opts.SkipNestedGit = true
+	}
+	opts.CollectFiles = opts.needFiles()
+	if opts.Logger, err = newLogger(opts); err != nil {
+		return err
+	}
+	// Log lines would break up the progress line
+	verbose := opts.Verbose || opts.VeryVerbose
+	if !opts.Quiet && !opts.Watch && !verbose && isTerminal(os.Stderr) {
+		opts.progress = newProgressLine(os.Stderr)
+		opts.Progress = opts.progress.update
+	}
+
+	// Subcommands read the config file of the working directory
+	projectPath := "."
+	if len(args) > 0 && command == "" {
+		projectPath = args[0]
+	}
+
+	// The first Ctrl-C stops the count and prints the partial results; a
+	// second one kills the process as usual
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	if command == "" && len(args) == 1 && isRemoteRepo(projectPath) {
+		if opts.Watch {
+			return fmt.Errorf("--watch cannot watch a remote repository")
+		}
+		dir, cleanup, err := cloneRemote(ctx, projectPath)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		opts.remote = projectPath
+		projectPath = dir
+	}
+
+	if err := applyConfig(&opts, projectPath); err != nil {
+		return err
+	}
+	if opts.ByOwner && command == "" {
+		path := counter.FindCodeowners(projectPath)
+		if path == "" {
+			return fmt.Errorf("--by-owner: no CODEOWNERS file in %s (looked for %s)", projectPath, strings.Join(counter.CodeownersFiles, ", "))
+		}
+		owners, err := counter.LoadCodeowners(path)
+		if err != nil {
+			return fmt.Errorf("--by-owner: %v", err)
+		}
+		opts.codeowners = owners
+	}
+
+	stopProfile, err := startProfile(opts.Pprof)
+	if err != nil {
+		return err
+	}
+	// The profile is written on the error returns below as well
+	defer func() {
+		if profileErr := stopProfile(); err == nil && profileErr != nil {
+			err = fmt.Errorf("could not write profile: %v", profileErr)
+		}
+	}()
+
+	out, closeOutput, err := openOutput(opts)
+	if err != nil {
+		return err
+	}
+	if opts.colors, err = newPalette(opts.Color, out); err != nil {
+		closeOutput()
+		return err
+	}
+	closeFileStream, err := openFileStream(&opts)
+	if err != nil {
+		closeOutput()
+		return err
+	}
+	if opts.usesNDJSON() {
+		streamNDJSON(&opts, out)
+	}
+
+	if command == "merge" {
+		err = runMerge(out, args, opts)
+	} else if command == "diff" {
+		err = runDiff(ctx, out, args, opts)
+	} else if command == "record" {
+		err = runRecord(ctx, out, args, opts)
+	} else if command == "check" {
+		err = runCheck(ctx, out, args, opts)
+	} else if command == "history" {
+		err = runHistory(out, args, opts)
+	} else if command == "trend" {
+		err = runTrend(ctx, out, args, opts)
+	} else if command == "serve" {
+		err = runServe(ctx, args, opts)
+	} else if opts.Interactive {
+		if opts.Watch || len(args) > 1 {
+			err = fmt.Errorf("--interactive takes a single path and cannot be combined with --watch")
+		} else if opts.FilesFrom != "" {
+			err = runInteractive(ctx, ".", opts)
+		} else {
+			err = runInteractive(ctx, projectPath, opts)
+		}
+	} else if opts.FilesFrom != "" {
+		err = reportProject(ctx, out, ".", opts)
+	} else if projectPath == "-" || (len(args) == 0 && stdinIsPipe()) {
+		err = reportStdin(out, opts)
+	} else if len(args) > 0 && !opts.Watch && allFiles(args) {
+		err = reportFiles(out, args, opts)
+	} else if len(args) > 1 {
+		if opts.Watch {
+			err = fmt.Errorf("--watch takes a single path")
+		} else {
+			opts.Roots = args
+			err = reportProject(ctx, out, ".", opts)
+		}
+	} else if opts.Watch {
+		err = Watch(ctx, projectPath, opts, out)
 	} else {
-		projectPath = "."
+		err = reportProject(ctx, out, projectPath, opts)
+	}
+
+	if closeErr := closeOutput(); err == nil {
+		err = closeErr
+	}
+	if streamErr := closeFileStream(); err == nil && streamErr != nil {
+		err = fmt.Errorf("could not write %s: %v", opts.FilesNDJSON, streamErr)
+	}
+	return err
+}
+
+// newLogger returns the logger for warnings and, with -v or -vv, the
+// skipped files and detection decisions, in the --log-format on stderr
+func newLogger(opts Options) (*slog.Logger, error) {
+	level := slog.LevelWarn
+	if opts.VeryVerbose {
+		level = slog.LevelDebug
+	} else if opts.Verbose {
+		level = slog.LevelInfo
+	}
+
+	switch opts.LogFormat {
+	case "text":
+		return slog.New(counter.NewLogHandler(os.Stderr, level)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level})), nil
 	}
+	return nil, fmt.Errorf("invalid --log-format %q (expected text or json)", opts.LogFormat)
+}
 
-	fmt.Printf("Counting lines of code in: %s\n", projectPath)
-	fmt.Println(strings.Repeat("=", 50))
+// applyConfig loads and applies the --config file or, without one, the
+// config file found in root, if any. Its nested_git setting applies unless
+// --skip-nested-git or --nested-git-roots is given.
+func applyConfig(opts *Options, root string) error {
+	path := opts.Config
+	if path == "" {
+		path = counter.FindConfig(root)
+		if path == "" {
+			return nil
+		}
+	}
 
-	stats, err := countProjectLines(projectPath)
+	cfg, err := counter.LoadConfig(path)
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		os.Exit(1)
+		return err
+	}
+	cfg.Apply()
+	if !opts.SkipNestedGit && cfg.NestedGit != "" {
+		opts.SkipNestedGit = true
+		opts.NestedGitRoots = cfg.NestedGit == counter.NestedGitRoots
 	}
+	return nil
+}
 
-	printResults(stats)
+// reportStdin counts the single file read from stdin and prints its stats
+func reportStdin(w io.Writer, opts Options) error {
+	c, err := counter.New(opts.Options)
+	if err != nil {
+		return err
+	}
+	fileStats, err := c.CountReader(os.Stdin, opts.Ext)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "Counting lines of code in: stdin")
+	fmt.Fprintln(w, strings.Repeat("=", 50))
+	printFileDetail(w, fileStats, opts)
+	return nil
 }
 
-func countProjectLines(rootPath string) (*ProjectStats, error) {
-	stats := &ProjectStats{
-		FilesByExt: make(map[string]int),
-		StatsByExt: make(map[string]FileStats),
+// printFileDetail prints the stats of a single file, including the
+// measurements turned on by opts
+func printFileDetail(w io.Writer, fileStats counter.FileStats, opts Options) {
+	printFileStats(w, fileStats, opts)
+	if opts.Complexity {
+		fmt.Fprintf(w, "Complexity: %d\n", fileStats.Complexity)
+	}
+	if opts.GoGenerics {
+		fmt.Fprintf(w, "Generic Lines (Go): %d\n", fileStats.GenericLines)
+	}
+	if opts.ModelicaDetail {
+		fmt.Fprintf(w, "Annotation Lines (Modelica): %d\n", fileStats.AnnotationLines)
 	}
+	if opts.LicenseHeaders {
+		fmt.Fprintf(w, "License Header Lines: %d\n", fileStats.LicenseHeaderLines)
+	}
+	if opts.MetaAs != counter.MetaAsSyntax {
+		fmt.Fprintf(w, "Meta Lines (shebangs, front matter): %d\n", fileStats.MetaLines)
+	}
+}
 
-	err := filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
+// allFiles reports whether every one of paths is a file rather than a
+// directory or an archive to count
+func allFiles(paths []string) bool {
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() || counter.IsArchive(path) {
+			return false
+		}
+	}
+	return true
+}
+
+// reportFiles counts the files named on the command line, whatever their
+// extension, and prints the stats of each followed by their total. Formats
+// other than text are given the files as one project.
+func reportFiles(w io.Writer, paths []string, opts Options) error {
+	c, err := counter.New(opts.Options)
+	if err != nil {
+		return err
+	}
+	stats := counter.NewProjectStats()
+	for _, path := range paths {
+		result, err := c.CountFile(path)
 		if err != nil {
 			return err
 		}
+		stats.AddFile(result)
+		if opts.OnFile != nil {
+			opts.OnFile(result)
+		}
+	}
 
-		// Skip directories we want to ignore
-		if info.IsDir() {
-			if shouldIgnoreDir(info.Name()) {
-				return filepath.SkipDir
-			}
-			return nil
+	if opts.Format != "text" || opts.Template != "" || opts.Embed || opts.SummaryOnly {
+		formatter, err := newFormatter(".", opts)
+		if err != nil {
+			return err
+		}
+		return formatter.Format(w, stats)
+	}
+
+	for i, file := range stats.Files {
+		if i > 0 {
+			fmt.Fprintln(w)
 		}
+		fmt.Fprintf(w, "Counting lines of code in: %s\n", file.Path)
+		fmt.Fprintln(w, strings.Repeat("=", 50))
+		fmt.Fprintf(w, "Language: %s\n", counter.LanguageName(file.Language))
+		printFileDetail(w, file.Stats, opts)
+	}
+	if len(stats.Files) > 1 {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "Total of %d files\n", stats.TotalFiles)
+		fmt.Fprintln(w, strings.Repeat("=", 50))
+		printFileDetail(w, stats.TotalStats, opts)
+	}
+	return nil
+}
 
-		// Check if it's a code file
-		ext := strings.ToLower(filepath.Ext(path))
-		if !CodeExtensions[ext] {
-			return nil
+// reportProject counts the tree at projectPath and prints the results in
+// the requested form, then applies any baseline comparison.
+func reportProject(ctx context.Context, w io.Writer, projectPath string, opts Options) error {
+	formatter, err := newFormatter(projectPath, opts)
+	if err != nil {
+		return err
+	}
+
+	var stats *counter.ProjectStats
+	var rootStats []*counter.ProjectStats
+	if len(opts.Revs) > 0 {
+		stats, rootStats, err = countRevs(ctx, projectPath, opts.Revs, opts)
+	} else if len(opts.Roots) > 0 {
+		stats, rootStats, err = countRoots(ctx, opts.Roots, opts)
+	} else {
+		stats, err = countStats(ctx, projectPath, opts)
+		rootStats = []*counter.ProjectStats{stats}
+	}
+	opts.progress.clear()
+	if err != nil && (stats == nil || !stats.Partial) {
+		return countError(err, opts)
+	}
+
+	if err := formatter.Format(w, stats); err != nil {
+		return err
+	}
+	if opts.ByRoot {
+		roots := opts.Roots
+		if len(opts.Revs) > 0 {
+			roots = opts.Revs
+		} else if len(roots) == 0 {
+			roots = []string{projectPath}
+		}
+		printRootBreakdown(w, "Breakdown by path:", roots, rootStats)
+	}
+	if opts.NestedGitRoots && len(stats.NestedRepos) > 0 && !stats.Partial {
+		repos, repoStats, err := countNestedRepos(ctx, stats.NestedRepos, opts)
+		if err != nil {
+			return countError(err, opts)
 		}
+		printRootBreakdown(w, "Nested repositories (not in the totals):", repos, repoStats)
+	}
+	for i := 1; i < len(opts.Revs) && !stats.Partial; i++ {
+		printDelta(w, fmt.Sprintf("Change from %s to %s:", opts.Revs[i-1], opts.Revs[i]), rootStats[i-1], rootStats[i])
+	}
+	if stats.Partial {
+		// Baselines and limits would judge an incomplete count
+		return countError(err, opts)
+	}
 
-		// Count lines in the file
-		fileStats, err := countLinesInFile(path)
+	if opts.SaveBaseline != "" {
+		if err := SaveBaseline(opts.SaveBaseline, stats); err != nil {
+			return err
+		}
+	}
+	if opts.PromTextfile != "" {
+		if err := writePromTextfile(opts.PromTextfile, stats); err != nil {
+			return err
+		}
+	}
+	if opts.CompareBaseline != "" {
+		baseline, err := LoadBaseline(opts.CompareBaseline, opts.Logger)
 		if err != nil {
-			fmt.Printf("Warning: Could not read %s: %v\n", path, err)
-			return nil
+			return err
+		}
+		printDelta(w, "Change since baseline:", baseline.Stats, stats)
+		if err := checkGrowth(baseline.Stats, stats, opts.FailIfCodeGrowsBy); err != nil {
+			return err
 		}
+	}
+	return checkLimits(os.Stderr, stats, opts)
+}
 
-		// Update statistics
-		stats.FilesByExt[ext]++
-		stats.TotalFiles++
+// stringList is a flag.Value collecting the values of a repeatable flag
+type stringList []string
 
-		extStats := stats.StatsByExt[ext]
-		extStats.TotalLines += fileStats.TotalLines
-		extStats.CodeLines += fileStats.CodeLines
-		extStats.BlankLines += fileStats.BlankLines
-		extStats.CommentLines += fileStats.CommentLines
-		stats.StatsByExt[ext] = extStats
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
 
-		stats.TotalStats.TotalLines += fileStats.TotalLines
-		stats.TotalStats.CodeLines += fileStats.CodeLines
-		stats.TotalStats.BlankLines += fileStats.BlankLines
-		stats.TotalStats.CommentLines += fileStats.CommentLines
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
 
-		return nil
-	})
+// countStats counts the files listed by --files-from or, without it, the
+// tree at root
+func countStats(ctx context.Context, root string, opts Options) (*counter.ProjectStats, error) {
+	if opts.FilesFrom == "" {
+		return counter.Count(ctx, root, opts.Options)
+	}
 
-	return stats, err
+	paths, err := readFileList(opts.FilesFrom)
+	if err != nil {
+		return nil, err
+	}
+	c, err := counter.New(opts.Options)
+	if err != nil {
+		return nil, err
+	}
+	return c.CountFiles(ctx, paths)
+}
+
+// countRoots counts each of roots and returns their combined stats along
+// with the stats of each root. If ctx is done, the roots counted so far are
+// returned, marked Partial, along with the error.
+func countRoots(ctx context.Context, roots []string, opts Options) (*counter.ProjectStats, []*counter.ProjectStats, error) {
+	combined := counter.NewProjectStats()
+	var rootStats []*counter.ProjectStats
+	for _, root := range roots {
+		stats, err := counter.Count(ctx, root, opts.Options)
+		if err != nil && (stats == nil || !stats.Partial) {
+			return nil, nil, err
+		}
+		combined.Merge(stats)
+		rootStats = append(rootStats, stats)
+		if err != nil {
+			return combined, rootStats, err
+		}
+	}
+	return combined, rootStats, nil
 }
 
-func shouldIgnoreDir(dirName string) bool {
-	if IgnoreDirs[dirName] {
-		return true
+// countNestedRepos counts each of repos, the nested repositories skipped
+// by a count, and the repositories nested in them in turn, returning them
+// in order along with their stats
+func countNestedRepos(ctx context.Context, repos []string, opts Options) ([]string, []*counter.ProjectStats, error) {
+	var counted []string
+	var repoStats []*counter.ProjectStats
+	repos = append([]string(nil), repos...)
+	for i := 0; i < len(repos); i++ {
+		stats, err := counter.Count(ctx, repos[i], opts.Options)
+		if err != nil {
+			return nil, nil, err
+		}
+		counted = append(counted, repos[i])
+		repoStats = append(repoStats, stats)
+		repos = append(repos, stats.NestedRepos...)
 	}
-	// Only ignore hidden directories if not "." or ".."
-	return dirName != "." && dirName != ".." && strings.HasPrefix(dirName, ".")
+	return counted, repoStats, nil
 }
 
-func countLinesInFile(filePath string) (FileStats, error) {
-	file, err := os.Open(filePath)
+// countRevs counts each of revs of the repository at repoPath and returns
+// the stats of the last one along with the stats of each revision. If ctx
+// is done, the revisions counted so far are returned along with the error
+// and the last one is marked Partial.
+func countRevs(ctx context.Context, repoPath string, revs []string, opts Options) (*counter.ProjectStats, []*counter.ProjectStats, error) {
+	c, err := counter.New(opts.Options)
 	if err != nil {
-		return FileStats{}, err
+		return nil, nil, err
+	}
+	var revStats []*counter.ProjectStats
+	for _, rev := range revs {
+		stats, err := c.CountRev(ctx, repoPath, rev)
+		if err != nil && (stats == nil || !stats.Partial) {
+			return nil, nil, err
+		}
+		revStats = append(revStats, stats)
+		if err != nil {
+			return stats, revStats, err
+		}
 	}
-	defer file.Close()
+	return revStats[len(revStats)-1], revStats, nil
+}
 
-	var stats FileStats
-	scanner := bufio.NewScanner(file)
-	ext := strings.ToLower(filepath.Ext(filePath))
+// countError describes err, returned by a count, for the user: a count
+// stopped by Ctrl-C or --timeout is reported as such
+func countError(err error, opts Options) error {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("timed out after %v", opts.Timeout)
+	case errors.Is(err, context.Canceled):
+		return errors.New("interrupted")
+	}
+	return err
+}
 
-	inBlockComment := false
+// readFileList reads newline-separated paths from the file at path, or
+// from stdin if path is "-". Blank lines are skipped.
+func readFileList(path string) ([]string, error) {
+	r := io.Reader(os.Stdin)
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
 
+	var paths []string
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		stats.TotalLines++
-
-		if line == "" {
-			stats.BlankLines++
-			continue
-		}
-
-		// Improved comment detection with block comment support
-		switch ext {
-		case ".go", ".js", ".ts", ".jsx", ".tsx", ".java", ".c", ".cpp", ".cc", ".h", ".hpp", ".cs", ".php", ".rs", ".swift", ".kt", ".scala", ".css", ".scss", ".sql":
-			if inBlockComment {
-				stats.CommentLines++
-				if strings.Contains(line, "*/") {
-					inBlockComment = false
-				}
-				continue
-			}
-			if strings.HasPrefix(line, "//") || strings.HasPrefix(line, "--") {
-				stats.CommentLines++
-				continue
-			}
-			if strings.HasPrefix(line, "/*") {
-				stats.CommentLines++
-				if !strings.Contains(line, "*/") {
-					inBlockComment = true
-				}
-				continue
-			}
-			if strings.HasPrefix(line, "*") {
-				stats.CommentLines++
-				continue
-			}
-		case ".py", ".sh", ".bash", ".rb", ".yaml", ".yml", ".toml":
-			if strings.HasPrefix(line, "#") {
-				stats.CommentLines++
-				continue
-			}
-		case ".html", ".xml":
-			if inBlockComment {
-				stats.CommentLines++
-				if strings.Contains(line, "-->") {
-					inBlockComment = false
-				}
-				continue
-			}
-			if strings.HasPrefix(line, "<!--") {
-				stats.CommentLines++
-				if !strings.Contains(line, "-->") {
-					inBlockComment = true
-				}
-				continue
-			}
-		default:
-			// fallback: treat as code
-		}
-
-		stats.CodeLines++
-	}
-
-	return stats, scanner.Err()
+		if line := strings.TrimRight(scanner.Text(), "\r"); strings.TrimSpace(line) != "" {
+			paths = append(paths, line)
+		}
+	}
+	return paths, scanner.Err()
+}
+
+// fileListLabel describes the --files-from source in the report header
+func fileListLabel(path string) string {
+	if path == "-" {
+		return "files listed on stdin"
+	}
+	return "files listed in " + path
+}
+
+// depthFlag is a positive integer flag that may be given without a value,
+// meaning 1, e.g. --by-dir or --by-dir=2
+type depthFlag int
+
+func (d *depthFlag) String() string {
+	return strconv.Itoa(int(*d))
+}
+
+func (d *depthFlag) Set(value string) error {
+	switch value {
+	case "true":
+		*d = 1
+		return nil
+	case "false":
+		*d = 0
+		return nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return fmt.Errorf("expected a positive depth, got %q", value)
+	}
+	*d = depthFlag(n)
+	return nil
+}
+
+func (d *depthFlag) IsBoolFlag() bool {
+	return true
 }
 
-func printResults(stats *ProjectStats) {
-	// Print summary
-	fmt.Printf("Total Files: %d\n", stats.TotalFiles)
-	fmt.Printf("Total Lines: %d\n", stats.TotalStats.TotalLines)
-	fmt.Printf("Code Lines: %d\n", stats.TotalStats.CodeLines)
-	fmt.Printf("Comment Lines: %d\n", stats.TotalStats.CommentLines)
-	fmt.Printf("Blank Lines: %d\n", stats.TotalStats.BlankLines)
-	fmt.Println()
-
-	// Print breakdown by file extension
-	fmt.Println("Breakdown by file type:")
-	fmt.Println(strings.Repeat("-", 70))
-	fmt.Printf("%-8s %-8s %-10s %-10s %-12s %-10s\n", "Ext", "Files", "Total", "Code", "Comments", "Blank")
-	fmt.Println(strings.Repeat("-", 70))
-
-	// Sort extensions for consistent output
-	var extensions []string
-	for ext := range stats.FilesByExt {
-		extensions = append(extensions, ext)
-	}
-	sort.Strings(extensions)
-
-	for _, ext := range extensions {
-		fileCount := stats.FilesByExt[ext]
-		extStats := stats.StatsByExt[ext]
-		fmt.Printf("%-8s %-8d %-10d %-10d %-12d %-10d\n",
-			ext, fileCount, extStats.TotalLines, extStats.CodeLines,
-			extStats.CommentLines, extStats.BlankLines)
-	}
-
-	fmt.Println(strings.Repeat("-", 70))
-	fmt.Printf("%-8s %-8d %-10d %-10d %-12d %-10d\n",
-		"TOTAL", stats.TotalFiles, stats.TotalStats.TotalLines,
-		stats.TotalStats.CodeLines, stats.TotalStats.CommentLines,
-		stats.TotalStats.BlankLines)
+// parseInterspersed parses args with fs, allowing flags to appear after
+// positional arguments, and returns the positional arguments in order.
+// Everything after a "--" terminator is treated as positional.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		consumed := len(args) - len(rest)
+		if consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		if consumed > 0 && isBareDepth(fs, args[consumed-1], rest[0]) {
+			return nil, fmt.Errorf("%s takes its depth after an equals sign, e.g. %s=%s", args[consumed-1], args[consumed-1], rest[0])
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// isBareDepth reports whether arg, a flag without a value, is a depthFlag
+// and next, the argument after it, a number meant as its depth rather than
+// a path, as in --by-dir 2. A file or directory named next is a path.
+func isBareDepth(fs *flag.FlagSet, arg, next string) bool {
+	f := fs.Lookup(strings.TrimLeft(arg, "-"))
+	if f == nil {
+		return false
+	}
+	if _, ok := f.Value.(*depthFlag); !ok {
+		return false
+	}
+	if _, err := strconv.Atoi(next); err != nil {
+		return false
+	}
+	_, err := os.Stat(next)
+	return err != nil
+}
+
+// openOutput returns the writer results are printed to, along with a
+// function that closes it once everything has been written.
+func openOutput(opts Options) (io.Writer, func() error, error) {
+	if opts.Output == "" {
+		return os.Stdout, func() error { return nil }, nil
+	}
+
+	mode := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if opts.Append {
+		mode = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	file, err := os.OpenFile(opts.Output, mode, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, file.Close, nil
+}
+
+// openFileStream opens the --files-ndjson file and sets opts.OnFile to
+// write each counted file to it, one JSON object per line. The returned
+// function closes the file and reports the first error writing it.
+func openFileStream(opts *Options) (func() error, error) {
+	if opts.FilesNDJSON == "" {
+		return func() error { return nil }, nil
+	}
+	file, err := os.Create(opts.FilesNDJSON)
+	if err != nil {
+		return nil, err
+	}
+	buf := bufio.NewWriter(file)
+	enc := json.NewEncoder(buf)
+	var writeErr error
+	opts.OnFile = func(result counter.FileResult) {
+		if writeErr == nil {
+			writeErr = enc.Encode(result)
+		}
+	}
+	return func() error {
+		if err := buf.Flush(); writeErr == nil {
+			writeErr = err
+		}
+		if err := file.Close(); writeErr == nil {
+			writeErr = err
+		}
+		return writeErr
+	}, nil
+}
+
+// stdinIsPipe reports whether stdin is redirected from a pipe or file
+func stdinIsPipe() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice == 0
+}
+
+// isTerminal reports whether w is a character device such as a TTY
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
 }
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// TestParseInterspersedByDir checks that a depth given to --by-dir as a
+// separate argument is rejected instead of being counted as a path
+func TestParseInterspersedByDir(t *testing.T) {
+	for _, tc := range []struct {
+		args       []string
+		depth      int
+		positional []string
+		wantErr    bool
+	}{
+		{[]string{"--by-dir", "src"}, 1, []string{"src"}, false},
+		{[]string{"--by-dir=2", "src"}, 2, []string{"src"}, false},
+		{[]string{"src", "--by-dir"}, 1, []string{"src"}, false},
+		{[]string{"--by-dir", "2"}, 0, nil, true},
+		{[]string{"src", "-by-dir", "3"}, 0, nil, true},
+		{[]string{"--", "--by-dir", "2"}, 0, []string{"--by-dir", "2"}, false},
+	} {
+		fs := flag.NewFlagSet("line-counter", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		var depth int
+		fs.Var((*depthFlag)(&depth), "by-dir", "")
+		positional, err := parseInterspersed(fs, tc.args)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%q: err = %v, want error %v", tc.args, err, tc.wantErr)
+			continue
+		}
+		if tc.wantErr {
+			continue
+		}
+		if depth != tc.depth || !reflect.DeepEqual(positional, tc.positional) {
+			t.Errorf("%q: depth %d, positional %q, want %d, %q", tc.args, depth, positional, tc.depth, tc.positional)
+		}
+	}
+}
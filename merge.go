@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/a2hop/line-counter/counter"
+)
+
+// MergeReports loads the baselines saved at paths and combines them into a
+// single ProjectStats, as if all of their trees had been scanned together.
+// Files listed in more than one report, which are then counted more than
+// once, are reported as a warning to logger, or to slog.Default() if it is
+// nil.
+func MergeReports(paths []string, logger *slog.Logger) (*counter.ProjectStats, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	merged := counter.NewProjectStats()
+	// reports lists the reports holding each file path
+	reports := make(map[string][]string)
+	var duplicates []string
+	for _, path := range paths {
+		baseline, err := LoadBaseline(path, logger)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range baseline.Stats.Files {
+			if len(reports[file.Path]) == 1 {
+				duplicates = append(duplicates, file.Path)
+			}
+			reports[file.Path] = append(reports[file.Path], path)
+		}
+		merged.Merge(baseline.Stats)
+	}
+	for _, file := range duplicates {
+		logger.Warn("file is counted in several reports", "path", file, "reports", strings.Join(reports[file], ", "))
+	}
+	return merged, nil
+}
+
+// runMerge implements "line-counter merge report.json...", writing the
+// combined report to w in the baseline format
+func runMerge(w io.Writer, paths []string, opts Options) error {
+	if len(paths) == 0 {
+		return errors.New("merge: no reports given")
+	}
+
+	merged, err := MergeReports(paths, opts.Logger)
+	if err != nil {
+		return err
+	}
+
+	data, err := encodeBaseline(merged)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
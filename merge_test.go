@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/a2hop/line-counter/counter"
+)
+
+// TestMergeReportsRoundTrip saves the counts of two directories as
+// baselines and checks that merging them gives the count of both
+func TestMergeReportsRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"a/main.go":   "package main\n\n// main runs\nfunc main() {}\n",
+		"a/util.py":   "# util\nx = 1\n",
+		"b/lib.go":    "package lib\n\nvar X = 1 // X\n",
+		"b/style.css": "/* style */\nbody {}\n\n",
+	})
+	opts := counter.Options{CollectFiles: true}
+
+	var reports []string
+	reportDir := t.TempDir()
+	for _, name := range []string{"a", "b"} {
+		path := filepath.Join(reportDir, name+".json")
+		if err := SaveBaseline(path, count(t, filepath.Join(dir, name), opts)); err != nil {
+			t.Fatal(err)
+		}
+		reports = append(reports, path)
+	}
+	var log bytes.Buffer
+	merged, err := MergeReports(reports, slog.New(slog.NewTextHandler(&log, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if log.Len() != 0 {
+		t.Errorf("unexpected warnings: %s", log.String())
+	}
+
+	full := count(t, dir, opts)
+	if merged.TotalFiles != full.TotalFiles {
+		t.Errorf("TotalFiles = %d, want %d", merged.TotalFiles, full.TotalFiles)
+	}
+	if merged.TotalStats != full.TotalStats {
+		t.Errorf("TotalStats = %+v, want %+v", merged.TotalStats, full.TotalStats)
+	}
+	if !reflect.DeepEqual(merged.FilesByExt, full.FilesByExt) {
+		t.Errorf("FilesByExt = %v, want %v", merged.FilesByExt, full.FilesByExt)
+	}
+	if !reflect.DeepEqual(merged.StatsByExt, full.StatsByExt) {
+		t.Errorf("StatsByExt = %v, want %v", merged.StatsByExt, full.StatsByExt)
+	}
+}
+
+func TestMergeReportsDuplicateFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{"src/main.go": "package main\n"})
+	report := filepath.Join(dir, "report.json")
+	if err := SaveBaseline(report, count(t, filepath.Join(dir, "src"), counter.Options{CollectFiles: true})); err != nil {
+		t.Fatal(err)
+	}
+
+	var log bytes.Buffer
+	if _, err := MergeReports([]string{report, report}, slog.New(slog.NewTextHandler(&log, nil))); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(log.String(), "file is counted in several reports"); got != 1 {
+		t.Fatalf("got %d duplicate warnings, want 1:\n%s", got, log.String())
+	}
+	if !strings.Contains(log.String(), "main.go") {
+		t.Errorf("warning does not name the file:\n%s", log.String())
+	}
+}
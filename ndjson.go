@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/a2hop/line-counter/counter"
+)
+
+// ndjsonCounts are the counts of a file, a language or the project in
+// --format ndjson
+type ndjsonCounts struct {
+	Files        int `json:"files,omitempty"`
+	TotalLines   int `json:"total_lines"`
+	CodeLines    int `json:"code_lines"`
+	CommentLines int `json:"comment_lines"`
+	BlankLines   int `json:"blank_lines"`
+}
+
+func ndjsonCountsOf(files int, stats counter.FileStats) ndjsonCounts {
+	return ndjsonCounts{
+		Files:        files,
+		TotalLines:   stats.TotalLines,
+		CodeLines:    stats.CodeLines,
+		CommentLines: stats.CommentLines,
+		BlankLines:   stats.BlankLines,
+	}
+}
+
+// ndjsonFile is the event written for each counted file
+type ndjsonFile struct {
+	Type     string `json:"type"`
+	Path     string `json:"path"`
+	Language string `json:"language"`
+	Ext      string `json:"ext"`
+	ndjsonCounts
+}
+
+// ndjsonSummary is the event written once the count is complete
+type ndjsonSummary struct {
+	Type string `json:"type"`
+	ndjsonCounts
+	Languages map[string]ndjsonCounts `json:"languages"`
+	Partial   bool                    `json:"partial,omitempty"`
+}
+
+// usesNDJSON reports whether opts select --format ndjson, which
+// --summary-only, --embed and --template override
+func (o Options) usesNDJSON() bool {
+	return o.Format == "ndjson" && !o.SummaryOnly && !o.Embed && o.Template == ""
+}
+
+// streamNDJSON sets opts.OnFile to also write a "file" event to w for each
+// file as it is counted, one JSON object per line. NDJSONFormatter writes
+// the closing "summary" event.
+func streamNDJSON(opts *Options, w io.Writer) {
+	enc := json.NewEncoder(w)
+	next := opts.OnFile
+	opts.OnFile = func(result counter.FileResult) {
+		if next != nil {
+			next(result)
+		}
+		// A failing output also fails the summary, which reports it
+		enc.Encode(ndjsonFile{
+			Type:         "file",
+			Path:         result.Path,
+			Language:     counter.LanguageName(result.Language),
+			Ext:          result.Ext,
+			ndjsonCounts: ndjsonCountsOf(0, result.Stats),
+		})
+	}
+}
+
+// NDJSONFormatter writes the "summary" event of --format ndjson, with the
+// totals and the counts of each language, after the "file" events
+// streamNDJSON wrote while counting
+type NDJSONFormatter struct{}
+
+func (NDJSONFormatter) Format(w io.Writer, stats *counter.ProjectStats) error {
+	languages := byLanguage(stats)
+	names := make([]string, 0, len(languages.StatsByExt))
+	for name := range languages.StatsByExt {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	summary := ndjsonSummary{
+		Type:         "summary",
+		ndjsonCounts: ndjsonCountsOf(stats.TotalFiles, stats.TotalStats),
+		Languages:    make(map[string]ndjsonCounts, len(names)),
+		Partial:      stats.Partial,
+	}
+	for _, name := range names {
+		summary.Languages[name] = ndjsonCountsOf(languages.FilesByExt[name], languages.StatsByExt[name])
+	}
+	return json.NewEncoder(w).Encode(summary)
+}
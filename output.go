@@ -0,0 +1,894 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/a2hop/line-counter/counter"
+)
+
+func printFileStats(w io.Writer, stats counter.FileStats, opts Options) {
+	count := func(n int) string { return opts.colors.paint("total", strconv.Itoa(n)) }
+	fmt.Fprintf(w, "Total Lines: %s\n", count(stats.TotalLines))
+	fmt.Fprintf(w, "Code Lines: %s\n", count(stats.CodeLines))
+	fmt.Fprintf(w, "Comment Lines: %s\n", count(stats.CommentLines))
+	fmt.Fprintf(w, "Blank Lines: %s\n", count(stats.BlankLines))
+	if opts.MixedAs != "code" {
+		fmt.Fprintf(w, "Mixed Lines: %s\n", count(stats.MixedLines))
+	}
+	if opts.Percentages {
+		fmt.Fprintf(w, "Comment Density: %s\n", commentDensity(stats))
+	}
+}
+
+// commentDensity returns the share of comment lines among the code and
+// comment lines of stats as a percentage
+func commentDensity(stats counter.FileStats) string {
+	return percent(stats.CommentLines, stats.CodeLines+stats.CommentLines)
+}
+
+// printReport prints the header for rootPath followed by the results
+func printReport(w io.Writer, rootPath string, stats *counter.ProjectStats, opts Options) {
+	label := rootPath
+	if opts.FilesFrom != "" {
+		label = fileListLabel(opts.FilesFrom)
+	} else if len(opts.Roots) > 0 {
+		label = strings.Join(opts.Roots, ", ")
+	} else if opts.remote != "" {
+		label = opts.remote
+	}
+	fmt.Fprintln(w, opts.colors.paint("title", "Counting lines of code in: "+label))
+	fmt.Fprintln(w, strings.Repeat("=", 50))
+	printResults(w, rootPath, stats, opts)
+}
+
+func printResults(w io.Writer, rootPath string, stats *counter.ProjectStats, opts Options) {
+	if stats.SampledFiles > 0 {
+		fmt.Fprintf(w, "Estimated (%d-file sample)\n", stats.SampledFiles)
+	}
+	if stats.Partial {
+		fmt.Fprintln(w, "(partial) Counting stopped early; only the files counted so far are included")
+	}
+
+	// Print summary
+	fmt.Fprintf(w, "Total Files: %s\n", opts.colors.paint("total", strconv.Itoa(stats.TotalFiles)))
+	printFileStats(w, stats.TotalStats, opts)
+	if opts.GoGenerics {
+		fmt.Fprintf(w, "Generic Lines (Go): %d\n", stats.TotalStats.GenericLines)
+	}
+	if opts.ModelicaDetail {
+		fmt.Fprintf(w, "Annotation Lines (Modelica): %d\n", stats.TotalStats.AnnotationLines)
+	}
+	if opts.MetaAs != counter.MetaAsSyntax {
+		fmt.Fprintf(w, "Meta Lines (shebangs, front matter): %d\n", stats.TotalStats.MetaLines)
+	}
+	if opts.LicenseHeaders {
+		fmt.Fprintf(w, "License Header Lines: %d in %d files (%s of comment lines)\n",
+			stats.TotalStats.LicenseHeaderLines, stats.TotalStats.LicenseHeaderFiles,
+			percent(stats.TotalStats.LicenseHeaderLines, stats.TotalStats.CommentLines))
+	}
+	if stats.Terraform.Detected {
+		fmt.Fprintln(w, "Terraform project detected")
+	}
+	if stats.TotalStats.TruncatedLines > 0 {
+		fmt.Fprintf(w, "Lines over --max-line-bytes: %d (classified by their first %d bytes)\n",
+			stats.TotalStats.TruncatedLines, opts.MaxLineBytes)
+	}
+	if g := stats.Generated; g.Generated+g.Minified > 0 {
+		fmt.Fprintf(w, "Left out: %d generated, %d minified and %d binary files (%d lines; --include-generated counts them)\n",
+			g.Generated, g.Minified, g.Binary, g.Stats.TotalLines)
+	} else if g.Binary > 0 {
+		fmt.Fprintf(w, "Left out: %d binary files\n", g.Binary)
+	}
+	if stats.FilteredFiles > 0 {
+		fmt.Fprintf(w, "Filtered files: %d (--file-min-lines/--file-max-lines)\n", stats.FilteredFiles)
+	}
+	if len(stats.NestedRepos) > 0 && !opts.NestedGitRoots {
+		fmt.Fprintf(w, "Nested repositories skipped: %d (%s)\n", len(stats.NestedRepos), strings.Join(stats.NestedRepos, ", "))
+	}
+	if stats.Duplicates.Files > 0 {
+		fmt.Fprintf(w, "Duplicates skipped: %d files (--unique)\n", stats.Duplicates.Files)
+	}
+	fmt.Fprintln(w)
+
+	// Print breakdown by language
+	key := reportKey(opts)
+	languages := reportStats(stats, opts)
+	names := printLanguageTable(w, "Breakdown by language:", languages, opts)
+
+	if stats.Tests != nil {
+		fmt.Fprintln(w)
+		printLanguageTable(w, "Tests by language:", reportStats(stats.Tests, opts), opts)
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "Test code ratio: %.2f test code lines per code line\n",
+			ratio(stats.Tests.TotalStats.CodeLines, stats.TotalStats.CodeLines))
+	}
+
+	if stats.Changes != nil {
+		printChanges(w, stats.Changes, key)
+	}
+
+	if opts.LicenseHeaders {
+		printLicenseHeaders(w, languages, names)
+	}
+
+	if opts.ByDir > 0 {
+		printDirBreakdown(w, rootPath, stats.Files, opts.ByDir)
+	}
+
+	if opts.Tree {
+		printTree(w, rootPath, stats.Files, opts.TreeDepth)
+	}
+
+	if opts.ByFile {
+		printFileBreakdown(w, stats.Files, opts.Top)
+	}
+
+	if opts.TopFiles > 0 {
+		printTopFiles(w, stats.Files, names, reportFileKey(opts), opts.TopFiles)
+	}
+
+	if opts.Stats {
+		printFileSizeStats(w, languages, names, opts.LargeFile)
+	}
+
+	if opts.LineLengthStats {
+		printLineLengthStats(w, languages, names)
+	}
+
+	if opts.Complexity {
+		printComplexityStats(w, languages, names)
+	}
+
+	if opts.StyleMetrics {
+		printStyleMetrics(w, languages, names, opts.LongLine)
+	}
+
+	if opts.hasReport("generated") {
+		printGenerated(w, stats, key)
+	}
+
+	if opts.hasReport("effort") {
+		printEffort(w, stats.TotalStats.CodeLines, opts.CostPerMonth)
+	}
+
+	if opts.HCLDetail {
+		printTerraformStats(w, stats.Terraform)
+	}
+
+	if opts.ByAuthor {
+		printAuthorStats(w, stats.AuthorStats)
+	}
+
+	if opts.codeowners != nil {
+		printOwnerStats(w, counter.CountByOwner(stats, rootPath, opts.codeowners))
+	}
+
+	// The line endings are summarized without --check-line-endings too once
+	// they are inconsistent, so mixed endings do not go unnoticed
+	if opts.CheckLineEndings || stats.LineEndings.Inconsistent() {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "Dominant line endings: LF %d, CRLF %d, CR %d files\n",
+			stats.LineEndings.LF, stats.LineEndings.CRLF, stats.LineEndings.CR)
+		if opts.CheckLineEndings {
+			printMixedLineEndings(w, stats.Files)
+		} else if stats.LineEndings.Mixed > 0 {
+			fmt.Fprintf(w, "%d files with mixed line endings (--check-line-endings lists them)\n", stats.LineEndings.Mixed)
+		}
+	}
+
+	if opts.ListDuplicates {
+		printDuplicates(w, stats.Duplicates)
+	}
+
+	if len(stats.Errors) > 0 {
+		printErrors(w, stats.Errors, opts.ListErrors)
+	}
+}
+
+// printErrors prints how many paths were skipped because they could not be
+// read and, with list, the paths and their errors
+func printErrors(w io.Writer, errors []counter.PathError, list bool) {
+	fmt.Fprintln(w)
+	if !list {
+		fmt.Fprintf(w, "Errors: %d paths could not be read and were skipped (--list-errors lists them)\n", len(errors))
+		return
+	}
+	fmt.Fprintf(w, "Errors: %d paths could not be read and were skipped:\n", len(errors))
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	for _, e := range errors {
+		fmt.Fprintf(w, "%s: %s\n", e.Path, e.Err)
+	}
+}
+
+// printChanges prints the lines added and removed per language in the git
+// range of --since or --diff-range
+func printChanges(w io.Writer, changes map[string]counter.LineChanges, key func(ext string) string) {
+	languages := make(map[string]counter.LineChanges)
+	for ext, extChanges := range changes {
+		name := key(ext)
+		langChanges := languages[name]
+		langChanges.Add(extChanges)
+		languages[name] = langChanges
+	}
+	names := make([]string, 0, len(languages))
+	for name := range languages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	row := func(name string, c counter.LineChanges) {
+		fmt.Fprintf(w, "%-16s %-10s %-10s %-10s\n", name, "+"+strconv.Itoa(c.Added), "-"+strconv.Itoa(c.Removed), fmt.Sprintf("%+d", c.Added-c.Removed))
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Changes by language:")
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	fmt.Fprintf(w, "%-16s %-10s %-10s %-10s\n", "Language", "Added", "Removed", "Net")
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	var total counter.LineChanges
+	for _, name := range names {
+		row(name, languages[name])
+		total.Add(languages[name])
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	row("TOTAL", total)
+}
+
+// printDuplicates lists the counted files that have copies skipped by
+// --unique, each followed by its copies
+func printDuplicates(w io.Writer, duplicates counter.DuplicateStats) {
+	firsts := make([]string, 0, len(duplicates.Groups))
+	for first := range duplicates.Groups {
+		firsts = append(firsts, first)
+	}
+	sort.Strings(firsts)
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Duplicate files:")
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	if len(firsts) == 0 {
+		fmt.Fprintln(w, "(none)")
+	}
+	for _, first := range firsts {
+		fmt.Fprintln(w, first)
+		for _, dup := range duplicates.Groups[first] {
+			fmt.Fprintf(w, "  = %s\n", dup)
+		}
+	}
+}
+
+// printLanguageTable prints the breakdown of languages, a language view
+// from byLanguage, under title with the --columns and returns the
+// languages shown
+func printLanguageTable(w io.Writer, title string, languages *counter.ProjectStats, opts Options) []string {
+	columns, _ := tableColumns(opts)
+	width := 6
+	for _, col := range columns {
+		width += col.width + 1
+	}
+	// row prints one line of the table
+	row := func(name string, files int, s counter.FileStats) {
+		cells := make([]string, len(columns))
+		for i, col := range columns {
+			cells[i] = fmt.Sprintf("%-*s", col.width, col.value(name, files, s, languages.TotalStats))
+			if col.header == "Language" && name != "TOTAL" {
+				cells[i] = opts.colors.paint("language", cells[i])
+			}
+		}
+		line := strings.Join(cells, " ")
+		if name == "TOTAL" {
+			line = opts.colors.paint("total", line)
+		}
+		fmt.Fprintln(w, line)
+	}
+
+	fmt.Fprintln(w, opts.colors.paint("title", title))
+	fmt.Fprintln(w, strings.Repeat("-", width))
+	headers := make([]string, len(columns))
+	for i, col := range columns {
+		headers[i] = fmt.Sprintf("%-*s", col.width, col.header)
+	}
+	fmt.Fprintln(w, strings.Join(headers, " "))
+	fmt.Fprintln(w, strings.Repeat("-", width))
+
+	names := visibleExtensions(languages, opts)
+
+	for _, name := range names {
+		row(name, languages.FilesByExt[name], languages.StatsByExt[name])
+	}
+
+	if hidden := len(languages.FilesByExt) - len(names); hidden > 0 {
+		fmt.Fprintf(w, "(%d languages below --min-files/--min-lines not shown)\n", hidden)
+	}
+
+	fmt.Fprintln(w, strings.Repeat("-", width))
+	row("TOTAL", languages.TotalFiles, languages.TotalStats)
+	return names
+}
+
+// tableColumn is a column of the language table
+type tableColumn struct {
+	header string
+	width  int
+	// value returns the cell of the row for name, with files and s, in a
+	// table totalling total
+	value func(name string, files int, s, total counter.FileStats) string
+}
+
+// tableColumnNames lists the columns --columns accepts, in the order of
+// the default table
+var tableColumnNames = []string{"language", "files", "total", "code", "comments", "blank", "mixed", "share", "density"}
+
+// tableColumnSet maps the names accepted by --columns to their column
+var tableColumnSet = map[string]tableColumn{
+	"language": {"Language", 16, func(name string, _ int, _, _ counter.FileStats) string { return name }},
+	"files":    {"Files", 8, func(_ string, files int, _, _ counter.FileStats) string { return strconv.Itoa(files) }},
+	"total":    {"Total", 10, func(_ string, _ int, s, _ counter.FileStats) string { return strconv.Itoa(s.TotalLines) }},
+	"code":     {"Code", 10, func(_ string, _ int, s, _ counter.FileStats) string { return strconv.Itoa(s.CodeLines) }},
+	"comments": {"Comments", 12, func(_ string, _ int, s, _ counter.FileStats) string { return strconv.Itoa(s.CommentLines) }},
+	"blank":    {"Blank", 10, func(_ string, _ int, s, _ counter.FileStats) string { return strconv.Itoa(s.BlankLines) }},
+	"mixed":    {"Mixed", 10, func(_ string, _ int, s, _ counter.FileStats) string { return strconv.Itoa(s.MixedLines) }},
+	"share": {"% Code", 9, func(_ string, _ int, s, total counter.FileStats) string {
+		return percent(s.CodeLines, total.CodeLines)
+	}},
+	"density": {"Density", 9, func(_ string, _ int, s, _ counter.FileStats) string { return commentDensity(s) }},
+}
+
+// tableColumns returns the columns of the language table: those listed by
+// --columns or, without it, the counts, followed by the share and density
+// with --percentages
+func tableColumns(opts Options) ([]tableColumn, error) {
+	names := []string{"language", "files", "total", "code", "comments", "blank"}
+	if opts.Percentages {
+		names = append(names, "share", "density")
+	}
+	if opts.Columns != "" {
+		names = strings.Split(opts.Columns, ",")
+	}
+
+	var columns []tableColumn
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "ext" {
+			// The table is grouped by language rather than extension
+			name = "language"
+		}
+		col, ok := tableColumnSet[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q (expected %s)", name, strings.Join(tableColumnNames, ", "))
+		}
+		columns = append(columns, col)
+	}
+	return columns, nil
+}
+
+// ratio returns n/d, or 0 if d is 0
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
+
+func printMixedLineEndings(w io.Writer, files []counter.FileResult) {
+	var mixed []string
+	for _, file := range files {
+		if file.LineEnding == counter.EndingMixed {
+			mixed = append(mixed, file.Path)
+		}
+	}
+	sort.Strings(mixed)
+
+	if len(mixed) == 0 {
+		fmt.Fprintln(w, "No files with mixed line endings")
+		return
+	}
+	fmt.Fprintf(w, "%d files with mixed line endings:\n", len(mixed))
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	for _, path := range mixed {
+		fmt.Fprintln(w, path)
+	}
+}
+
+func printAuthorStats(w io.Writer, authorStats map[string]counter.FileStats) {
+	// Sort authors by code lines, most first
+	var authors []string
+	for author := range authorStats {
+		authors = append(authors, author)
+	}
+	sort.Slice(authors, func(i, j int) bool {
+		a, b := authorStats[authors[i]], authorStats[authors[j]]
+		if a.CodeLines != b.CodeLines {
+			return a.CodeLines > b.CodeLines
+		}
+		return authors[i] < authors[j]
+	})
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Breakdown by author:")
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	fmt.Fprintf(w, "%-24s %-10s %-10s %-12s %-10s\n", "Author", "Total", "Code", "Comments", "Blank")
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	for _, author := range authors {
+		s := authorStats[author]
+		fmt.Fprintf(w, "%-24s %-10d %-10d %-12d %-10d\n",
+			author, s.TotalLines, s.CodeLines, s.CommentLines, s.BlankLines)
+	}
+}
+
+// printOwnerStats prints the files and lines of each owner of the
+// CODEOWNERS file
+func printOwnerStats(w io.Writer, owners []counter.OwnerStats) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Breakdown by owner:")
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	fmt.Fprintf(w, "%-32s %-8s %-10s %-10s %s\n", "Owner", "Files", "Total", "Code", "Comments")
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	for _, o := range owners {
+		fmt.Fprintf(w, "%-32s %-8d %-10d %-10d %d\n", o.Owner, o.Files, o.Stats.TotalLines, o.Stats.CodeLines, o.Stats.CommentLines)
+	}
+}
+
+// Values of --group-by
+const (
+	groupByLanguage  = "language"
+	groupByExtension = "extension"
+)
+
+// reportKey returns the function giving the row of the breakdown the files
+// of a key are reported in: its language group or, with --group-by
+// extension, the key itself
+func reportKey(opts Options) func(ext string) string {
+	if opts.GroupBy == groupByExtension {
+		return func(ext string) string { return ext }
+	}
+	return counter.GroupName
+}
+
+// reportFileKey returns the function giving the row of the breakdown a
+// file is reported in, like reportKey but by the language the file was
+// counted as
+func reportFileKey(opts Options) func(file counter.FileResult) string {
+	if opts.GroupBy == groupByExtension {
+		return func(file counter.FileResult) string { return file.Ext }
+	}
+	return func(file counter.FileResult) string {
+		if file.Language == "" {
+			return counter.GroupName(file.Ext)
+		}
+		return counter.GroupName(file.Language)
+	}
+}
+
+// reportStats returns a copy of stats with the per-extension maps keyed by
+// the rows of the breakdown
+func reportStats(stats *counter.ProjectStats, opts Options) *counter.ProjectStats {
+	if opts.GroupBy == groupByExtension {
+		return groupStats(stats, reportKey(opts))
+	}
+	return byLanguage(stats)
+}
+
+// byLanguage returns a copy of stats whose per-extension maps are keyed by
+// language group instead, merging the keys of each group. Files are grouped
+// by the language they were counted as, which their content may have told
+// rather than their extension.
+func byLanguage(stats *counter.ProjectStats) *counter.ProjectStats {
+	if len(stats.FilesByLanguage) > 0 {
+		languages := *stats
+		languages.FilesByExt = stats.FilesByLanguage
+		languages.StatsByExt = stats.StatsByLanguage
+		languages.PerExtFileLengths = stats.PerLanguageFileLengths
+		stats = &languages
+	}
+	return groupStats(stats, counter.GroupName)
+}
+
+// groupStats returns a copy of stats with the per-extension maps keyed by
+// key(ext) instead. The per-language maps are left out.
+func groupStats(stats *counter.ProjectStats, key func(ext string) string) *counter.ProjectStats {
+	languages := *stats
+	languages.FilesByLanguage = nil
+	languages.StatsByLanguage = nil
+	languages.PerLanguageFileLengths = nil
+	languages.FilesByExt = make(map[string]int)
+	languages.StatsByExt = make(map[string]counter.FileStats)
+	languages.PerExtFileLengths = make(map[string][]int)
+	for ext, fileCount := range stats.FilesByExt {
+		name := key(ext)
+		languages.FilesByExt[name] += fileCount
+
+		langStats := languages.StatsByExt[name]
+		langStats.Add(stats.StatsByExt[ext])
+		languages.StatsByExt[name] = langStats
+
+		languages.PerExtFileLengths[name] = append(languages.PerExtFileLengths[name], stats.PerExtFileLengths[ext]...)
+	}
+	return &languages
+}
+
+// visibleExtensions returns the keys of stats that meet the --min-files
+// and --min-lines thresholds, in --sort order. Hidden keys still count
+// towards totals.
+func visibleExtensions(stats *counter.ProjectStats, opts Options) []string {
+	var extensions []string
+	for ext, fileCount := range stats.FilesByExt {
+		if !opts.ShowAll && (fileCount < opts.MinFiles || stats.StatsByExt[ext].TotalLines < opts.MinLines) {
+			continue
+		}
+		extensions = append(extensions, ext)
+	}
+
+	// Sort by name first for consistent output among equal values
+	sort.Strings(extensions)
+	field, descending, _ := parseSort(opts.Sort)
+	if value := sortValues[field]; value != nil {
+		sort.SliceStable(extensions, func(i, j int) bool {
+			a, b := value(stats, extensions[i]), value(stats, extensions[j])
+			if descending {
+				return a > b
+			}
+			return a < b
+		})
+	} else if descending {
+		sort.Sort(sort.Reverse(sort.StringSlice(extensions)))
+	}
+	return extensions
+}
+
+// sortValues are the numeric --sort fields, by name
+var sortValues = map[string]func(stats *counter.ProjectStats, key string) float64{
+	"files": func(stats *counter.ProjectStats, key string) float64 {
+		return float64(stats.FilesByExt[key])
+	},
+	"total": func(stats *counter.ProjectStats, key string) float64 {
+		return float64(stats.StatsByExt[key].TotalLines)
+	},
+	"code": func(stats *counter.ProjectStats, key string) float64 {
+		return float64(stats.StatsByExt[key].CodeLines)
+	},
+	// comments sorts by the share of comment lines among non-blank lines
+	"comments": func(stats *counter.ProjectStats, key string) float64 {
+		s := stats.StatsByExt[key]
+		if nonBlank := s.TotalLines - s.BlankLines; nonBlank > 0 {
+			return float64(s.CommentLines) / float64(nonBlank)
+		}
+		return 0
+	},
+}
+
+// parseSort splits a --sort value such as "code" or "files:asc" into its
+// field and direction. Numeric fields sort descending and the name
+// ascending unless a direction is given.
+func parseSort(value string) (field string, descending bool, err error) {
+	field, direction, _ := strings.Cut(value, ":")
+	if field == "" {
+		field = "name"
+	}
+	if field != "name" && sortValues[field] == nil {
+		return "", false, fmt.Errorf("invalid --sort %q (expected name, files, total, code or comments)", value)
+	}
+	switch direction {
+	case "":
+		return field, field != "name", nil
+	case "asc":
+		return field, false, nil
+	case "desc":
+		return field, true, nil
+	}
+	return "", false, fmt.Errorf("invalid --sort direction %q (expected asc or desc)", direction)
+}
+
+// printRootBreakdown prints the totals of each of roots, counted as stats,
+// under title
+func printRootBreakdown(w io.Writer, title string, roots []string, stats []*counter.ProjectStats) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, title)
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	fmt.Fprintf(w, "%-8s %-10s %-10s %-12s %-10s %s\n", "Files", "Total", "Code", "Comments", "Blank", "Path")
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	for i, root := range roots {
+		total := stats[i].TotalStats
+		fmt.Fprintf(w, "%-8d %-10d %-10d %-12d %-10d %s\n",
+			stats[i].TotalFiles, total.TotalLines, total.CodeLines,
+			total.CommentLines, total.BlankLines, root)
+	}
+}
+
+// printDirBreakdown prints the totals of files grouped by the directory
+// holding them, cut off depth levels below rootPath
+func printDirBreakdown(w io.Writer, rootPath string, files []counter.FileResult, depth int) {
+	filesByDir := make(map[string]int)
+	statsByDir := make(map[string]counter.FileStats)
+	for _, file := range files {
+		dir := dirAtDepth(rootPath, file.Path, depth)
+		filesByDir[dir]++
+		dirStats := statsByDir[dir]
+		dirStats.Add(file.Stats)
+		statsByDir[dir] = dirStats
+	}
+
+	dirs := make([]string, 0, len(filesByDir))
+	for dir := range filesByDir {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Breakdown by directory:")
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	fmt.Fprintf(w, "%-8s %-10s %-10s %-12s %-10s %s\n", "Files", "Total", "Code", "Comments", "Blank", "Directory")
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	for _, dir := range dirs {
+		dirStats := statsByDir[dir]
+		fmt.Fprintf(w, "%-8d %-10d %-10d %-12d %-10d %s\n",
+			filesByDir[dir], dirStats.TotalLines, dirStats.CodeLines,
+			dirStats.CommentLines, dirStats.BlankLines, dir)
+	}
+}
+
+// dirAtDepth returns the directory of path relative to rootPath, keeping at
+// most depth components, with a trailing slash; files directly in rootPath
+// belong to "./"
+func dirAtDepth(rootPath, path string, depth int) string {
+	rel, err := filepath.Rel(rootPath, path)
+	if err != nil {
+		rel = path
+	}
+	dir := filepath.ToSlash(filepath.Dir(rel))
+	if dir == "." {
+		return "./"
+	}
+	parts := strings.Split(dir, "/")
+	if len(parts) > depth {
+		parts = parts[:depth]
+	}
+	return strings.Join(parts, "/") + "/"
+}
+
+// printFileBreakdown lists files by code lines, most first, limited to the
+// top entries when top is positive
+func printFileBreakdown(w io.Writer, files []counter.FileResult, top int) {
+	sorted := append([]counter.FileResult(nil), files...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Stats.CodeLines != sorted[j].Stats.CodeLines {
+			return sorted[i].Stats.CodeLines > sorted[j].Stats.CodeLines
+		}
+		return sorted[i].Path < sorted[j].Path
+	})
+	if top > 0 && top < len(sorted) {
+		sorted = sorted[:top]
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Breakdown by file:")
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	fmt.Fprintf(w, "%-10s %-10s %-12s %-10s %s\n", "Total", "Code", "Comments", "Blank", "File")
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	for _, file := range sorted {
+		fmt.Fprintf(w, "%-10d %-10d %-12d %-10d %s\n",
+			file.Stats.TotalLines, file.Stats.CodeLines, file.Stats.CommentLines,
+			file.Stats.BlankLines, file.Path)
+	}
+}
+
+// printTopFiles lists, for each of the rows names of the breakdown, the n
+// files with the most code lines and the n files with the longest line
+func printTopFiles(w io.Writer, files []counter.FileResult, names []string, key func(file counter.FileResult) string, n int) {
+	byLang := make(map[string][]counter.FileResult)
+	for _, file := range files {
+		name := key(file)
+		byLang[name] = append(byLang[name], file)
+	}
+
+	printTop := func(title, column string, value func(counter.FileStats) int) {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, title)
+		fmt.Fprintln(w, strings.Repeat("-", 70))
+		fmt.Fprintf(w, "%-16s %-10s %s\n", "Language", column, "File")
+		fmt.Fprintln(w, strings.Repeat("-", 70))
+		for _, name := range names {
+			sorted := append([]counter.FileResult(nil), byLang[name]...)
+			sort.SliceStable(sorted, func(i, j int) bool {
+				if a, b := value(sorted[i].Stats), value(sorted[j].Stats); a != b {
+					return a > b
+				}
+				return sorted[i].Path < sorted[j].Path
+			})
+			if len(sorted) > n {
+				sorted = sorted[:n]
+			}
+			for _, file := range sorted {
+				fmt.Fprintf(w, "%-16s %-10d %s\n", name, value(file.Stats), file.Path)
+			}
+		}
+	}
+
+	printTop(fmt.Sprintf("Largest files (top %d per language by code lines):", n), "Code",
+		func(s counter.FileStats) int { return s.CodeLines })
+	printTop(fmt.Sprintf("Longest lines (top %d per language, in bytes):", n), "Longest",
+		func(s counter.FileStats) int { return s.MaxLineLength })
+}
+
+// printGenerated prints the lines of the generated and minified files left
+// out of the totals by language, and the share of hand-written code
+func printGenerated(w io.Writer, stats *counter.ProjectStats, key func(ext string) string) {
+	languages := make(map[string]counter.FileStats)
+	for ext, extStats := range stats.Generated.StatsByExt {
+		name := key(ext)
+		langStats := languages[name]
+		langStats.Add(extStats)
+		languages[name] = langStats
+	}
+	names := make([]string, 0, len(languages))
+	for name := range languages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	row := func(name string, s counter.FileStats) {
+		fmt.Fprintf(w, "%-16s %-10d %-10d %-12d %-10d\n", name, s.TotalLines, s.CodeLines, s.CommentLines, s.BlankLines)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Generated code by language (left out of the totals):")
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	fmt.Fprintf(w, "%-16s %-10s %-10s %-12s %-10s\n", "Language", "Total", "Code", "Comments", "Blank")
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	for _, name := range names {
+		row(name, languages[name])
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	generated := stats.Generated.Stats
+	row("TOTAL", generated)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Hand-written code lines: %d (%s of all code lines)\n", stats.TotalStats.CodeLines,
+		percent(stats.TotalStats.CodeLines, stats.TotalStats.CodeLines+generated.CodeLines))
+}
+
+// printLicenseHeaders prints the license header lines of each language
+// and their share of its comment lines
+func printLicenseHeaders(w io.Writer, stats *counter.ProjectStats, extensions []string) {
+	row := func(name string, s counter.FileStats) {
+		fmt.Fprintf(w, "%-16s %-8d %-14d %-12d %-12s\n", name, s.LicenseHeaderFiles, s.LicenseHeaderLines,
+			s.CommentLines, percent(s.LicenseHeaderLines, s.CommentLines))
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "License headers by language:")
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	fmt.Fprintf(w, "%-16s %-8s %-14s %-12s %-12s\n", "Language", "Files", "Header Lines", "Comments", "% Comments")
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	for _, ext := range extensions {
+		row(ext, stats.StatsByExt[ext])
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	row("TOTAL", stats.TotalStats)
+}
+
+// defaultLargeFile is the number of lines above which --stats counts a
+// file as large without --large-file
+const defaultLargeFile = 1000
+
+// printFileSizeStats prints the average, median and longest file of each
+// language and how many files have more than largeFile lines
+func printFileSizeStats(w io.Writer, stats *counter.ProjectStats, extensions []string, largeFile int) {
+	large := fmt.Sprintf("> %d", largeFile)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "File size statistics (lines per file):")
+	fmt.Fprintln(w, strings.Repeat("-", 78))
+	fmt.Fprintf(w, "%-16s %-12s %-12s %-12s %-10s %-10s\n", "Language", "Avg Code", "Avg Total", "Median", "Max", large)
+	fmt.Fprintln(w, strings.Repeat("-", 78))
+
+	for _, ext := range extensions {
+		if stats.FilesByExt[ext] == 0 {
+			// Only embedded in other files, e.g. Markdown code blocks
+			continue
+		}
+		fmt.Fprintf(w, "%-16s %-12.1f %-12.1f %-12.1f %-10d %-10d\n",
+			ext, stats.ExtAverageCodeLines(ext), stats.ExtAverageTotalLines(ext),
+			stats.ExtMedianTotalLines(ext), stats.ExtMaxTotalLines(ext),
+			stats.ExtLargeFiles(ext, largeFile))
+	}
+
+	fmt.Fprintln(w, strings.Repeat("-", 78))
+	fmt.Fprintf(w, "%-16s %-12.1f %-12.1f %-12.1f %-10d %-10d\n",
+		"TOTAL", stats.AverageCodeLines(), stats.AverageTotalLines(), stats.MedianTotalLines(),
+		stats.MaxTotalLines(), stats.LargeFiles(largeFile))
+}
+
+func printLineLengthStats(w io.Writer, stats *counter.ProjectStats, extensions []string) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Line length statistics:")
+	fmt.Fprintln(w, strings.Repeat("-", 58))
+	fmt.Fprintf(w, "%-16s %-10s %-10s %-10s\n", "Language", "Min", "Max", "Average")
+	fmt.Fprintln(w, strings.Repeat("-", 58))
+
+	for _, ext := range extensions {
+		extStats := stats.StatsByExt[ext]
+		fmt.Fprintf(w, "%-16s %-10d %-10d %-10.1f\n",
+			ext, extStats.MinLineLength, extStats.MaxLineLength, extStats.AverageLineLength())
+	}
+
+	fmt.Fprintln(w, strings.Repeat("-", 58))
+	fmt.Fprintf(w, "%-16s %-10d %-10d %-10.1f\n",
+		"TOTAL", stats.TotalStats.MinLineLength, stats.TotalStats.MaxLineLength,
+		stats.TotalStats.AverageLineLength())
+}
+
+// printStyleMetrics prints the long lines, lines with trailing whitespace
+// and lines with mixed indentation of each language
+func printStyleMetrics(w io.Writer, stats *counter.ProjectStats, extensions []string, longLine int) {
+	row := func(name string, s counter.FileStats) {
+		fmt.Fprintf(w, "%-16s %-12d %-12d %-12d\n", name, s.LongLines, s.TrailingWhitespaceLines, s.MixedIndentLines)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Style metrics (long lines over %d characters):\n", longLine)
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	fmt.Fprintf(w, "%-16s %-12s %-12s %-12s\n", "Language", "Long", "Trailing WS", "Mixed Indent")
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	for _, ext := range extensions {
+		row(ext, stats.StatsByExt[ext])
+	}
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	row("TOTAL", stats.TotalStats)
+}
+
+func printComplexityStats(w io.Writer, stats *counter.ProjectStats, extensions []string) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Complexity (branches per file):")
+	fmt.Fprintln(w, strings.Repeat("-", 58))
+	fmt.Fprintf(w, "%-16s %-12s %-12s %-12s\n", "Language", "Complexity", "Average", "Max")
+	fmt.Fprintln(w, strings.Repeat("-", 58))
+
+	for _, ext := range extensions {
+		extStats := stats.StatsByExt[ext]
+		fmt.Fprintf(w, "%-16s %-12d %-12.1f %-12d\n",
+			ext, extStats.Complexity, stats.ExtAverageComplexity(ext), extStats.MaxComplexity)
+	}
+
+	fmt.Fprintln(w, strings.Repeat("-", 58))
+	fmt.Fprintf(w, "%-16s %-12d %-12.1f %-12d\n",
+		"TOTAL", stats.TotalStats.Complexity,
+		stats.AverageComplexity(), stats.TotalStats.MaxComplexity)
+}
+
+func printTerraformStats(w io.Writer, stats counter.TerraformStats) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Terraform blocks:")
+	fmt.Fprintln(w, strings.Repeat("-", 30))
+	fmt.Fprintf(w, "%-12s %-10s\n", "Kind", "Count")
+	fmt.Fprintln(w, strings.Repeat("-", 30))
+	fmt.Fprintf(w, "%-12s %-10d\n", "resource", stats.ResourceBlocks)
+	fmt.Fprintf(w, "%-12s %-10d\n", "data", stats.DataBlocks)
+}
+
+// printEmbed writes stats as a Go source file declaring a LineCounterStats
+// variable holding the JSON-encoded results, for use with go generate.
+func printEmbed(w io.Writer, stats *counter.ProjectStats, pkg string) error {
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Raw strings cannot contain backticks, so fall back to a quoted string
+	literal := "`" + string(data) + "`"
+	if strings.Contains(string(data), "`") {
+		literal = strconv.Quote(string(data))
+	}
+
+	fmt.Fprintln(w, "// Code generated by line-counter; DO NOT EDIT.")
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "package %s\n", pkg)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "// LineCounterStats holds the JSON-encoded line counts of the project")
+	fmt.Fprintf(w, "var LineCounterStats = %s\n", literal)
+	return nil
+}
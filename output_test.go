@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/a2hop/line-counter/counter"
+)
+
+// smallLanguages returns counts of one large language and several small
+// ones, as in a project with a few configuration files
+func smallLanguages() *counter.ProjectStats {
+	stats := counter.NewProjectStats()
+	for name, counts := range map[string][2]int{
+		"Go":   {40, 6000},
+		"YAML": {3, 60},
+		"TOML": {1, 12},
+		"XML":  {1, 300},
+		"JSON": {2, 8},
+	} {
+		s := counter.FileStats{TotalLines: counts[1], CodeLines: counts[1]}
+		stats.FilesByExt[name] = counts[0]
+		stats.StatsByExt[name] = s
+		stats.TotalFiles += counts[0]
+		stats.TotalStats.Add(s)
+	}
+	return stats
+}
+
+func TestLanguageTableThresholds(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts Options
+		rows []string
+	}{
+		{"no thresholds", Options{}, []string{"Go", "XML", "YAML", "TOML", "JSON"}},
+		{"min files", Options{MinFiles: 2}, []string{"Go", "YAML", "JSON"}},
+		{"min lines", Options{MinLines: 50}, []string{"Go", "XML", "YAML"}},
+		{"both", Options{MinFiles: 2, MinLines: 50}, []string{"Go", "YAML"}},
+		{"show all", Options{MinFiles: 2, MinLines: 50, ShowAll: true}, []string{"Go", "XML", "YAML", "TOML", "JSON"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.opts.Sort = "code"
+			var buf bytes.Buffer
+			names := printLanguageTable(&buf, "Breakdown by language:", smallLanguages(), tc.opts)
+			if strings.Join(names, ",") != strings.Join(tc.rows, ",") {
+				t.Errorf("rows = %v, want %v", names, tc.rows)
+			}
+
+			// Title, three rules, header and TOTAL, plus a note on hidden rows
+			lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+			want := 6 + len(tc.rows)
+			if len(tc.rows) < 5 {
+				want++
+			}
+			if len(lines) != want {
+				t.Errorf("table has %d lines, want %d:\n%s", len(lines), want, buf.String())
+			}
+			// Hidden rows still count towards the total
+			total := lines[len(lines)-1]
+			if !strings.HasPrefix(total, "TOTAL") || !strings.Contains(total, "6380") {
+				t.Errorf("TOTAL row = %q, want 6380 lines", total)
+			}
+		})
+	}
+}
+
+// TestRefinedLanguageRows checks that a C++ header named .h is listed
+// under C++ in the language breakdown but under .h by extension
+func TestRefinedLanguageRows(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"c.h":   "#include <stdio.h>\nint f(void);\n",
+		"cpp.h": "#include <vector>\nnamespace app {\nint f();\n}\n",
+	})
+	stats := count(t, dir, counter.Options{})
+
+	for _, tc := range []struct {
+		groupBy string
+		want    map[string]int
+	}{
+		{groupByLanguage, map[string]int{"C": 1, "C++": 1}},
+		{groupByExtension, map[string]int{".h": 2}},
+	} {
+		got := reportStats(stats, Options{GroupBy: tc.groupBy}).FilesByExt
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("--group-by %s: rows %v, want %v", tc.groupBy, got, tc.want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := (CSVFormatter{}).Format(&buf, stats); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "\n.h,C Header,2,") {
+		t.Errorf("CSV lacks a .h row with both files:\n%s", buf.String())
+	}
+}
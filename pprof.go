@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// defaultPprofMode is the profile collected when --pprof is not given.
+// Debug builds override it so every run is profiled.
+var defaultPprofMode = ""
+
+// startProfile begins collecting the profile selected by mode ("cpu" or
+// "mem") and returns a function that writes it out once the scan completes.
+func startProfile(mode string) (func() error, error) {
+	switch mode {
+	case "":
+		return func() error { return nil }, nil
+	case "cpu":
+		file, err := os.Create("line-counter-cpu.pprof")
+		if err != nil {
+			return nil, err
+		}
+		if err := pprof.StartCPUProfile(file); err != nil {
+			file.Close()
+			return nil, err
+		}
+		return func() error {
+			pprof.StopCPUProfile()
+			return file.Close()
+		}, nil
+	case "mem":
+		return func() error {
+			file, err := os.Create("line-counter-mem.pprof")
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			runtime.GC()
+			return pprof.WriteHeapProfile(file)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown profile %q (expected cpu or mem)", mode)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/a2hop/line-counter/counter"
+)
+
+// progressInterval is how often the progress line is redrawn at most
+const progressInterval = 100 * time.Millisecond
+
+// progressWidth is the widest a progress line gets, so it fits an 80
+// column terminal without wrapping
+const progressWidth = 79
+
+// progressLine keeps a single status line on a terminal up to date while
+// a count runs
+type progressLine struct {
+	w     io.Writer
+	last  time.Time
+	shown bool
+}
+
+func newProgressLine(w io.Writer) *progressLine {
+	return &progressLine{w: w}
+}
+
+// update redraws the line with p, at most once per progressInterval
+func (l *progressLine) update(p counter.Progress) {
+	now := time.Now()
+	if now.Sub(l.last) < progressInterval {
+		return
+	}
+	l.last = now
+
+	prefix := fmt.Sprintf("Scanned %d files, %d lines: ", p.Files, p.Lines)
+	dir := filepath.Dir(p.Path)
+	// Keep the end of long directories, which tells them apart
+	if room := progressWidth - len(prefix); len(dir) > room && room > 3 {
+		dir = "..." + dir[len(dir)-room+3:]
+	}
+	fmt.Fprintf(l.w, "\r\033[K%s%s", prefix, dir)
+	l.shown = true
+}
+
+// clear erases the line so the report starts on a clean line. It does
+// nothing on a nil progressLine.
+func (l *progressLine) clear() {
+	if l == nil || !l.shown {
+		return
+	}
+	fmt.Fprint(l.w, "\r\033[K")
+	l.shown = false
+}
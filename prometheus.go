@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/a2hop/line-counter/counter"
+)
+
+// defaultListen is the address serve listens on without --listen
+const defaultListen = ":9777"
+
+// writePrometheus writes stats in the Prometheus text exposition format:
+// the project totals and the code lines and files of each language
+func writePrometheus(w io.Writer, stats *counter.ProjectStats) error {
+	languages := byLanguage(stats)
+	names := make([]string, 0, len(languages.FilesByExt))
+	for name := range languages.FilesByExt {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	gauge := func(name, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	}
+	gauge("loc_total", "Code lines in the project.")
+	fmt.Fprintf(&b, "loc_total %d\n", stats.TotalStats.CodeLines)
+	gauge("comments_total", "Comment lines in the project.")
+	fmt.Fprintf(&b, "comments_total %d\n", stats.TotalStats.CommentLines)
+	gauge("blank_total", "Blank lines in the project.")
+	fmt.Fprintf(&b, "blank_total %d\n", stats.TotalStats.BlankLines)
+	gauge("files_total", "Code files in the project.")
+	fmt.Fprintf(&b, "files_total %d\n", stats.TotalFiles)
+	gauge("loc_by_language", "Code lines per language.")
+	for _, name := range names {
+		fmt.Fprintf(&b, "loc_by_language{lang=\"%s\"} %d\n", promEscaper.Replace(name), languages.StatsByExt[name].CodeLines)
+	}
+	gauge("files_by_language", "Code files per language.")
+	for _, name := range names {
+		fmt.Fprintf(&b, "files_by_language{lang=\"%s\"} %d\n", promEscaper.Replace(name), languages.FilesByExt[name])
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// promEscaper escapes label values for the text exposition format
+var promEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writePromTextfile writes stats to path for node_exporter's textfile
+// collector. The file is replaced in one step, so the collector never
+// reads a partly written file.
+func writePromTextfile(path string, stats *counter.ProjectStats) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	err = writePrometheus(tmp, stats)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o644)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// runServe implements "line-counter serve [path]": it counts the path
+// (or --path) every --interval and serves the latest counts on /metrics
+// at --listen until ctx is done
+func runServe(ctx context.Context, args []string, opts Options) error {
+	if len(args) > 1 {
+		return errors.New("serve: expected at most one path")
+	}
+	root := opts.ServePath
+	if len(args) == 1 {
+		root = args[0]
+	}
+	if opts.Interval <= 0 {
+		return errors.New("serve: --interval must be positive")
+	}
+	// Recounts run in the background, so a progress line would only
+	// garble the terminal
+	opts.Progress = nil
+
+	var mu sync.Mutex
+	var latest *counter.ProjectStats
+	recount := func() {
+		stats, err := counter.Count(ctx, root, opts.Options)
+		if err != nil {
+			if ctx.Err() == nil {
+				opts.Logger.Warn("could not count", "path", root, "err", err)
+			}
+			return
+		}
+		mu.Lock()
+		latest = stats
+		mu.Unlock()
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		stats := latest
+		mu.Unlock()
+		if stats == nil {
+			http.Error(w, "first count still running", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writePrometheus(w, stats)
+	})
+	server := &http.Server{Addr: opts.Listen, Handler: mux}
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- server.ListenAndServe() }()
+	go func() {
+		recount()
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				recount()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	fmt.Fprintf(os.Stderr, "Serving metrics for %s on %s/metrics\n", root, opts.Listen)
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return server.Shutdown(shutdownCtx)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// remotePrefixes are the URL prefixes of the remote repositories that are
+// cloned instead of walked
+var remotePrefixes = []string{"https://", "http://", "ssh://", "git://", "file://", "git@"}
+
+// isRemoteRepo reports whether arg names a remote git repository rather
+// than a local path
+func isRemoteRepo(arg string) bool {
+	if _, err := os.Stat(arg); err == nil {
+		return false
+	}
+	for _, prefix := range remotePrefixes {
+		if strings.HasPrefix(arg, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitRemoteRef splits a repository argument of the form url[@ref] into
+// the URL and the branch, tag or commit, which is "" if not given
+func splitRemoteRef(arg string) (url, ref string) {
+	slash := strings.LastIndex(arg, "/")
+	if at := strings.LastIndex(arg, "@"); slash >= 0 && at > slash {
+		return arg[:at], arg[at+1:]
+	}
+	return arg, ""
+}
+
+// cloneRemote shallow-clones the repository arg, a URL with an optional
+// @ref, into a temporary directory and returns the directory and a
+// function removing it
+func cloneRemote(ctx context.Context, arg string) (string, func(), error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return "", nil, errors.New("counting a remote repository requires git, but it was not found in PATH")
+	}
+	url, ref := splitRemoteRef(arg)
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	dir, err := os.MkdirTemp("", "line-counter-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	// Fetching the single ref works for branches, tags and commits alike,
+	// which git clone --branch does not
+	steps := [][]string{
+		{"init", "-q"},
+		{"fetch", "-q", "--depth", "1", "--", url, ref},
+		{"checkout", "-q", "FETCH_HEAD"},
+	}
+	for _, step := range steps {
+		cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, step...)...)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			cleanup()
+			if ctx.Err() != nil {
+				return "", nil, ctx.Err()
+			}
+			msg := strings.TrimSpace(stderr.String())
+			if msg == "" {
+				msg = err.Error()
+			}
+			return "", nil, fmt.Errorf("could not clone %s: %s", arg, msg)
+		}
+	}
+	return dir, cleanup, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/a2hop/line-counter/counter"
+)
+
+//go:embed templates/*.tmpl
+var builtinTemplates embed.FS
+
+// templateFuncs are the helpers available to --template files:
+//
+//	percent part whole  formats part/whole as a percentage, e.g. "62.3%"
+//	humanize n          formats n with thousands separators, e.g. "12,345"
+var templateFuncs = template.FuncMap{
+	"percent":  percent,
+	"humanize": humanize,
+	"language": counter.LanguageName,
+}
+
+// LoadTemplate parses the template at path, or one of the built-in
+// templates when path is "builtin:markdown" or "builtin:html". A path
+// holding "{{" is the text of the template itself.
+func LoadTemplate(path string) (*template.Template, error) {
+	if strings.Contains(path, "{{") {
+		return template.New("inline").Funcs(templateFuncs).Parse(path)
+	}
+	if name, ok := strings.CutPrefix(path, "builtin:"); ok {
+		data, err := builtinTemplates.ReadFile("templates/" + name + ".tmpl")
+		if err != nil {
+			return nil, fmt.Errorf("unknown built-in template %q (expected markdown or html)", name)
+		}
+		return template.New(name).Funcs(templateFuncs).Parse(string(data))
+	}
+	return template.New(filepath.Base(path)).Funcs(templateFuncs).ParseFiles(path)
+}
+
+// RenderTemplate executes tmpl with stats as its data and writes the result to w
+func RenderTemplate(tmpl *template.Template, stats *counter.ProjectStats, w io.Writer) error {
+	return tmpl.Execute(w, stats)
+}
+
+func percent(part, whole int) string {
+	return fmt.Sprintf("%.1f%%", percentOf(part, whole))
+}
+
+// percentOf returns part as a percentage of whole, or 0 when whole is 0
+func percentOf(part, whole int) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(whole)
+}
+
+func humanize(n int) string {
+	digits := strconv.Itoa(n)
+	sign := ""
+	if n < 0 {
+		sign, digits = "-", digits[1:]
+	}
+
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(d)
+	}
+	return sign + b.String()
+}
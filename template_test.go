@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/a2hop/line-counter/counter"
+)
+
+// templateStats returns counts of a Go and Python project
+func templateStats() *counter.ProjectStats {
+	stats := counter.NewProjectStats()
+	for ext, s := range map[string]counter.FileStats{
+		".go": {TotalLines: 1500, CodeLines: 1200, CommentLines: 200, BlankLines: 100},
+		".py": {TotalLines: 500, CodeLines: 400, CommentLines: 50, BlankLines: 50},
+	} {
+		stats.FilesByExt[ext] = 2
+		stats.StatsByExt[ext] = s
+		stats.TotalFiles += 2
+		stats.TotalStats.Add(s)
+	}
+	return stats
+}
+
+func TestBuiltinTemplates(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		want []string
+	}{
+		{"markdown", []string{
+			"**1,600** lines of code in **4** files",
+			"| Language | Files | Total | Code | Comments | Blank | % of code |",
+			"| Go | 2 | 1,500 | 1,200 | 200 | 100 | 75.0% |",
+			"| Python | 2 | 500 | 400 | 50 | 50 | 25.0% |",
+			"| **Total** | **4** | **2,000** | **1,600** | **250** | **150** | **100.0%** |",
+		}},
+		{"html", []string{
+			`<div class="line-counter">`,
+			"<strong>1,600</strong> lines of code",
+			"<tr><td>Go</td><td>2</td><td>1,500</td><td>1,200</td><td>200</td><td>100</td><td>75.0%</td>",
+			"width:25.0%",
+			"<tr><th>Total</th><th>4</th><th>2,000</th>",
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpl, err := LoadTemplate("builtin:" + tc.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := (TemplateFormatter{Template: tmpl, ByLanguage: true}).Format(&buf, templateStats()); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("output lacks %q:\n%s", want, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func TestLoadTemplateUnknownBuiltin(t *testing.T) {
+	if _, err := LoadTemplate("builtin:latex"); err == nil {
+		t.Error("LoadTemplate accepted an unknown built-in template")
+	}
+}
+
+func TestHumanize(t *testing.T) {
+	for n, want := range map[int]string{0: "0", 999: "999", 1000: "1,000", 123456: "123,456", -1234567: "-1,234,567"} {
+		if got := humanize(n); got != want {
+			t.Errorf("humanize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
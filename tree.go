@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/a2hop/line-counter/counter"
+)
+
+// defaultTreeDepth is the number of directory levels --tree shows without
+// --tree-depth
+const defaultTreeDepth = 3
+
+const (
+	// treeBarWidth is the width of the bar of a directory holding all the
+	// code
+	treeBarWidth = 30
+	// treeNameWidth caps the width of the name column
+	treeNameWidth = 50
+)
+
+// treeRow is one directory of the --tree output
+type treeRow struct {
+	label string
+	code  int
+}
+
+// printTree prints the directories below rootPath, depth levels deep (0
+// for all), as a tree annotated with their code lines and a bar
+// proportional to their share of the code, largest first
+func printTree(w io.Writer, rootPath string, files []counter.FileResult, depth int) {
+	tree := buildTree(rootPath, files)
+	code := make(map[*dirNode]int)
+	var sum func(d *dirNode) int
+	sum = func(d *dirNode) int {
+		n := 0
+		for _, file := range d.files {
+			n += file.Stats.CodeLines
+		}
+		for _, child := range d.dirs {
+			n += sum(child)
+		}
+		code[d] = n
+		return n
+	}
+	total := sum(tree)
+
+	rows := []treeRow{{label: rootPath, code: total}}
+	var walk func(d *dirNode, prefix string, level int)
+	walk = func(d *dirNode, prefix string, level int) {
+		if depth > 0 && level > depth {
+			return
+		}
+		children := make([]*dirNode, 0, len(d.dirs))
+		for _, child := range d.dirs {
+			children = append(children, child)
+		}
+		sort.Slice(children, func(i, j int) bool {
+			if code[children[i]] != code[children[j]] {
+				return code[children[i]] > code[children[j]]
+			}
+			return children[i].name < children[j].name
+		})
+		for i, child := range children {
+			branch, indent := "├── ", "│   "
+			if i == len(children)-1 {
+				branch, indent = "└── ", "    "
+			}
+			rows = append(rows, treeRow{label: prefix + branch + child.name + "/", code: code[child]})
+			walk(child, prefix+indent, level+1)
+		}
+	}
+	walk(tree, "", 1)
+
+	width := 0
+	for _, row := range rows {
+		width = max(width, utf8.RuneCountInString(row.label))
+	}
+	width = min(width, treeNameWidth)
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Code lines by directory:")
+	fmt.Fprintln(w, strings.Repeat("-", 70))
+	for _, row := range rows {
+		label := row.label
+		if n := utf8.RuneCountInString(label); n > width {
+			label = "…" + string([]rune(label)[n-width+1:])
+		}
+		bar := 0
+		if total > 0 {
+			bar = (row.code*treeBarWidth + total/2) / total
+		}
+		fmt.Fprintf(w, "%s%s %10s %s%s %s\n", label, strings.Repeat(" ", width-utf8.RuneCountInString(label)),
+			humanize(row.code), strings.Repeat("█", bar), strings.Repeat(" ", treeBarWidth-bar), percent(row.code, total))
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a2hop/line-counter/counter"
+)
+
+// Steps of "line-counter trend"
+const (
+	stepDaily   = "daily"
+	stepWeekly  = "weekly"
+	stepMonthly = "monthly"
+)
+
+// trendCommit is a commit of the first-parent history of a repository
+type trendCommit struct {
+	hash string
+	time time.Time
+}
+
+// runTrend implements "line-counter trend [path]": it counts the last
+// commit of each of the last --last intervals of --step in the history of
+// the git repository at path, read from its object database, and prints
+// the counts per language over time as a table or, with --format csv, CSV
+func runTrend(ctx context.Context, w io.Writer, args []string, opts Options) error {
+	if len(args) > 1 {
+		return errors.New("trend: expected at most one path")
+	}
+	root := "."
+	if len(args) == 1 {
+		root = args[0]
+	}
+	if opts.Format != "text" && opts.Format != "csv" {
+		return fmt.Errorf("trend: --format must be text or csv")
+	}
+	if opts.Step != stepDaily && opts.Step != stepWeekly && opts.Step != stepMonthly {
+		return fmt.Errorf("trend: unknown --step %q (expected daily, weekly or monthly)", opts.Step)
+	}
+	if opts.Last < 1 {
+		return fmt.Errorf("trend: --last must be at least 1")
+	}
+	commits, err := trendCommits(ctx, root, opts.Step, opts.Last)
+	if err != nil {
+		return err
+	}
+	if len(commits) == 0 {
+		return fmt.Errorf("trend: %s has no commits", root)
+	}
+
+	// The files of a commit rarely change from one interval to the next,
+	// so they are cached by git object unless --cache says otherwise
+	if opts.Cache == "" {
+		if dir, err := os.UserCacheDir(); err == nil {
+			opts.Cache = filepath.Join(dir, "line-counter")
+		}
+	}
+	c, err := counter.New(opts.Options)
+	if err != nil {
+		return err
+	}
+	entries := make([]HistoryEntry, 0, len(commits))
+	for _, commit := range commits {
+		stats, err := c.CountRev(ctx, root, commit.hash)
+		if err != nil {
+			opts.progress.clear()
+			return countError(err, opts)
+		}
+		entry := historyEntry(commit.time, root, stats)
+		entry.Commit = commit.hash
+		entries = append(entries, entry)
+	}
+	opts.progress.clear()
+
+	if opts.Format == "csv" {
+		return writeTrendCSV(w, entries)
+	}
+	printHistory(w, fmt.Sprintf("Trend of %s (%d commits, %s)", root, len(entries), opts.Step), entries)
+	return nil
+}
+
+// trendCommits returns the last commit of each of the last intervals of
+// step in the first-parent history of HEAD of the repository at root,
+// oldest first
+func trendCommits(ctx context.Context, root, step string, last int) ([]trendCommit, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("trend requires git, but it was not found in PATH")
+	}
+	cmd := exec.CommandContext(ctx, "git", "-C", root, "log", "--first-parent", "--format=%H %cI", "HEAD")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("trend: could not read the history of %s: %s", root, msg)
+	}
+
+	// The log is newest first, so the first commit of an interval is its
+	// last
+	var commits []trendCommit
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(string(out), "\n") {
+		hash, date, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, date)
+		if err != nil {
+			continue
+		}
+		interval := stepInterval(t.UTC(), step)
+		if seen[interval] {
+			continue
+		}
+		seen[interval] = true
+		commits = append(commits, trendCommit{hash: hash, time: t})
+		if len(commits) == last {
+			break
+		}
+	}
+	for i, j := 0, len(commits)-1; i < j; i, j = i+1, j-1 {
+		commits[i], commits[j] = commits[j], commits[i]
+	}
+	return commits, nil
+}
+
+// stepInterval names the interval of step holding t
+func stepInterval(t time.Time, step string) string {
+	switch step {
+	case stepDaily:
+		return t.Format("2006-01-02")
+	case stepWeekly:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	default:
+		return t.Format("2006-01")
+	}
+}
+
+// writeTrendCSV writes one row per language of each entry, followed by a
+// TOTAL row for the entry
+func writeTrendCSV(w io.Writer, entries []HistoryEntry) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"time", "commit", "language", "files", "total_lines", "code_lines", "comment_lines", "blank_lines"})
+
+	record := func(entry HistoryEntry, label string, c HistoryCounts) []string {
+		return []string{
+			entry.Time.Format(time.RFC3339),
+			entry.Commit,
+			label,
+			strconv.Itoa(c.Files),
+			strconv.Itoa(c.Total),
+			strconv.Itoa(c.Code),
+			strconv.Itoa(c.Comments),
+			strconv.Itoa(c.Blank),
+		}
+	}
+	for _, entry := range entries {
+		names := make([]string, 0, len(entry.Languages))
+		for name := range entry.Languages {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			cw.Write(record(entry, name, entry.Languages[name]))
+		}
+		cw.Write(record(entry, "TOTAL", entry.Total))
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,45 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// makeRaw puts the terminal tty in raw mode, so key presses are read one
+// at a time without echo, and returns a function restoring its settings
+func makeRaw(tty *os.File) (func(), error) {
+	saved, err := stty(tty, "-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty(tty, "raw", "-echo"); err != nil {
+		return nil, err
+	}
+	return func() { stty(tty, strings.TrimSpace(saved)) }, nil
+}
+
+// terminalSize returns the rows and columns of the terminal tty, or 24 by
+// 80 if they cannot be read
+func terminalSize(tty *os.File) (rows, cols int) {
+	out, err := stty(tty, "size")
+	if fields := strings.Fields(out); err == nil && len(fields) == 2 {
+		rows, _ = strconv.Atoi(fields[0])
+		cols, _ = strconv.Atoi(fields[1])
+	}
+	if rows <= 0 || cols <= 0 {
+		return 24, 80
+	}
+	return rows, cols
+}
+
+// stty runs stty with args on tty and returns its output
+func stty(tty *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+	out, err := cmd.Output()
+	return string(out), err
+}
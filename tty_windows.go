@@ -0,0 +1,15 @@
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// makeRaw is not supported on Windows consoles
+func makeRaw(tty *os.File) (func(), error) {
+	return nil, errors.New("--interactive is not supported on Windows")
+}
+
+func terminalSize(tty *os.File) (rows, cols int) {
+	return 24, 80
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/a2hop/line-counter/counter"
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long the tree must stay quiet after an event before
+// it is recounted, so a burst of saves gives a single recount
+const watchDebounce = 500 * time.Millisecond
+
+// watchBatch collects the events of the paths below the root until the
+// tree is quiet
+type watchBatch struct {
+	// written are the paths created, renamed into place or written
+	written map[string]bool
+	// removed are the paths deleted or renamed away
+	removed map[string]bool
+	// walk is set once the tree must be walked again: an ignore file
+	// changed, which may change what is counted anywhere below it, or the
+	// events overflowed the notification queue
+	walk bool
+}
+
+func newWatchBatch() *watchBatch {
+	return &watchBatch{written: make(map[string]bool), removed: make(map[string]bool)}
+}
+
+// add records event, reporting whether it may change the counts
+func (b *watchBatch) add(event fsnotify.Event) bool {
+	switch {
+	case event.Has(fsnotify.Create) || event.Has(fsnotify.Write):
+		b.written[event.Name] = true
+	case event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename):
+		b.removed[event.Name] = true
+	default:
+		return false
+	}
+	if name := filepath.Base(event.Name); name == ".gitignore" || name == counter.LcignoreFileName {
+		b.walk = true
+	}
+	return true
+}
+
+// Watch counts root, prints the results to w and then subscribes to the
+// file notifications of the directories walked. Once events stop for
+// watchDebounce, the files created or written since the last count that
+// pass the filters are read, removed files are dropped, and the results
+// are printed again; events for files that are not counted, such as .log
+// files, print nothing. The tree is only walked again when a .gitignore or
+// .lcignore file changes or notifications were lost.
+// When w is a terminal the screen is cleared before each report; otherwise
+// reports are appended below each other under a timestamp header.
+// Watch returns when ctx is done or a count fails.
+func Watch(ctx context.Context, root string, opts Options, w io.Writer) error {
+	c, err := counter.New(opts.Options)
+	if err != nil {
+		return err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	inc := c.Incremental(root)
+	clearScreen := isTerminal(w)
+
+	render := func(stats *counter.ProjectStats) {
+		if clearScreen {
+			fmt.Fprint(w, "\033[H\033[2J")
+		} else {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "[%s] recounted %d of %d files\n", time.Now().Format("2006-01-02 15:04:05"), inc.Recounted, stats.TotalFiles)
+		printReport(w, root, stats, opts)
+	}
+
+	stats, err := inc.Count(ctx)
+	if err != nil {
+		return watchError(ctx, err)
+	}
+	if err := addWatches(watcher, inc.Dirs()); err != nil {
+		return err
+	}
+	render(stats)
+
+	batch := newWatchBatch()
+	var quiet <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if batch.add(event) {
+				quiet = time.After(watchDebounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			if !errors.Is(err, fsnotify.ErrEventOverflow) {
+				return fmt.Errorf("--watch: %v", err)
+			}
+			batch.walk = true
+			quiet = time.After(watchDebounce)
+		case <-quiet:
+			quiet = nil
+			stats, err := applyBatch(ctx, inc, watcher, batch)
+			if err != nil {
+				return watchError(ctx, err)
+			}
+			batch = newWatchBatch()
+			if stats != nil {
+				render(stats)
+			}
+		}
+	}
+}
+
+// applyBatch updates the counts of inc with the events of batch and
+// watches the directories it finds. It returns nil results if no counted
+// file changed.
+func applyBatch(ctx context.Context, inc *counter.Incremental, watcher *fsnotify.Watcher, batch *watchBatch) (*counter.ProjectStats, error) {
+	if batch.walk {
+		stats, err := inc.Count(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return stats, addWatches(watcher, inc.Dirs())
+	}
+
+	// An editor may save by removing or renaming a file and then
+	// creating it again, so paths count as removed only if they are gone
+	var removed []string
+	for path := range batch.removed {
+		if _, err := os.Lstat(path); err != nil {
+			removed = append(removed, path)
+		}
+	}
+	written := make([]string, 0, len(batch.written))
+	for path := range batch.written {
+		written = append(written, path)
+	}
+	sort.Strings(written)
+	changed, dirs, err := inc.Discover(ctx, written)
+	if err != nil {
+		return nil, err
+	}
+	if err := addWatches(watcher, dirs); err != nil {
+		return nil, err
+	}
+	if len(changed) == 0 && len(removed) == 0 {
+		return nil, nil
+	}
+
+	stats, err := inc.Recount(ctx, changed, removed)
+	if err != nil || (inc.Recounted == 0 && inc.Removed == 0) {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// addWatches subscribes watcher to the events of dirs. Directories removed
+// since they were walked are skipped.
+func addWatches(watcher *fsnotify.Watcher, dirs []string) error {
+	for _, dir := range dirs {
+		err := watcher.Add(dir)
+		switch {
+		case err == nil, errors.Is(err, os.ErrNotExist):
+		case errors.Is(err, syscall.ENOSPC):
+			return fmt.Errorf("--watch: too many directories to watch %s; raise fs.inotify.max_user_watches", dir)
+		default:
+			return fmt.Errorf("--watch: could not watch %s: %v", dir, err)
+		}
+	}
+	return nil
+}
+
+// watchError returns err, or nil if it is due to ctx being done, which
+// ends the watch normally
+func watchError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
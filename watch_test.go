@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/a2hop/line-counter/counter"
+)
+
+// syncBuffer is a bytes.Buffer safe for a writer and readers on other
+// goroutines
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"main.go":    "package main\n",
+		"pkg/lib.go": "package pkg\n",
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	var out syncBuffer
+	done := make(chan error, 1)
+	go func() {
+		done <- Watch(ctx, dir, Options{Options: counter.Options{}, Format: "text"}, &out)
+	}()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Watch: %v", err)
+		}
+	}()
+
+	// waitFor waits until the output holds n reports, the last one with
+	// header
+	waitFor := func(n int, header string) {
+		t.Helper()
+		deadline := time.Now().Add(10 * time.Second)
+		for time.Now().Before(deadline) {
+			reports := strings.Split(out.String(), "] recounted ")
+			if len(reports)-1 > n {
+				t.Fatalf("got %d reports, want %d:\n%s", len(reports)-1, n, out.String())
+			}
+			if len(reports)-1 == n {
+				if !strings.HasPrefix(reports[n], header) {
+					t.Fatalf("report %d starts with %q, want %q", n, reports[n][:min(len(reports[n]), 30)], header)
+				}
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		t.Fatalf("timed out waiting for report %d:\n%s", n, out.String())
+	}
+	waitFor(1, "2 of 2 files")
+
+	// Only the written file is read again
+	writeTree(t, dir, map[string]string{"pkg/lib.go": "package pkg\n\nvar X = 1\n"})
+	waitFor(2, "1 of 2 files")
+
+	// Files below a new directory are found without walking the tree
+	writeTree(t, dir, map[string]string{"cmd/tool/main.go": "package main\n"})
+	waitFor(3, "1 of 3 files")
+
+	// A file that is not counted prints nothing
+	writeTree(t, dir, map[string]string{"debug.log": "line\n"})
+	time.Sleep(3 * watchDebounce)
+	waitFor(3, "1 of 3 files")
+
+	if err := os.RemoveAll(filepath.Join(dir, "cmd")); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(4, "0 of 2 files")
+}